@@ -0,0 +1,113 @@
+// Package main - утилита для управления миграциями схемы базы данных.
+//
+// Использование:
+//
+//	migrate -d <dsn> up          применить все миграции
+//	migrate -d <dsn> down [N]    откатить N последних миграций (по умолчанию 1)
+//	migrate -d <dsn> status      показать состояние миграций
+//
+// DSN также можно передать через переменную окружения DATABASE_DSN.
+// DSN вида sqlite://<path> выбирает встроенную базу SQLite, иначе - PostgreSQL.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // pgx driver
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/migrations"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/sqlitestorage"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+}
+
+// run разбирает аргументы и выполняет команду.
+func run(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dsn := flags.String("d", os.Getenv("DATABASE_DSN"), "address DB")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *dsn == "" {
+		return fmt.Errorf("database DSN is empty, use -d or DATABASE_DSN")
+	}
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("command is required: up, down [N], status")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	db, dialect, err := open(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	switch cmd := flags.Arg(0); cmd {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			steps, err = strconv.Atoi(flags.Arg(1))
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps: %s", flags.Arg(1))
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", rolledBack)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown command: %s", cmd)
+	}
+
+	return nil
+}
+
+// open подключается к базе и выбирает диалект миграций по DSN.
+func open(dsn string) (*sql.DB, migrations.Dialect, error) {
+	if sqlitestorage.IsDSN(dsn) {
+		db, err := sqlitestorage.Open(dsn)
+		return db, migrations.SQLite, err
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	return db, migrations.Postgres, err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/boltstorage"
+)
+
+// backupOnSignal снимает резервную копию хранилища bbolt по сигналу SIGUSR1
+// (например, kill -USR1 <pid>). Копия кладется рядом с базой
+// в файл <база>.backup-<время с миллисекундами>.
+func backupOnSignal(ctx context.Context, repo *boltstorage.BoltStorage, logs *logger.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			logs.Info("Backup requested by signal")
+			path, err := backup(repo, time.Now())
+			if err != nil {
+				logs.Error("Error backup = ", logger.ErrAttr(err))
+				continue
+			}
+			logs.Info("Bolt storage backed up", logger.StringAttr("path", path))
+		}
+	}
+}
+
+// backup пишет копию во временный файл и переносит его под итоговое имя
+// жесткой ссылкой, чтобы незавершенная копия никогда не лежала под
+// итоговым именем. В отличие от переименования, ссылка не заменяет
+// существующий файл: повторная копия с тем же именем возвращает ошибку.
+func backup(repo *boltstorage.BoltStorage, now time.Time) (string, error) {
+	path := fmt.Sprintf("%s.backup-%s", repo.Path(), now.Format("20060102-150405.000"))
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = repo.Backup(tmp); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Link(tmp.Name(), path)
+	}
+	os.Remove(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
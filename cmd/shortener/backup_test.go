@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/boltstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	repo, err := boltstorage.NewBoltStorage("bolt://" + filepath.Join(t.TempDir(), "shortener.bolt"))
+	require.NoError(t, err)
+	defer repo.Close()
+
+	_, err = repo.SaveURL(context.Background(), "short", "https://example.com", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	path, err := backup(repo, now)
+	require.NoError(t, err)
+	assert.Equal(t, repo.Path()+".backup-20240102-030405.006", path)
+
+	// копия в ту же миллисекунду не перезаписывает предыдущую
+	_, err = backup(repo, now)
+	assert.ErrorIs(t, err, os.ErrExist)
+	other, err := backup(repo, now.Add(time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, repo.Path()+".backup-20240102-030405.007", other)
+
+	copied, err := boltstorage.NewBoltStorage("bolt://" + path)
+	require.NoError(t, err)
+	defer copied.Close()
+
+	record, err := copied.GetURL(context.Background(), "short")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", record.OriginalURL)
+
+	// временных файлов не остается
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp-*"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/filestorage"
+)
+
+// compactOnSignal запускает компактизацию файлового хранилища по сигналу SIGUSR1
+// (например, kill -USR1 <pid>).
+func compactOnSignal(ctx context.Context, repo *filestorage.SaveFile, logs *logger.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			logs.Info("Compaction requested by signal")
+			if err := repo.Compact(); err != nil {
+				logs.Error("Error compaction = ", logger.ErrAttr(err))
+				continue
+			}
+			logs.Info("File storage compacted")
+		}
+	}
+}
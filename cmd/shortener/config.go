@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"time"
 )
 
 // Configs структура основных зависимостей при запуске.
@@ -17,6 +20,121 @@ type Configs struct {
 	AddrDB     string `json:"database_dsn"`
 	HTTPS      *bool  `json:"enable_https"`
 	ConfigFile string
+
+	FileCompactInterval Duration `json:"file_compact_interval"`
+	FileCompactSize     int64    `json:"file_compact_size"`
+	FileClickRetention  Duration `json:"file_click_retention"`
+
+	// BoltBackupInterval - интервал резервного копирования bbolt,
+	// ноль - копия снимается только вручную.
+	BoltBackupInterval Duration `json:"bolt_backup_interval"`
+
+	StorageReadTimeout  Duration `json:"storage_read_timeout"`
+	StorageWriteTimeout Duration `json:"storage_write_timeout"`
+
+	CacheSize        int      `json:"cache_size"`
+	CacheTTL         Duration `json:"cache_ttl"`
+	CacheNegativeTTL Duration `json:"cache_negative_ttl"`
+
+	CodeStrategy string `json:"code_strategy"`
+	CodeAlphabet string `json:"code_alphabet"`
+	CodeLength   int    `json:"code_length"`
+	CodeSalt     string `json:"code_salt"`
+
+	// UserTTL - срок жизни ссылок по умолчанию для отдельных пользователей,
+	// задается только в конфигурационном файле.
+	DefaultTTL    Duration            `json:"default_ttl"`
+	MaxTTL        Duration            `json:"max_ttl"`
+	UserTTL       map[string]Duration `json:"user_default_ttl"`
+	ReapInterval  Duration            `json:"reap_interval"`
+	ReapBatchSize int                 `json:"reap_batch_size"`
+	// ReapSchedule - расписание cron удаления истекших ссылок,
+	// если задано, заменяет ReapInterval.
+	ReapSchedule string `json:"reap_schedule"`
+	// PurgeDeletedInterval - интервал окончательного удаления ссылок,
+	// помеченных удаленными, пачками по ReapBatchSize.
+	PurgeDeletedInterval Duration `json:"purge_deleted_interval"`
+	// StatsInterval - интервал сбора статистики сервиса для /debug/vars.
+	StatsInterval Duration `json:"stats_interval"`
+
+	// JobJitter - наибольшая случайная задержка запуска фоновых задач по расписанию.
+	JobJitter Duration `json:"job_jitter"`
+	// JobTrigger включает ручной запуск фоновых задач через
+	// POST /api/internal/jobs/{name}/run. Доступ к /api/internal проверяется
+	// только по заголовку X-Real-IP, поэтому включать запуск можно лишь за
+	// прокси, который перезаписывает X-Real-IP адресом клиента, а к самому
+	// сервису снаружи прокси доступа нет.
+	JobTrigger bool `json:"job_trigger"`
+
+	PasswordAttempts int      `json:"password_attempts"`
+	PasswordWindow   Duration `json:"password_window"`
+
+	ClickBufferSize    int      `json:"click_buffer_size"`
+	ClickBatchSize     int      `json:"click_batch_size"`
+	ClickFlushInterval Duration `json:"click_flush_interval"`
+	ClickIPKey         string   `json:"click_ip_key"`
+
+	DeleteWorkers       int      `json:"delete_workers"`
+	DeleteBufferSize    int      `json:"delete_buffer_size"`
+	DeleteFlushInterval Duration `json:"delete_flush_interval"`
+	DeleteMaxAttempts   int      `json:"delete_max_attempts"`
+	DeleteRetryBackoff  Duration `json:"delete_retry_backoff"`
+	// DeleteJobRetention - сколько хранится выполненное или отложенное
+	// задание на удаление, чтобы клиент мог узнать его результат.
+	DeleteJobRetention Duration `json:"delete_job_retention"`
+	// DeleteDrainTimeout - сколько при остановке выполняются задания,
+	// оставшиеся в очереди, остальные выполнятся после запуска.
+	DeleteDrainTimeout Duration `json:"delete_drain_timeout"`
+
+	// TrustedSubnet - CIDR, из которого доступна внутренняя статистика,
+	// пустая строка закрывает к ней доступ.
+	TrustedSubnet string `json:"trusted_subnet"`
+
+	// GRPCAddr - адрес gRPC-сервера. По умолчанию пустой: gRPC выключен,
+	// пока адрес не задан явно.
+	GRPCAddr string `json:"grpc_address"`
+}
+
+// Duration - time.Duration, который в JSON задается строкой вида "1h30m".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON читает длительность из строки или числа наносекунд.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var raw any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	switch value := raw.(type) {
+	case float64:
+		d.Duration = time.Duration(value)
+	case string:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		d.Duration = duration
+	default:
+		return fmt.Errorf("invalid duration: %s", b)
+	}
+
+	return nil
+}
+
+// MarshalJSON записывает длительность строкой.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UserDefaultTTL возвращает сроки жизни ссылок по умолчанию для пользователей.
+func (c *Configs) UserDefaultTTL() map[string]time.Duration {
+	ttls := make(map[string]time.Duration, len(c.UserTTL))
+	for userID, ttl := range c.UserTTL {
+		ttls[userID] = ttl.Duration
+	}
+	return ttls
 }
 
 // NewConfigs конструктор конфига.
@@ -64,6 +182,211 @@ func (c *Configs) parseEnv() {
 		c.AddrDB = envAddrDB
 	}
 
+	// Проверка переменной окружения FILE_COMPACT_INTERVAL
+	if envInterval := os.Getenv("FILE_COMPACT_INTERVAL"); envInterval != "" {
+		if interval, err := time.ParseDuration(envInterval); err == nil {
+			c.FileCompactInterval.Duration = interval
+		}
+	}
+	// Проверка переменной окружения FILE_COMPACT_SIZE
+	if envSize := os.Getenv("FILE_COMPACT_SIZE"); envSize != "" {
+		if size, err := strconv.ParseInt(envSize, 10, 64); err == nil {
+			c.FileCompactSize = size
+		}
+	}
+	// Проверка переменной окружения FILE_CLICK_RETENTION
+	if envRetention := os.Getenv("FILE_CLICK_RETENTION"); envRetention != "" {
+		if retention, err := time.ParseDuration(envRetention); err == nil {
+			c.FileClickRetention.Duration = retention
+		}
+	}
+
+	// Проверка переменной окружения BOLT_BACKUP_INTERVAL
+	if envInterval := os.Getenv("BOLT_BACKUP_INTERVAL"); envInterval != "" {
+		if interval, err := time.ParseDuration(envInterval); err == nil {
+			c.BoltBackupInterval.Duration = interval
+		}
+	}
+
+	// Проверка переменных окружения STORAGE_READ_TIMEOUT и STORAGE_WRITE_TIMEOUT
+	if envTimeout := os.Getenv("STORAGE_READ_TIMEOUT"); envTimeout != "" {
+		if timeout, err := time.ParseDuration(envTimeout); err == nil {
+			c.StorageReadTimeout.Duration = timeout
+		}
+	}
+	if envTimeout := os.Getenv("STORAGE_WRITE_TIMEOUT"); envTimeout != "" {
+		if timeout, err := time.ParseDuration(envTimeout); err == nil {
+			c.StorageWriteTimeout.Duration = timeout
+		}
+	}
+
+	// Проверка переменных окружения CACHE_SIZE, CACHE_TTL и CACHE_NEGATIVE_TTL
+	if envSize := os.Getenv("CACHE_SIZE"); envSize != "" {
+		if size, err := strconv.Atoi(envSize); err == nil {
+			c.CacheSize = size
+		}
+	}
+	if envTTL := os.Getenv("CACHE_TTL"); envTTL != "" {
+		if ttl, err := time.ParseDuration(envTTL); err == nil {
+			c.CacheTTL.Duration = ttl
+		}
+	}
+	if envTTL := os.Getenv("CACHE_NEGATIVE_TTL"); envTTL != "" {
+		if ttl, err := time.ParseDuration(envTTL); err == nil {
+			c.CacheNegativeTTL.Duration = ttl
+		}
+	}
+
+	// Проверка переменных окружения CODE_STRATEGY, CODE_ALPHABET, CODE_LENGTH и CODE_SALT
+	if envStrategy := os.Getenv("CODE_STRATEGY"); envStrategy != "" {
+		c.CodeStrategy = envStrategy
+	}
+	if envAlphabet := os.Getenv("CODE_ALPHABET"); envAlphabet != "" {
+		c.CodeAlphabet = envAlphabet
+	}
+	if envLength := os.Getenv("CODE_LENGTH"); envLength != "" {
+		if length, err := strconv.Atoi(envLength); err == nil {
+			c.CodeLength = length
+		}
+	}
+	if envSalt := os.Getenv("CODE_SALT"); envSalt != "" {
+		c.CodeSalt = envSalt
+	}
+
+	// Проверка переменных окружения DEFAULT_TTL и MAX_TTL
+	if envTTL := os.Getenv("DEFAULT_TTL"); envTTL != "" {
+		if ttl, err := time.ParseDuration(envTTL); err == nil {
+			c.DefaultTTL.Duration = ttl
+		}
+	}
+	if envTTL := os.Getenv("MAX_TTL"); envTTL != "" {
+		if ttl, err := time.ParseDuration(envTTL); err == nil {
+			c.MaxTTL.Duration = ttl
+		}
+	}
+
+	// Проверка переменных окружения REAP_INTERVAL, REAP_BATCH_SIZE и REAP_SCHEDULE
+	if envInterval := os.Getenv("REAP_INTERVAL"); envInterval != "" {
+		if interval, err := time.ParseDuration(envInterval); err == nil {
+			c.ReapInterval.Duration = interval
+		}
+	}
+	if envSize := os.Getenv("REAP_BATCH_SIZE"); envSize != "" {
+		if size, err := strconv.Atoi(envSize); err == nil {
+			c.ReapBatchSize = size
+		}
+	}
+	if envSchedule := os.Getenv("REAP_SCHEDULE"); envSchedule != "" {
+		c.ReapSchedule = envSchedule
+	}
+
+	// Проверка переменных окружения PURGE_DELETED_INTERVAL и STATS_INTERVAL
+	if envInterval := os.Getenv("PURGE_DELETED_INTERVAL"); envInterval != "" {
+		if interval, err := time.ParseDuration(envInterval); err == nil {
+			c.PurgeDeletedInterval.Duration = interval
+		}
+	}
+	if envInterval := os.Getenv("STATS_INTERVAL"); envInterval != "" {
+		if interval, err := time.ParseDuration(envInterval); err == nil {
+			c.StatsInterval.Duration = interval
+		}
+	}
+
+	// Проверка переменной окружения JOB_JITTER
+	if envJitter := os.Getenv("JOB_JITTER"); envJitter != "" {
+		if jitter, err := time.ParseDuration(envJitter); err == nil {
+			c.JobJitter.Duration = jitter
+		}
+	}
+	// Проверка переменной окружения JOB_TRIGGER
+	if envTrigger := os.Getenv("JOB_TRIGGER"); envTrigger != "" {
+		if trigger, err := strconv.ParseBool(envTrigger); err == nil {
+			c.JobTrigger = trigger
+		}
+	}
+
+	// Проверка переменных окружения PASSWORD_ATTEMPTS и PASSWORD_WINDOW
+	if envAttempts := os.Getenv("PASSWORD_ATTEMPTS"); envAttempts != "" {
+		if attempts, err := strconv.Atoi(envAttempts); err == nil {
+			c.PasswordAttempts = attempts
+		}
+	}
+	if envWindow := os.Getenv("PASSWORD_WINDOW"); envWindow != "" {
+		if window, err := time.ParseDuration(envWindow); err == nil {
+			c.PasswordWindow.Duration = window
+		}
+	}
+
+	// Проверка переменных окружения CLICK_BUFFER_SIZE, CLICK_BATCH_SIZE,
+	// CLICK_FLUSH_INTERVAL и CLICK_IP_KEY
+	if envSize := os.Getenv("CLICK_BUFFER_SIZE"); envSize != "" {
+		if size, err := strconv.Atoi(envSize); err == nil {
+			c.ClickBufferSize = size
+		}
+	}
+	if envSize := os.Getenv("CLICK_BATCH_SIZE"); envSize != "" {
+		if size, err := strconv.Atoi(envSize); err == nil {
+			c.ClickBatchSize = size
+		}
+	}
+	if envInterval := os.Getenv("CLICK_FLUSH_INTERVAL"); envInterval != "" {
+		if interval, err := time.ParseDuration(envInterval); err == nil {
+			c.ClickFlushInterval.Duration = interval
+		}
+	}
+	if envKey := os.Getenv("CLICK_IP_KEY"); envKey != "" {
+		c.ClickIPKey = envKey
+	}
+
+	// Проверка переменных окружения DELETE_WORKERS, DELETE_BUFFER_SIZE,
+	// DELETE_FLUSH_INTERVAL, DELETE_MAX_ATTEMPTS, DELETE_RETRY_BACKOFF, DELETE_JOB_RETENTION
+	// и DELETE_DRAIN_TIMEOUT
+	if envWorkers := os.Getenv("DELETE_WORKERS"); envWorkers != "" {
+		if pool, err := strconv.Atoi(envWorkers); err == nil {
+			c.DeleteWorkers = pool
+		}
+	}
+	if envSize := os.Getenv("DELETE_BUFFER_SIZE"); envSize != "" {
+		if size, err := strconv.Atoi(envSize); err == nil {
+			c.DeleteBufferSize = size
+		}
+	}
+	if envInterval := os.Getenv("DELETE_FLUSH_INTERVAL"); envInterval != "" {
+		if interval, err := time.ParseDuration(envInterval); err == nil {
+			c.DeleteFlushInterval.Duration = interval
+		}
+	}
+	if envAttempts := os.Getenv("DELETE_MAX_ATTEMPTS"); envAttempts != "" {
+		if attempts, err := strconv.Atoi(envAttempts); err == nil {
+			c.DeleteMaxAttempts = attempts
+		}
+	}
+	if envBackoff := os.Getenv("DELETE_RETRY_BACKOFF"); envBackoff != "" {
+		if backoff, err := time.ParseDuration(envBackoff); err == nil {
+			c.DeleteRetryBackoff.Duration = backoff
+		}
+	}
+	if envRetention := os.Getenv("DELETE_JOB_RETENTION"); envRetention != "" {
+		if retention, err := time.ParseDuration(envRetention); err == nil {
+			c.DeleteJobRetention.Duration = retention
+		}
+	}
+	if envDrain := os.Getenv("DELETE_DRAIN_TIMEOUT"); envDrain != "" {
+		if drain, err := time.ParseDuration(envDrain); err == nil {
+			c.DeleteDrainTimeout.Duration = drain
+		}
+	}
+
+	// Проверка переменной окружения TRUSTED_SUBNET
+	if envSubnet := os.Getenv("TRUSTED_SUBNET"); envSubnet != "" {
+		c.TrustedSubnet = envSubnet
+	}
+
+	// Проверка переменной окружения GRPC_ADDRESS
+	if envGRPC := os.Getenv("GRPC_ADDRESS"); envGRPC != "" {
+		c.GRPCAddr = envGRPC
+	}
+
 	// Проверка переменной окружения ENABLE_HTTPS
 	if envHTTPS := os.Getenv("ENABLE_HTTPS"); envHTTPS == "true" {
 		*c.HTTPS = true
@@ -71,6 +394,20 @@ func (c *Configs) parseEnv() {
 
 }
 
+// TrustedNet возвращает доверенную подсеть, nil - если она не задана.
+func (c *Configs) TrustedNet() (*net.IPNet, error) {
+	if c.TrustedSubnet == "" {
+		return nil, nil
+	}
+
+	_, subnet, err := net.ParseCIDR(c.TrustedSubnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted subnet %q: %w", c.TrustedSubnet, err)
+	}
+
+	return subnet, nil
+}
+
 // loadFromFile загружает конфигурационный файл.
 func (c *Configs) loadFromFile() error {
 	file, err := os.Open(c.ConfigFile)
@@ -106,6 +443,83 @@ func (c *Configs) parseFlags() {
 	// Флаг -s отвечает за включение HTTPS в веб версии
 	c.HTTPS = flag.Bool("s", false, "Enable HTTPS")
 
+	// Флаги -compact-interval и -compact-size отвечают за компактизацию файла storage
+	// по расписанию и по достижении размера в байтах
+	flag.DurationVar(&c.FileCompactInterval.Duration, "compact-interval", 0, "file storage compaction interval")
+	flag.Int64Var(&c.FileCompactSize, "compact-size", 0, "file storage size in bytes that triggers compaction")
+	// Флаг -click-retention отвечает за окно хранения поминутной истории переходов в файле storage
+	flag.DurationVar(&c.FileClickRetention.Duration, "click-retention", 30*24*time.Hour, "file storage per-minute click history retention, 0 keeps all")
+
+	// Флаг -backup-interval отвечает за резервное копирование bbolt по расписанию
+	flag.DurationVar(&c.BoltBackupInterval.Duration, "backup-interval", 0, "bolt storage backup interval, 0 means manual backups only")
+
+	// Флаги -read-timeout и -write-timeout отвечают за таймауты операций с хранилищем
+	flag.DurationVar(&c.StorageReadTimeout.Duration, "read-timeout", 5*time.Second, "storage read operation timeout")
+	flag.DurationVar(&c.StorageWriteTimeout.Duration, "write-timeout", 10*time.Second, "storage write operation timeout")
+
+	// Флаги -cache-size, -cache-ttl и -cache-negative-ttl отвечают за кэш коротких ссылок,
+	// нулевой размер отключает кэш
+	flag.IntVar(&c.CacheSize, "cache-size", 10000, "redirect cache size in entries, 0 disables cache")
+	flag.DurationVar(&c.CacheTTL.Duration, "cache-ttl", 5*time.Minute, "redirect cache entry TTL")
+	flag.DurationVar(&c.CacheNegativeTTL.Duration, "cache-negative-ttl", 30*time.Second, "redirect cache TTL for unknown short URLs")
+
+	// Флаги -code-strategy, -code-alphabet, -code-length и -code-salt отвечают
+	// за генерацию коротких ссылок: random, sequential, hash или hashids;
+	// пустой алфавит и нулевая длина означают значения стратегии по умолчанию
+	flag.StringVar(&c.CodeStrategy, "code-strategy", "random", "short code strategy: random, sequential, hash or hashids")
+	flag.StringVar(&c.CodeAlphabet, "code-alphabet", "", "short code alphabet")
+	flag.IntVar(&c.CodeLength, "code-length", 0, "short code length, minimum length for sequential and hashids")
+	flag.StringVar(&c.CodeSalt, "code-salt", "", "salt for hash and hashids short codes")
+
+	// Флаги -default-ttl и -max-ttl отвечают за срок жизни ссылок:
+	// по умолчанию и наибольший допустимый, ноль - без ограничения
+	flag.DurationVar(&c.DefaultTTL.Duration, "default-ttl", 0, "default short URL TTL, 0 means no expiration")
+	flag.DurationVar(&c.MaxTTL.Duration, "max-ttl", 0, "maximum short URL TTL, 0 means unlimited")
+
+	// Флаги -reap-interval, -reap-schedule и -reap-batch-size отвечают за удаление
+	// истекших ссылок, без интервала и расписания удаление запускается только вручную
+	flag.DurationVar(&c.ReapInterval.Duration, "reap-interval", time.Minute, "expired short URL purge interval, 0 disables scheduled purge")
+	flag.StringVar(&c.ReapSchedule, "reap-schedule", "", "expired short URL purge cron schedule, overrides -reap-interval")
+	flag.IntVar(&c.ReapBatchSize, "reap-batch-size", 1000, "expired and deleted short URLs purged per batch")
+
+	// Флаги -purge-deleted-interval и -stats-interval отвечают за окончательное
+	// удаление ссылок, помеченных удаленными, и за сбор статистики сервиса,
+	// нулевой интервал оставляет задачу только ручной
+	flag.DurationVar(&c.PurgeDeletedInterval.Duration, "purge-deleted-interval", time.Hour, "deleted short URL purge interval, 0 disables scheduled purge")
+	flag.DurationVar(&c.StatsInterval.Duration, "stats-interval", time.Minute, "service stats rollup interval, 0 disables scheduled rollup")
+
+	// Флаг -job-jitter задает наибольшую случайную задержку фоновых задач
+	flag.DurationVar(&c.JobJitter.Duration, "job-jitter", 0, "maximum random delay of scheduled background jobs")
+	// Флаг -job-trigger включает ручной запуск фоновых задач, только за прокси,
+	// который выставляет X-Real-IP
+	flag.BoolVar(&c.JobTrigger, "job-trigger", false, "enable POST /api/internal/jobs/{name}/run, only behind a proxy that sets X-Real-IP")
+
+	// Флаги -password-attempts и -password-window ограничивают неудачные
+	// попытки ввода пароля ссылки, ноль попыток снимает ограничение
+	flag.IntVar(&c.PasswordAttempts, "password-attempts", 5, "failed password attempts per short URL within the window, 0 means unlimited")
+	flag.DurationVar(&c.PasswordWindow.Duration, "password-window", 15*time.Minute, "failed password attempts window")
+
+	// Флаги -click-* отвечают за запись переходов по ссылкам:
+	// размер буфера, размер пачки, интервал сохранения и ключ хеширования IP,
+	// нулевой буфер отключает запись, без ключа он случайный для процесса
+	flag.IntVar(&c.ClickBufferSize, "click-buffer-size", 10000, "buffered click events, 0 disables click analytics")
+	flag.IntVar(&c.ClickBatchSize, "click-batch-size", 500, "click events saved per batch")
+	flag.DurationVar(&c.ClickFlushInterval.Duration, "click-flush-interval", time.Second, "click events flush interval")
+	flag.StringVar(&c.ClickIPKey, "click-ip-key", "", "HMAC key for client IP hashes, random per process if empty")
+
+	flag.IntVar(&c.DeleteWorkers, "delete-workers", 4, "deletion worker pool size")
+	flag.IntVar(&c.DeleteBufferSize, "delete-buffer-size", 100, "URLs deleted per batch and queued deletion requests")
+	flag.DurationVar(&c.DeleteFlushInterval.Duration, "delete-flush-interval", time.Second, "deletion batch flush interval")
+	flag.IntVar(&c.DeleteMaxAttempts, "delete-max-attempts", 5, "deletion job attempts before it is moved aside")
+	flag.DurationVar(&c.DeleteRetryBackoff.Duration, "delete-retry-backoff", time.Second, "pause after the first failed deletion attempt, doubled on each retry")
+	flag.DurationVar(&c.DeleteJobRetention.Duration, "delete-job-retention", 24*time.Hour, "how long finished deletion jobs are kept for status requests")
+	flag.DurationVar(&c.DeleteDrainTimeout.Duration, "delete-drain-timeout", 10*time.Second, "how long queued deletion jobs are processed on shutdown")
+
+	// Флаг -t/-trusted-subnet задает CIDR доверенной подсети для /api/internal/stats
+	flag.StringVar(&c.TrustedSubnet, "t", "", "trusted subnet CIDR for internal stats")
+	flag.StringVar(&c.TrustedSubnet, "trusted-subnet", "", "trusted subnet CIDR for internal stats")
+	flag.StringVar(&c.GRPCAddr, "grpc-address", "", "gRPC server address host:port, gRPC is disabled when empty")
+
 	// Флаг -c/-config отвечает за парсинг конфигурационного JSON
 	flag.StringVar(&c.ConfigFile, "c", "", "config file")
 	flag.StringVar(&c.ConfigFile, "config", "", "config file")
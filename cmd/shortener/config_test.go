@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 )
 
 // TestParse - тестирует парсинг конфигурационной строки.
@@ -51,6 +52,10 @@ func TestParse(t *testing.T) {
 			if cfg.AddrDB != tt.addrDB {
 				t.Errorf("Ожидали %v, пришли %v", tt.addrDB, cfg.AddrDB)
 			}
+			// gRPC включается только явным адресом
+			if cfg.GRPCAddr != "" {
+				t.Errorf("Ожидали пустой адрес gRPC, пришли %v", cfg.GRPCAddr)
+			}
 		})
 	}
 }
@@ -95,3 +100,217 @@ func TestLoadConfig_Successful(t *testing.T) {
 		t.Errorf("loadFromFile() = %v", err)
 	}
 }
+
+// TestDuration_UnmarshalJSON - тестирует чтение длительности из JSON.
+func TestDuration_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{name: "string", data: `"1h30m"`, expected: 90 * time.Minute},
+		{name: "number", data: `1000`, expected: time.Microsecond},
+		{name: "bad_string", data: `"soon"`, wantErr: true},
+		{name: "bad_type", data: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Duration
+			err := json.Unmarshal([]byte(tt.data), &d)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if d.Duration != tt.expected {
+				t.Errorf("Ожидали %v, пришли %v", tt.expected, d.Duration)
+			}
+		})
+	}
+}
+
+// TestParseEnv_CodeStrategy - тестирует настройку генерации коротких ссылок из окружения.
+func TestParseEnv_CodeStrategy(t *testing.T) {
+	t.Setenv("CODE_STRATEGY", "hashids")
+	t.Setenv("CODE_ALPHABET", "abcdefghijklmnop")
+	t.Setenv("CODE_LENGTH", "8")
+	t.Setenv("CODE_SALT", "salt")
+
+	cfg := NewConfigs()
+	cfg.parseEnv()
+
+	if cfg.CodeStrategy != "hashids" {
+		t.Errorf("Ожидали %v, пришли %v", "hashids", cfg.CodeStrategy)
+	}
+	if cfg.CodeAlphabet != "abcdefghijklmnop" {
+		t.Errorf("Ожидали %v, пришли %v", "abcdefghijklmnop", cfg.CodeAlphabet)
+	}
+	if cfg.CodeLength != 8 {
+		t.Errorf("Ожидали %v, пришли %v", 8, cfg.CodeLength)
+	}
+	if cfg.CodeSalt != "salt" {
+		t.Errorf("Ожидали %v, пришли %v", "salt", cfg.CodeSalt)
+	}
+}
+
+// TestParseEnv_Expiry - тестирует настройку срока жизни ссылок и фоновых задач из окружения.
+func TestParseEnv_Expiry(t *testing.T) {
+	t.Setenv("DEFAULT_TTL", "24h")
+	t.Setenv("MAX_TTL", "720h")
+	t.Setenv("REAP_INTERVAL", "30s")
+	t.Setenv("REAP_BATCH_SIZE", "50")
+	t.Setenv("REAP_SCHEDULE", "*/5 * * * *")
+	t.Setenv("JOB_JITTER", "10s")
+	t.Setenv("JOB_TRIGGER", "true")
+	t.Setenv("PURGE_DELETED_INTERVAL", "6h")
+	t.Setenv("STATS_INTERVAL", "15s")
+	t.Setenv("BOLT_BACKUP_INTERVAL", "24h")
+
+	cfg := NewConfigs()
+	cfg.parseEnv()
+
+	if cfg.DefaultTTL.Duration != 24*time.Hour {
+		t.Errorf("Ожидали %v, пришли %v", 24*time.Hour, cfg.DefaultTTL.Duration)
+	}
+	if cfg.MaxTTL.Duration != 720*time.Hour {
+		t.Errorf("Ожидали %v, пришли %v", 720*time.Hour, cfg.MaxTTL.Duration)
+	}
+	if cfg.ReapInterval.Duration != 30*time.Second {
+		t.Errorf("Ожидали %v, пришли %v", 30*time.Second, cfg.ReapInterval.Duration)
+	}
+	if cfg.ReapBatchSize != 50 {
+		t.Errorf("Ожидали %v, пришли %v", 50, cfg.ReapBatchSize)
+	}
+	if cfg.ReapSchedule != "*/5 * * * *" {
+		t.Errorf("Ожидали %v, пришли %v", "*/5 * * * *", cfg.ReapSchedule)
+	}
+	if cfg.JobJitter.Duration != 10*time.Second {
+		t.Errorf("Ожидали %v, пришли %v", 10*time.Second, cfg.JobJitter.Duration)
+	}
+	if !cfg.JobTrigger {
+		t.Errorf("Ожидали %v, пришли %v", true, cfg.JobTrigger)
+	}
+	if cfg.PurgeDeletedInterval.Duration != 6*time.Hour {
+		t.Errorf("Ожидали %v, пришли %v", 6*time.Hour, cfg.PurgeDeletedInterval.Duration)
+	}
+	if cfg.StatsInterval.Duration != 15*time.Second {
+		t.Errorf("Ожидали %v, пришли %v", 15*time.Second, cfg.StatsInterval.Duration)
+	}
+	if cfg.BoltBackupInterval.Duration != 24*time.Hour {
+		t.Errorf("Ожидали %v, пришли %v", 24*time.Hour, cfg.BoltBackupInterval.Duration)
+	}
+}
+
+// TestParseEnv_PasswordAttempts - тестирует настройку ограничения попыток ввода пароля из окружения.
+func TestParseEnv_PasswordAttempts(t *testing.T) {
+	t.Setenv("PASSWORD_ATTEMPTS", "3")
+	t.Setenv("PASSWORD_WINDOW", "5m")
+
+	cfg := NewConfigs()
+	cfg.parseEnv()
+
+	if cfg.PasswordAttempts != 3 {
+		t.Errorf("Ожидали %v, пришли %v", 3, cfg.PasswordAttempts)
+	}
+	if cfg.PasswordWindow.Duration != 5*time.Minute {
+		t.Errorf("Ожидали %v, пришли %v", 5*time.Minute, cfg.PasswordWindow.Duration)
+	}
+}
+
+func TestParseEnv_Clicks(t *testing.T) {
+	t.Setenv("CLICK_BUFFER_SIZE", "100")
+	t.Setenv("CLICK_BATCH_SIZE", "10")
+	t.Setenv("CLICK_FLUSH_INTERVAL", "5s")
+	t.Setenv("CLICK_IP_KEY", "secret")
+	t.Setenv("FILE_CLICK_RETENTION", "168h")
+
+	cfg := NewConfigs()
+	cfg.parseEnv()
+
+	if cfg.ClickBufferSize != 100 {
+		t.Errorf("Ожидали %v, пришли %v", 100, cfg.ClickBufferSize)
+	}
+	if cfg.ClickBatchSize != 10 {
+		t.Errorf("Ожидали %v, пришли %v", 10, cfg.ClickBatchSize)
+	}
+	if cfg.ClickFlushInterval.Duration != 5*time.Second {
+		t.Errorf("Ожидали %v, пришли %v", 5*time.Second, cfg.ClickFlushInterval.Duration)
+	}
+	if cfg.ClickIPKey != "secret" {
+		t.Errorf("Ожидали %v, пришли %v", "secret", cfg.ClickIPKey)
+	}
+	if cfg.FileClickRetention.Duration != 168*time.Hour {
+		t.Errorf("Ожидали %v, пришли %v", 168*time.Hour, cfg.FileClickRetention.Duration)
+	}
+}
+
+func TestParseEnv_Deletion(t *testing.T) {
+	t.Setenv("DELETE_WORKERS", "8")
+	t.Setenv("DELETE_BUFFER_SIZE", "50")
+	t.Setenv("DELETE_FLUSH_INTERVAL", "2s")
+	t.Setenv("DELETE_MAX_ATTEMPTS", "3")
+	t.Setenv("DELETE_RETRY_BACKOFF", "500ms")
+	t.Setenv("DELETE_JOB_RETENTION", "1h")
+	t.Setenv("DELETE_DRAIN_TIMEOUT", "30s")
+
+	cfg := NewConfigs()
+	cfg.parseEnv()
+
+	if cfg.DeleteWorkers != 8 {
+		t.Errorf("Ожидали %v, пришли %v", 8, cfg.DeleteWorkers)
+	}
+	if cfg.DeleteBufferSize != 50 {
+		t.Errorf("Ожидали %v, пришли %v", 50, cfg.DeleteBufferSize)
+	}
+	if cfg.DeleteFlushInterval.Duration != 2*time.Second {
+		t.Errorf("Ожидали %v, пришли %v", 2*time.Second, cfg.DeleteFlushInterval.Duration)
+	}
+	if cfg.DeleteMaxAttempts != 3 {
+		t.Errorf("Ожидали %v, пришли %v", 3, cfg.DeleteMaxAttempts)
+	}
+	if cfg.DeleteRetryBackoff.Duration != 500*time.Millisecond {
+		t.Errorf("Ожидали %v, пришли %v", 500*time.Millisecond, cfg.DeleteRetryBackoff.Duration)
+	}
+	if cfg.DeleteJobRetention.Duration != time.Hour {
+		t.Errorf("Ожидали %v, пришли %v", time.Hour, cfg.DeleteJobRetention.Duration)
+	}
+	if cfg.DeleteDrainTimeout.Duration != 30*time.Second {
+		t.Errorf("Ожидали %v, пришли %v", 30*time.Second, cfg.DeleteDrainTimeout.Duration)
+	}
+}
+
+func TestParseEnv_TrustedSubnet(t *testing.T) {
+	t.Setenv("TRUSTED_SUBNET", "10.0.0.0/8")
+
+	cfg := NewConfigs()
+	cfg.parseEnv()
+
+	subnet, err := cfg.TrustedNet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subnet.String() != "10.0.0.0/8" {
+		t.Errorf("Ожидали %v, пришли %v", "10.0.0.0/8", subnet)
+	}
+
+	cfg.TrustedSubnet = ""
+	if subnet, err = cfg.TrustedNet(); err != nil || subnet != nil {
+		t.Errorf("пустая подсеть: ожидали nil, пришли %v, %v", subnet, err)
+	}
+
+	cfg.TrustedSubnet = "10.0.0.1"
+	if _, err = cfg.TrustedNet(); err == nil {
+		t.Error("ожидали ошибку для адреса без маски")
+	}
+}
+
+func TestParseEnv_GRPCAddr(t *testing.T) {
+	t.Setenv("GRPC_ADDRESS", "localhost:3300")
+
+	cfg := NewConfigs()
+	cfg.parseEnv()
+
+	if cfg.GRPCAddr != "localhost:3300" {
+		t.Errorf("Ожидали %v, пришли %v", "localhost:3300", cfg.GRPCAddr)
+	}
+}
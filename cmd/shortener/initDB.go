@@ -4,15 +4,35 @@ import (
 	"fmt"
 
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/boltstorage"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/db"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/filestorage"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/sqlitestorage"
 )
 
 // initDB инициализация базы.
-func initDB(addrDB, pathFile string) service.Storage {
+// fileOpts передаются файловому хранилищу.
+func initDB(addrDB, pathFile string, fileOpts ...filestorage.Option) service.Storage {
 
-	if addrDB != "" {
+	if sqlitestorage.IsDSN(addrDB) {
+		// Хранение во встроенной базе SQLite
+		fmt.Println("Using SQLite storage with DSN:", addrDB)
+		repoSQLite, err := sqlitestorage.NewSQLiteStorage(addrDB)
+		if err != nil {
+			fmt.Println("Fatal: ", err)
+		}
+		return repoSQLite
+	} else if boltstorage.IsDSN(addrDB) {
+		// Хранение во встроенной базе ключ-значение bbolt
+		fmt.Println("Using bbolt storage with DSN:", addrDB)
+		repoBolt, err := boltstorage.NewBoltStorage(addrDB)
+		if err != nil {
+			fmt.Println("Fatal: ", err)
+		}
+		return repoBolt
+	} else if addrDB != "" {
 		// Хранение в базе данных
 		fmt.Println("Using database storage with DSN:", addrDB)
 		// Инициализация базы данных и работа с ней
@@ -25,7 +45,7 @@ func initDB(addrDB, pathFile string) service.Storage {
 		// Хранение в файле
 		fmt.Println("Using database storage with file:", pathFile)
 		// Инициализируем хранение в файле
-		repoFile, err := filestorage.NewSaveFile(pathFile)
+		repoFile, err := filestorage.NewSaveFile(pathFile, fileOpts...)
 		if err != nil {
 			fmt.Println("Fatal: ", err)
 		}
@@ -38,3 +58,25 @@ func initDB(addrDB, pathFile string) service.Storage {
 		return repoMap
 	}
 }
+
+// initCodeGenerator создает стратегию генерации коротких ссылок по конфигурации
+// и передает ее хранилищу для пакетного сохранения.
+// Счетчиком для последовательных стратегий служит само хранилище.
+func initCodeGenerator(configs *Configs, repo service.Storage) (codegen.CodeGenerator, error) {
+	counter, _ := repo.(codegen.Counter)
+	gen, err := codegen.New(codegen.Config{
+		Strategy: configs.CodeStrategy,
+		Alphabet: configs.CodeAlphabet,
+		Length:   configs.CodeLength,
+		Salt:     configs.CodeSalt,
+	}, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	if receiver, ok := repo.(codegen.Receiver); ok {
+		receiver.SetCodeGenerator(gen)
+	}
+
+	return gen, nil
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -12,6 +17,8 @@ type MockPstStorage struct {
 }
 
 func TestInitDB(t *testing.T) {
+	dir := t.TempDir()
+
 	cases := []struct {
 		name     string
 		db       string
@@ -24,6 +31,18 @@ func TestInitDB(t *testing.T) {
 			file:     "",
 			expected: "*db.PstStorage",
 		},
+		{
+			name:     "WithSQLite",
+			db:       "sqlite://" + filepath.Join(dir, "shortener.db"),
+			file:     "",
+			expected: "*sqlitestorage.SQLiteStorage",
+		},
+		{
+			name:     "WithBolt",
+			db:       "bolt://" + filepath.Join(dir, "shortener.bolt"),
+			file:     "",
+			expected: "*boltstorage.BoltStorage",
+		},
 		{
 			name:     "WithFile",
 			db:       "",
@@ -40,9 +59,45 @@ func TestInitDB(t *testing.T) {
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := initDB(tt.db, tt.file)
+			defer storage.Close()
 			if reflect.TypeOf(storage).String() != tt.expected {
 				t.Errorf("Expected storage to be *db.PstStorage, got %T", storage)
 			}
 		})
 	}
 }
+
+func TestInitCodeGenerator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sequential", func(t *testing.T) {
+		repo := mapstorage.NewMapURL()
+		gen, err := initCodeGenerator(&Configs{CodeStrategy: codegen.StrategySequential}, repo)
+		if err != nil {
+			t.Fatalf("initCodeGenerator() error = %v", err)
+		}
+
+		// счетчиком служит хранилище
+		code, err := gen.Generate(ctx, "https://example.com", 0)
+		if err != nil || code != "1" {
+			t.Errorf("Ожидали 1, пришли %q, %v", code, err)
+		}
+		if id, _ := repo.NextID(ctx); id != 2 {
+			t.Errorf("Ожидали 2, пришли %d", id)
+		}
+	})
+
+	t.Run("without_counter", func(t *testing.T) {
+		_, err := initCodeGenerator(&Configs{CodeStrategy: codegen.StrategyHashids}, &MockPstStorage{})
+		if !errors.Is(err, codegen.ErrCounterRequired) {
+			t.Errorf("Ожидали %v, пришли %v", codegen.ErrCounterRequired, err)
+		}
+	})
+
+	t.Run("unknown_strategy", func(t *testing.T) {
+		_, err := initCodeGenerator(&Configs{CodeStrategy: "uuid"}, mapstorage.NewMapURL())
+		if !errors.Is(err, codegen.ErrUnknownStrategy) {
+			t.Errorf("Ожидали %v, пришли %v", codegen.ErrUnknownStrategy, err)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/boltstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/filestorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/workers"
+)
+
+// compactSizeCheck - как часто проверять размер файла для компактизации по порогу.
+const compactSizeCheck = 10 * time.Second
+
+// registerJobs регистрирует фоновые задачи сервиса в планировщике.
+//
+// Удаление истекших ссылок идет по cron из ReapSchedule или раз в
+// ReapInterval, окончательное удаление ссылок, помеченных удаленными, -
+// раз в PurgeDeletedInterval, сбор статистики - раз в StatsInterval.
+// Файловое хранилище компактизируется раз в FileCompactInterval и по
+// достижении FileCompactSize, bbolt копируется раз в BoltBackupInterval.
+// Задача с нулевым интервалом запускается только вручную.
+func registerJobs(scheduler *workers.Scheduler, configs *Configs, reaper *workers.WorkerReaper, rollup *workers.StatsRollup, repo service.Storage, logs *logger.Logger) error {
+	jitter := workers.WithJitter(configs.JobJitter.Duration)
+
+	var reapSchedule workers.Schedule
+	switch {
+	case configs.ReapSchedule != "":
+		schedule, err := workers.ParseCron(configs.ReapSchedule)
+		if err != nil {
+			return err
+		}
+		reapSchedule = schedule
+	case configs.ReapInterval.Duration > 0:
+		reapSchedule = workers.Every(configs.ReapInterval.Duration)
+	}
+	if configs.ReapBatchSize > 0 {
+		if err := scheduler.Register("reap_expired", reapSchedule, reaper.Run, jitter); err != nil {
+			return err
+		}
+		if err := scheduler.Register("purge_deleted", every(configs.PurgeDeletedInterval.Duration), reaper.RunDeleted, jitter); err != nil {
+			return err
+		}
+	}
+
+	if err := scheduler.Register("rollup_stats", every(configs.StatsInterval.Duration), rollup.Run, jitter); err != nil {
+		return err
+	}
+
+	if fileRepo, ok := repo.(*filestorage.SaveFile); ok {
+		err := scheduler.Register("compact_file", every(configs.FileCompactInterval.Duration), func(context.Context) error {
+			return skipInProgress(fileRepo.Compact())
+		}, jitter)
+		if err != nil {
+			return err
+		}
+
+		// проверка размера идет без jitter: она только смотрит размер файла,
+		// которым владеет один процесс, так что разносить запуски между
+		// копиями сервиса незачем, а задержка лишь дала бы файлу перерасти порог
+		if configs.FileCompactSize > 0 {
+			err = scheduler.Register("compact_file_size", workers.Every(compactSizeCheck), func(context.Context) error {
+				compacted, err := fileRepo.CompactIfLarger(configs.FileCompactSize)
+				if compacted {
+					logs.Info("File storage compacted by size")
+				}
+				return skipInProgress(err)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if boltRepo, ok := repo.(*boltstorage.BoltStorage); ok {
+		err := scheduler.Register("backup_bolt", every(configs.BoltBackupInterval.Duration), func(context.Context) error {
+			path, err := backup(boltRepo, time.Now())
+			if err != nil {
+				return err
+			}
+			logs.Info("Bolt storage backed up", logger.StringAttr("path", path))
+			return nil
+		}, jitter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// every возвращает расписание с запуском раз в interval,
+// nil - для ручной задачи при нулевом интервале.
+func every(interval time.Duration) workers.Schedule {
+	if interval <= 0 {
+		return nil
+	}
+	return workers.Every(interval)
+}
+
+// skipInProgress не считает ошибкой компактизацию, которую уже ведет
+// другая задача или сигнал администратора.
+func skipInProgress(err error) error {
+	if errors.Is(err, filestorage.ErrCompactionInProgress) {
+		return nil
+	}
+	return err
+}
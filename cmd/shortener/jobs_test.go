@@ -0,0 +1,108 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/boltstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/filestorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/workers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterJobs(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	repo := mapstorage.NewMapURL()
+	urlService := service.NewService(repo, logs)
+	reaper := workers.NewWorkerReaper(urlService, 10, logs)
+	rollup := workers.NewStatsRollup(urlService, logs)
+
+	// schedules возвращает расписания зарегистрированных задач по именам
+	schedules := func(t *testing.T, configs *Configs, repo service.Storage) map[string]string {
+		scheduler := workers.NewScheduler(logs)
+		require.NoError(t, registerJobs(scheduler, configs, reaper, rollup, repo, logs))
+
+		result := make(map[string]string)
+		for _, job := range scheduler.Jobs() {
+			result[job.Name] = job.Schedule
+		}
+		return result
+	}
+
+	t.Run("interval", func(t *testing.T) {
+		configs := &Configs{
+			ReapInterval:         Duration{time.Minute},
+			ReapBatchSize:        10,
+			PurgeDeletedInterval: Duration{time.Hour},
+			StatsInterval:        Duration{30 * time.Second},
+		}
+		assert.Equal(t, map[string]string{
+			"reap_expired":  "@every 1m0s",
+			"purge_deleted": "@every 1h0m0s",
+			"rollup_stats":  "@every 30s",
+		}, schedules(t, configs, repo))
+	})
+
+	t.Run("cron", func(t *testing.T) {
+		configs := &Configs{ReapInterval: Duration{time.Minute}, ReapSchedule: "0 3 * * *", ReapBatchSize: 10}
+		assert.Equal(t, map[string]string{
+			"reap_expired":  "0 3 * * *",
+			"purge_deleted": "manual",
+			"rollup_stats":  "manual",
+		}, schedules(t, configs, repo))
+	})
+
+	t.Run("manual", func(t *testing.T) {
+		configs := &Configs{ReapBatchSize: 10}
+		assert.Equal(t, map[string]string{
+			"reap_expired":  "manual",
+			"purge_deleted": "manual",
+			"rollup_stats":  "manual",
+		}, schedules(t, configs, repo))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		configs := &Configs{ReapInterval: Duration{time.Minute}, PurgeDeletedInterval: Duration{time.Hour}}
+		assert.Equal(t, map[string]string{"rollup_stats": "manual"}, schedules(t, configs, repo))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		fileRepo, err := filestorage.NewSaveFile(filepath.Join(t.TempDir(), "shortener.json"))
+		require.NoError(t, err)
+		defer fileRepo.Close()
+
+		configs := &Configs{FileCompactInterval: Duration{time.Hour}, FileCompactSize: 1 << 20}
+		assert.Equal(t, map[string]string{
+			"rollup_stats":      "manual",
+			"compact_file":      "@every 1h0m0s",
+			"compact_file_size": "@every 10s",
+		}, schedules(t, configs, fileRepo))
+
+		assert.Equal(t, map[string]string{
+			"rollup_stats": "manual",
+			"compact_file": "manual",
+		}, schedules(t, &Configs{}, fileRepo))
+	})
+
+	t.Run("bolt", func(t *testing.T) {
+		boltRepo, err := boltstorage.NewBoltStorage("bolt://" + filepath.Join(t.TempDir(), "shortener.bolt"))
+		require.NoError(t, err)
+		defer boltRepo.Close()
+
+		configs := &Configs{BoltBackupInterval: Duration{24 * time.Hour}}
+		assert.Equal(t, map[string]string{
+			"rollup_stats": "manual",
+			"backup_bolt":  "@every 24h0m0s",
+		}, schedules(t, configs, boltRepo))
+	})
+
+	t.Run("invalid_cron", func(t *testing.T) {
+		configs := &Configs{ReapSchedule: "every day", ReapBatchSize: 10}
+		assert.Error(t, registerJobs(workers.NewScheduler(logs), configs, reaper, rollup, repo, logs))
+	})
+}
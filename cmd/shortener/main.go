@@ -3,21 +3,29 @@ package main
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	middleware2 "github.com/go-chi/chi/v5/middleware"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/grpcserver"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/handlers"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/auth"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/boltstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/cache"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/filestorage"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/workers"
+	"google.golang.org/grpc"
 
 	_ "github.com/swaggo/http-swagger/example/go-chi/docs"
 
@@ -35,6 +43,9 @@ import (
 // @in header
 // @name Authorization
 
+// shutdownTimeout - сколько ждать завершения текущих запросов при остановке.
+const shutdownTimeout = 10 * time.Second
+
 // BuildVersion = определяет версию приложения
 // BuildDate = определяет дату сборки
 // BuildCommit = определяет коммит сборки
@@ -59,7 +70,7 @@ func main() {
 	logs.Info("Start logger")
 
 	// инициализируем хранилище.
-	repo := initDB(configs.AddrDB, configs.PathFile)
+	repo := initDB(configs.AddrDB, configs.PathFile, filestorage.WithClickRetention(configs.FileClickRetention.Duration))
 	logs.Info("Connecting DB")
 	defer func(repo service.Storage) {
 		err := repo.Close()
@@ -68,22 +79,91 @@ func main() {
 		}
 	}(repo)
 
+	// Через кэш идут все обращения по интерфейсу service.Storage: сервис,
+	// авторизация и задачи планировщика, которые работают через сервис.
+	// Напрямую с repo работают только возможности конкретного хранилища:
+	// счетчик кодов, компактизация файла и резервная копия bbolt. Кэш
+	// их не скрывает, а содержимое ссылок они не меняют.
+
+	// инициализируем генерацию коротких ссылок.
+	codes, err := initCodeGenerator(configs, repo)
+	if err != nil {
+		logs.Error("Fatal", logger.ErrAttr(err))
+		return
+	}
+	logs.Info("Short code strategy", logger.StringAttr("strategy", configs.CodeStrategy))
+
+	// оборачиваем хранилище кэшем коротких ссылок.
+	cached := repo
+	if configs.CacheSize > 0 {
+		cacheRepo := cache.New(
+			repo,
+			cache.WithSize(configs.CacheSize),
+			cache.WithTTL(configs.CacheTTL.Duration),
+			cache.WithNegativeTTL(configs.CacheNegativeTTL.Duration),
+			cache.WithLoadTimeout(configs.StorageReadTimeout.Duration),
+		)
+		// статистика доступна в /debug/vars
+		expvar.Publish("redirect_cache", expvar.Func(func() any {
+			return cacheRepo.Stats()
+		}))
+		cached = cacheRepo
+	}
+
 	// инициализируем сервис.
 	urlService := service.NewService(
-		repo,
+		cached,
 		logs,
+		service.WithReadTimeout(configs.StorageReadTimeout.Duration),
+		service.WithWriteTimeout(configs.StorageWriteTimeout.Duration),
+		service.WithCodeGenerator(codes),
+		service.WithDefaultTTL(configs.DefaultTTL.Duration),
+		service.WithUserTTL(configs.UserDefaultTTL()),
+		service.WithMaxTTL(configs.MaxTTL.Duration),
+		service.WithPasswordAttempts(configs.PasswordAttempts, configs.PasswordWindow.Duration),
 	)
 	logs.Info("Service created")
 
 	// инициализируем проверку авторизацию.
-	serviceAuth := auth.NewServiceAuth(repo)
+	serviceAuth := auth.NewServiceAuth(cached)
 	authorization := middleware.NewAuthMiddleware(serviceAuth)
 
+	// доверенная подсеть для внутренней статистики.
+	trustedNet, err := configs.TrustedNet()
+	if err != nil {
+		logs.Error("Fatal", logger.ErrAttr(err))
+		return
+	}
+
 	// инициализируем worker.
-	worker := workers.NewWorkerDeleted(urlService)
+	worker := workers.NewWorkerDeleted(urlService, configs.DeleteWorkers, configs.DeleteBufferSize,
+		configs.DeleteFlushInterval.Duration, logs,
+		workers.WithRetry(configs.DeleteMaxAttempts, configs.DeleteRetryBackoff.Duration),
+		workers.WithRetention(configs.DeleteJobRetention.Duration),
+		workers.WithDrainTimeout(configs.DeleteDrainTimeout.Duration))
+	reaper := workers.NewWorkerReaper(urlService, configs.ReapBatchSize, logs)
+	rollup := workers.NewStatsRollup(urlService, logs)
+	scheduler := workers.NewScheduler(logs)
+	if err = registerJobs(scheduler, configs, reaper, rollup, repo, logs); err != nil {
+		logs.Error("Fatal", logger.ErrAttr(err))
+		return
+	}
+	clicks := workers.NewClickRecorder(urlService, configs.ClickBufferSize, configs.ClickBatchSize,
+		configs.ClickFlushInterval.Duration, configs.ClickIPKey, logs)
+	// число отброшенных переходов доступно в /debug/vars
+	expvar.Publish("clicks_dropped", expvar.Func(func() any {
+		return clicks.Dropped()
+	}))
+	expvar.Publish("deletion_jobs", expvar.Func(func() any {
+		return worker.Stats()
+	}))
+	expvar.Publish("service_stats", expvar.Func(func() any {
+		return rollup.Stats()
+	}))
 
 	// передаем в хенлер сервис и baseURL.
-	shortHandlers := handlers.NewHandlers(urlService, configs.BaseURL, logs, worker)
+	shortHandlers := handlers.NewHandlers(urlService, configs.BaseURL, logs, worker,
+		handlers.WithClickRecorder(clicks), handlers.WithScheduler(scheduler))
 	logs.Info(fmt.Sprintf("Handlers created PORT: %s", configs.AddrServer))
 
 	// инициализировали роутер и создали Post и Get.
@@ -109,24 +189,90 @@ func main() {
 	})
 
 	r.Get("/{id}", shortHandlers.GetURL)
+	r.Post("/{id}", shortHandlers.UnlockURL)
 	r.Get("/ping", shortHandlers.GetPing)
 
 	r.Route("/api/user/urls", func(r chi.Router) {
 		r.Use(authorization.CheckAuthMiddleware)
 		r.Get("/", shortHandlers.GetUsersURLs)
 		r.Delete("/", shortHandlers.DeletionURLs)
+		r.Get("/{id}/stats", shortHandlers.GetURLStats)
+		r.Get("/deletions/{job}", shortHandlers.GetDeletionJob)
 	})
 
+	r.Route("/api/internal", func(r chi.Router) {
+		r.Use(middleware.TrustedSubnet(trustedNet))
+		r.Get("/stats", shortHandlers.GetInternalStats)
+		r.Get("/jobs", shortHandlers.GetJobs)
+		// ручной запуск меняет данные, поэтому он включается отдельно
+		if configs.JobTrigger {
+			r.Post("/jobs/{name}/run", shortHandlers.RunJob)
+		}
+	})
+
+	// Базовый контекст запросов: отменяется, если запросы не успели
+	// завершиться при остановке, чтобы прервать запросы к хранилищу.
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	// Создаем HTTP-сервер с поддержкой graceful shutdown
 	server := &http.Server{
 		Addr:    configs.AddrServer,
 		Handler: r,
+		BaseContext: func(net.Listener) context.Context {
+			return requestsCtx
+		},
 	}
 
 	// Настройка контекста для graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 
-	go worker.StartWorkerDeletion(ctx)
+	deletionDone := make(chan struct{})
+	go func() {
+		defer close(deletionDone)
+		worker.StartWorkerDeletion(ctx)
+	}()
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.StartScheduler(ctx)
+	}()
+	clicksDone := make(chan struct{})
+	go func() {
+		defer close(clicksDone)
+		clicks.StartWorkerClicks(ctx)
+	}()
+
+	// Компактизация журнала файлового хранилища по сигналу SIGUSR1 от
+	// администратора, по расписанию и размеру ее запускает планировщик.
+	if fileRepo, ok := repo.(*filestorage.SaveFile); ok {
+		go compactOnSignal(ctx, fileRepo, logs)
+	}
+
+	// Горячая резервная копия bbolt по сигналу SIGUSR1.
+	if boltRepo, ok := repo.(*boltstorage.BoltStorage); ok {
+		go backupOnSignal(ctx, boltRepo, logs)
+	}
+
+	// Запускаем gRPC-сервер рядом с HTTP, если задан его адрес
+	var grpcServer *grpc.Server
+	if configs.GRPCAddr != "" {
+		listener, err := net.Listen("tcp", configs.GRPCAddr)
+		if err != nil {
+			logs.Error("Fatal", logger.ErrAttr(err))
+			return
+		}
+
+		grpcServer = grpcserver.NewGRPCServer(
+			grpcserver.NewServer(urlService, configs.BaseURL, worker, logs, grpcserver.WithClickRecorder(clicks)),
+			serviceAuth, trustedNet, logs)
+		go func() {
+			logs.Info(fmt.Sprintf("Starting gRPC server on %s", configs.GRPCAddr))
+			if err := grpcServer.Serve(listener); err != nil {
+				logs.Error("Failed to start gRPC server:", logger.ErrAttr(err))
+			}
+		}()
+	}
 
 	// Запускаем сервер в горутине
 	go func() {
@@ -152,11 +298,40 @@ func main() {
 	logs.Info("Shutting down gracefully...")
 
 	// Останавливаем сервер и ожидаем завершения текущих запросов
-	if err := server.Shutdown(ctx); err != nil {
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		logs.Error("Failed to gracefully shutdown server:", logger.ErrAttr(err))
+		// отменяем незавершенные запросы вместе с их запросами к хранилищу
+		cancelRequests()
+	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
 	}
 
 	cancel() // Завершаем контекст для worker
 
+	// ждем сохранения переходов и удаления ссылок, оставшихся в буферах:
+	// очередь удаления разбирается не дольше DeleteDrainTimeout
+	<-clicksDone
+	<-deletionDone
+	<-schedulerDone
+
 	logs.Info("Shutdown complete")
 }
+
+// stopGRPC ждет завершения текущих вызовов gRPC,
+// а по истечении ctx прерывает их.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
@@ -1,6 +1,9 @@
 package errorscustom
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // ErrConflict указывает на конфликт данных в хранилище.
 var ErrConflict = errors.New("data conflict")
@@ -8,8 +11,111 @@ var ErrConflict = errors.New("data conflict")
 // ErrUserIDNotContext указывает на отсутствие контекста userID.
 var ErrUserIDNotContext = errors.New("userID not found or empty")
 
+// ErrNotFound указывает, что короткий URL не найден в хранилище.
+var ErrNotFound = errors.New("URL not found")
+
+// ErrShortURLCollision указывает, что короткая ссылка уже занята другим URL.
+var ErrShortURLCollision = errors.New("short URL already exists")
+
 // ErrDeletedURL указывает на удаление URL.
 var ErrDeletedURL = errors.New("URL DELETED")
 
+// ErrExpiredURL указывает, что срок жизни короткой ссылки истек.
+var ErrExpiredURL = errors.New("URL expired")
+
+// ErrInvalidExpiry указывает на некорректный срок жизни ссылки в запросе.
+var ErrInvalidExpiry = errors.New("invalid link expiration")
+
+// ErrExhaustedURL указывает, что переходы по ссылке с ограничением закончились.
+var ErrExhaustedURL = errors.New("URL click limit exhausted")
+
+// ErrInvalidMaxClicks указывает на некорректное ограничение числа переходов в запросе.
+var ErrInvalidMaxClicks = errors.New("invalid max_clicks")
+
+// ErrInvalidPassword указывает на некорректный пароль ссылки в запросе.
+var ErrInvalidPassword = errors.New("invalid link password")
+
+// ErrPasswordRequired указывает, что ссылка защищена паролем, а пароль не передан.
+var ErrPasswordRequired = errors.New("password required")
+
+// ErrWrongPassword указывает на неверный пароль ссылки.
+var ErrWrongPassword = errors.New("wrong password")
+
+// ErrTooManyAttempts указывает, что попытки ввода пароля ссылки временно исчерпаны.
+var ErrTooManyAttempts = errors.New("too many password attempts")
+
+// ErrInvalidStats указывает на некорректные параметры запроса статистики.
+var ErrInvalidStats = errors.New("invalid stats request")
+
 // ErrBadVarifyToken указывает что токен не прошел верификацию
 var ErrBadVarifyToken = errors.New("incorrect token")
+
+// ErrInvalidAlias указывает, что пользовательская короткая ссылка не прошла проверку.
+var ErrInvalidAlias = errors.New("invalid custom alias")
+
+// ErrReservedAlias указывает, что пользовательская короткая ссылка совпадает с маршрутом сервиса.
+var ErrReservedAlias = errors.New("custom alias is reserved")
+
+// ErrAliasTaken указывает, что пользовательская короткая ссылка уже занята.
+var ErrAliasTaken = errors.New("custom alias already taken")
+
+// AliasError - ошибка пользовательской короткой ссылки.
+// Err - одна из ErrInvalidAlias, ErrReservedAlias, ErrAliasTaken.
+type AliasError struct {
+	Alias string
+	Err   error
+}
+
+// Error возвращает текст ошибки вместе с пользовательской ссылкой.
+func (e *AliasError) Error() string {
+	return e.Err.Error() + ": " + e.Alias
+}
+
+// Unwrap возвращает причину ошибки для errors.Is.
+func (e *AliasError) Unwrap() error {
+	return e.Err
+}
+
+// AttemptsError - ошибка превышения числа попыток ввода пароля.
+// RetryAfter - через сколько можно повторить попытку.
+type AttemptsError struct {
+	RetryAfter time.Duration
+}
+
+// Error возвращает текст ошибки вместе со временем до следующей попытки.
+func (e *AttemptsError) Error() string {
+	return ErrTooManyAttempts.Error() + ", retry after " + e.RetryAfter.String()
+}
+
+// Unwrap возвращает ErrTooManyAttempts для errors.Is.
+func (e *AttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// ErrQueueFull указывает, что очередь заданий переполнена и запрос нужно повторить позже.
+var ErrQueueFull = errors.New("queue is full")
+
+// QueueFullError - ошибка переполненной очереди заданий.
+// RetryAfter - через сколько стоит повторить запрос.
+type QueueFullError struct {
+	RetryAfter time.Duration
+}
+
+// Error возвращает текст ошибки вместе со временем до повтора.
+func (e *QueueFullError) Error() string {
+	return ErrQueueFull.Error() + ", retry after " + e.RetryAfter.String()
+}
+
+// Unwrap возвращает ErrQueueFull для errors.Is.
+func (e *QueueFullError) Unwrap() error {
+	return ErrQueueFull
+}
+
+// ErrJobNotFound указывает, что фоновая задача не зарегистрирована в планировщике.
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobRunning указывает, что фоновая задача уже выполняется.
+var ErrJobRunning = errors.New("job is already running")
+
+// ErrSchedulerStopped указывает, что планировщик фоновых задач не запущен или остановлен.
+var ErrSchedulerStopped = errors.New("scheduler is not running")
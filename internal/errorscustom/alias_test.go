@@ -1,6 +1,7 @@
 package errorscustom
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,10 +20,18 @@ func TestErrors(t *testing.T) {
 			name: "ErrUserIDNotContext",
 			err:  ErrUserIDNotContext,
 		},
+		{
+			name: "ErrShortURLCollision",
+			err:  ErrShortURLCollision,
+		},
 		{
 			name: "ErrDeletedURL",
 			err:  ErrDeletedURL,
 		},
+		{
+			name: "ErrAliasTaken",
+			err:  ErrAliasTaken,
+		},
 	}
 
 	for _, tt := range tests {
@@ -31,3 +40,14 @@ func TestErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestAliasError(t *testing.T) {
+	err := error(&AliasError{Alias: "spring-sale", Err: ErrAliasTaken})
+
+	assert.ErrorIs(t, err, ErrAliasTaken)
+	assert.EqualError(t, err, "custom alias already taken: spring-sale")
+
+	var aliasErr *AliasError
+	assert.True(t, errors.As(err, &aliasErr))
+	assert.Equal(t, "spring-sale", aliasErr.Alias)
+}
@@ -0,0 +1,143 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/pb"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Ключи метаданных.
+const (
+	// authorizationKey - токен пользователя в запросе и новый токен в заголовке ответа.
+	authorizationKey = "authorization"
+	// realIPKey - IP-адрес клиента, который выставляет прокси.
+	realIPKey = "x-real-ip"
+	// refererKey - страница, с которой пришел клиент, для записи переходов.
+	refererKey = "referer"
+	// userAgentKey - клиент, для записи переходов.
+	userAgentKey = "user-agent"
+)
+
+// issuingMethods - методы, которые без действительного токена создают
+// нового пользователя, как AuthMiddleware.
+var issuingMethods = map[string]bool{
+	pb.Shortener_Shorten_FullMethodName:      true,
+	pb.Shortener_ShortenBatch_FullMethodName: true,
+}
+
+// protectedMethods - методы, которым нужен действительный токен,
+// как под CheckAuthMiddleware.
+var protectedMethods = map[string]bool{
+	pb.Shortener_ListUserURLs_FullMethodName:   true,
+	pb.Shortener_DeleteURLs_FullMethodName:     true,
+	pb.Shortener_GetDeletionJob_FullMethodName: true,
+}
+
+// trustedMethods - методы, доступные только из доверенной подсети.
+var trustedMethods = map[string]bool{
+	pb.Shortener_GetStats_FullMethodName: true,
+}
+
+// NewGRPCServer создает gRPC-сервер с перехватчиками логирования,
+// авторизации и проверки доверенной подсети и регистрирует в нем srv.
+func NewGRPCServer(srv pb.ShortenerServer, authService auth.AuthService, subnet *net.IPNet, logs *logger.Logger) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		LoggingInterceptor(logs),
+		TrustedSubnetInterceptor(subnet),
+		NewAuthInterceptor(authService).Unary,
+	))
+	pb.RegisterShortenerServer(server, srv)
+
+	return server
+}
+
+// LoggingInterceptor логирует метод, код ответа и длительность вызова.
+func LoggingInterceptor(logs *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		logs.Info(
+			"WithLogging",
+			"method", info.FullMethod,
+			"code", status.Code(err).String(),
+			"duration", time.Since(start),
+		)
+		return resp, err
+	}
+}
+
+// TrustedSubnetInterceptor пропускает вызовы trustedMethods, только если IP
+// из метаданных x-real-ip входит в доверенную подсеть, иначе PERMISSION_DENIED.
+func TrustedSubnetInterceptor(subnet *net.IPNet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if trustedMethods[info.FullMethod] && !middleware.InSubnet(subnet, firstValue(ctx, realIPKey)) {
+			return nil, status.Error(codes.PermissionDenied, "access denied")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// AuthInterceptor проверяет токен пользователя из метаданных authorization
+// и кладет пользователя в контекст по middleware.UserIDContextKey.
+type AuthInterceptor struct {
+	authService auth.AuthService
+}
+
+// NewAuthInterceptor - конструктор перехватчика авторизации.
+func NewAuthInterceptor(authService auth.AuthService) *AuthInterceptor {
+	return &AuthInterceptor{
+		authService: authService,
+	}
+}
+
+// Unary - перехватчик унарных вызовов. Для issuingMethods без действительного
+// токена создает пользователя и отдает токен в заголовке ответа, для
+// protectedMethods отвечает UNAUTHENTICATED, остальные методы пропускает.
+func (a *AuthInterceptor) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !issuingMethods[info.FullMethod] && !protectedMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	userID, err := a.authService.VerifyUser(firstValue(ctx, authorizationKey))
+	if err != nil {
+		if protectedMethods[info.FullMethod] {
+			return nil, status.Error(codes.Unauthenticated, "invalid or missing token")
+		}
+
+		userID = uuid.New().String()
+		token, err := a.authService.CreatTokenForUser(userID)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to generate auth token")
+		}
+		if err = grpc.SetHeader(ctx, metadata.Pairs(authorizationKey, token)); err != nil {
+			return nil, status.Error(codes.Internal, "failed to send auth token")
+		}
+	}
+
+	return handler(context.WithValue(ctx, middleware.UserIDContextKey, userID), req)
+}
+
+// firstValue возвращает первое значение ключа из входящих метаданных.
+func firstValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
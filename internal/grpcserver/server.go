@@ -0,0 +1,244 @@
+// Package grpcserver - gRPC API сокращателя ссылок поверх service.Service.
+//
+// Методы повторяют HTTP API из пакета handlers, ошибки сервиса
+// переводятся в коды gRPC в toStatus.
+package grpcserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/pb"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/workers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Server - реализация pb.ShortenerServer.
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	service *service.Service
+	baseURL string
+	worker  workers.Worker
+	clicks  workers.Recorder
+	logger  *logger.Logger
+}
+
+// Option - опция gRPC-сервера.
+type Option func(*Server)
+
+// WithClickRecorder задает запись переходов по коротким ссылкам в Resolve.
+// Без нее переходы не записываются.
+func WithClickRecorder(recorder workers.Recorder) Option {
+	return func(s *Server) {
+		s.clicks = recorder
+	}
+}
+
+// NewServer - конструктор gRPC-сервера.
+func NewServer(service *service.Service, baseURL string, worker workers.Worker, logs *logger.Logger, opts ...Option) *Server {
+	s := &Server{
+		service: service,
+		baseURL: baseURL,
+		worker:  worker,
+		logger:  logs,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// userID возвращает пользователя, которого AuthInterceptor положил в контекст.
+func userID(ctx context.Context) string {
+	userID, _ := ctx.Value(middleware.UserIDContextKey).(string)
+	return userID
+}
+
+// Shorten сокращает URL, при необходимости под пользовательской ссылкой.
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+
+	opts, err := linkOptions(req)
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	var shortURL string
+	if req.GetCustomAlias() != "" {
+		shortURL, err = s.service.SaveCustomURL(ctx, req.GetUrl(), req.GetCustomAlias(), userID(ctx), opts)
+	} else {
+		shortURL, err = s.service.SaveURL(ctx, req.GetUrl(), userID(ctx), opts)
+	}
+	if err != nil {
+		return nil, s.conflictStatus(err, shortURL)
+	}
+
+	return &pb.ShortenResponse{Result: s.resultURL(shortURL)}, nil
+}
+
+// ShortenBatch сокращает пакет URL.
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	if len(req.GetUrls()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "urls are required")
+	}
+
+	urls := make([]models.MultipleURL, 0, len(req.GetUrls()))
+	for _, item := range req.GetUrls() {
+		url := models.MultipleURL{
+			CorrelationID: item.GetCorrelationId(),
+			OriginalURL:   item.GetOriginalUrl(),
+			CustomAlias:   item.GetCustomAlias(),
+			MaxClicks:     int(item.GetMaxClicks()),
+		}
+		if item.GetExpiresAt() != nil {
+			expiresAt := item.GetExpiresAt().AsTime()
+			url.ExpiresAt = &expiresAt
+		}
+		urls = append(urls, url)
+	}
+
+	result, err := s.service.SaveSliceOfDB(ctx, urls, s.baseURL, userID(ctx))
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	resp := &pb.ShortenBatchResponse{Urls: make([]*pb.BatchResult, 0, len(result))}
+	for _, url := range result {
+		resp.Urls = append(resp.Urls, &pb.BatchResult{
+			CorrelationId: url.CorrelationID,
+			ShortUrl:      url.ShortURL,
+		})
+	}
+
+	return resp, nil
+}
+
+// Resolve возвращает оригинальный URL короткой ссылки.
+func (s *Server) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	if req.GetShortUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "short_url is required")
+	}
+
+	url, err := s.service.OpenURL(ctx, req.GetShortUrl(), req.GetPassword())
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	s.recordClick(ctx, req.GetShortUrl())
+
+	return &pb.ResolveResponse{OriginalUrl: url}, nil
+}
+
+// recordClick записывает переход по короткой ссылке, если запись включена.
+// Реферер и User-Agent берутся из метаданных, адрес - из метаданных
+// x-real-ip, которые выставляет прокси, а без них - у соединения клиента.
+func (s *Server) recordClick(ctx context.Context, shortURL string) {
+	if s.clicks == nil {
+		return
+	}
+
+	remoteAddr := firstValue(ctx, realIPKey)
+	if p, ok := peer.FromContext(ctx); remoteAddr == "" && ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	s.clicks.RecordClick(shortURL, firstValue(ctx, refererKey), firstValue(ctx, userAgentKey), remoteAddr)
+}
+
+// ListUserURLs возвращает ссылки пользователя.
+func (s *Server) ListUserURLs(ctx context.Context, _ *emptypb.Empty) (*pb.ListUserURLsResponse, error) {
+	urls, err := s.service.GetAllURL(ctx, userID(ctx), s.baseURL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, s.toStatus(err)
+	}
+
+	resp := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(urls))}
+	for _, url := range urls {
+		resp.Urls = append(resp.Urls, &pb.UserURL{
+			ShortUrl:    url.ShortURL,
+			OriginalUrl: url.OriginalURL,
+		})
+	}
+
+	return resp, nil
+}
+
+// DeleteURLs отправляет ссылки пользователя на удаление воркеру и
+// возвращает ID задания. Если очередь воркера полна, отвечает
+// RESOURCE_EXHAUSTED с паузой до повтора.
+func (s *Server) DeleteURLs(ctx context.Context, req *pb.DeleteURLsRequest) (*pb.DeleteURLsResponse, error) {
+	id, err := s.worker.SendDeletionRequestToWorker(ctx, workers.DeletionRequest{
+		User: userID(ctx),
+		URLs: req.GetShortUrls(),
+	})
+	if errors.Is(err, errorscustom.ErrQueueFull) {
+		return nil, s.toStatus(err)
+	}
+	if err != nil {
+		s.logger.Error("error send to deletion worker request", logger.ErrAttr(err))
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &pb.DeleteURLsResponse{JobId: id}, nil
+}
+
+// GetDeletionJob возвращает состояние задания на удаление пользователя.
+func (s *Server) GetDeletionJob(ctx context.Context, req *pb.GetDeletionJobRequest) (*pb.DeletionJob, error) {
+	if req.GetJobId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "job_id must be positive")
+	}
+
+	job, err := s.service.DeletionJob(ctx, userID(ctx), req.GetJobId())
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	result := models.NewDeletionJobStatus(*job)
+	return &pb.DeletionJob{
+		JobId:    result.JobID,
+		Status:   result.Status,
+		Urls:     result.URLs,
+		Deleted:  result.Deleted,
+		Skipped:  result.Skipped,
+		Attempts: int32(result.Attempts),
+	}, nil
+}
+
+// Ping проверяет соединение с хранилищем.
+func (s *Server) Ping(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := s.service.Ping(ctx); err != nil {
+		s.logger.Error("Error = ", logger.ErrAttr(err))
+		return nil, status.Error(codes.Unavailable, "storage is unavailable")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetStats возвращает число сокращенных URL и пользователей.
+func (s *Server) GetStats(ctx context.Context, _ *emptypb.Empty) (*pb.StatsResponse, error) {
+	stats, err := s.service.GetStats(ctx)
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	return &pb.StatsResponse{
+		Urls:  int32(stats.URLs),
+		Users: int32(stats.Users),
+	}, nil
+}
+
+// resultURL собирает короткую ссылку с базовым адресом.
+func (s *Server) resultURL(shortURL string) string {
+	return s.baseURL + "/" + shortURL
+}
@@ -0,0 +1,301 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/pb"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/auth"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/workers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	testUser  = "4b0f5e8e-6f4a-4f0c-9a43-3f1d2f0c8a11"
+	testToken = "valid-token"
+)
+
+// newTestClient поднимает сервер поверх bufconn и возвращает клиента к нему.
+func newTestClient(t *testing.T, authService auth.AuthService, worker workers.Worker, opts ...Option) pb.ShortenerClient {
+	t.Helper()
+
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	return newServiceClient(t, service.NewService(mapstorage.NewMapURL(), logs), authService, worker, opts...)
+}
+
+// newServiceClient поднимает сервер поверх bufconn с сервисом urlService
+// и возвращает клиента к нему.
+func newServiceClient(t *testing.T, urlService *service.Service, authService auth.AuthService, worker workers.Worker, opts ...Option) pb.ShortenerClient {
+	t.Helper()
+
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer(NewServer(urlService, "http://localhost:8080", worker, logs, opts...), authService, subnet, logs)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewShortenerClient(conn)
+}
+
+// withToken добавляет токен пользователя в метаданные запроса.
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, token)
+}
+
+func TestServer_Shorten(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	authService := auth.NewMockAuthService(ctrl)
+	authService.EXPECT().VerifyUser("").Return("", errors.New("token is empty")).AnyTimes()
+	authService.EXPECT().VerifyUser(testToken).Return(testUser, nil).AnyTimes()
+	authService.EXPECT().CreatTokenForUser(gomock.Any()).Return(testToken, nil)
+
+	client := newTestClient(t, authService, workers.NewMockWorker(ctrl))
+	ctx := context.Background()
+
+	t.Run("issue_token", func(t *testing.T) {
+		var header metadata.MD
+		resp, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/a", CustomAlias: "alias"}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/alias", resp.GetResult())
+		assert.Equal(t, []string{testToken}, header.Get(authorizationKey))
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		var header metadata.MD
+		_, err := client.Shorten(withToken(ctx, testToken), &pb.ShortenRequest{Url: "https://example.com/a"}, grpc.Header(&header))
+		st := status.Convert(err)
+		require.Equal(t, codes.AlreadyExists, st.Code())
+		require.Len(t, st.Details(), 1)
+		info, ok := st.Details()[0].(*errdetails.ResourceInfo)
+		require.True(t, ok)
+		assert.Equal(t, "http://localhost:8080/alias", info.GetResourceName())
+		assert.Empty(t, header.Get(authorizationKey))
+	})
+
+	t.Run("alias_taken", func(t *testing.T) {
+		_, err := client.Shorten(withToken(ctx, testToken), &pb.ShortenRequest{Url: "https://example.com/b", CustomAlias: "alias"})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("invalid_argument", func(t *testing.T) {
+		_, err := client.Shorten(withToken(ctx, testToken), &pb.ShortenRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.Shorten(withToken(ctx, testToken), &pb.ShortenRequest{
+			Url:       "https://example.com/c",
+			ExpiresAt: timestamppb.Now(),
+			Ttl:       durationpb.New(0),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+// clickLog запоминает записанные переходы.
+type clickLog struct {
+	mu     sync.Mutex
+	clicks []models.Click
+}
+
+func (c *clickLog) Record(shortURL string, r *http.Request) {
+	c.RecordClick(shortURL, r.Referer(), r.UserAgent(), r.RemoteAddr)
+}
+
+func (c *clickLog) RecordClick(shortURL, referrer, userAgent, remoteAddr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clicks = append(c.clicks, models.Click{ShortURL: shortURL, Referrer: referrer, UserAgent: userAgent, IPHash: remoteAddr})
+}
+
+func TestServer_ShortenBatchAndResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	authService := auth.NewMockAuthService(ctrl)
+	authService.EXPECT().VerifyUser(testToken).Return(testUser, nil).AnyTimes()
+
+	clicks := &clickLog{}
+	client := newTestClient(t, authService, workers.NewMockWorker(ctrl), WithClickRecorder(clicks))
+	ctx := metadata.AppendToOutgoingContext(withToken(context.Background(), testToken),
+		refererKey, "https://ref.example", realIPKey, "203.0.113.7")
+
+	batch, err := client.ShortenBatch(ctx, &pb.ShortenBatchRequest{Urls: []*pb.BatchItem{
+		{CorrelationId: "1", OriginalUrl: "https://example.com/1", CustomAlias: "one"},
+		{CorrelationId: "2", OriginalUrl: "https://example.com/2"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, batch.GetUrls(), 2)
+	assert.Equal(t, "1", batch.GetUrls()[0].GetCorrelationId())
+	assert.Equal(t, "http://localhost:8080/one", batch.GetUrls()[0].GetShortUrl())
+
+	resp, err := client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "one"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", resp.GetOriginalUrl())
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/secret", CustomAlias: "secret", Password: "hunter22"})
+	require.NoError(t, err)
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "secret"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "secret", Password: "wrong"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err = client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "secret", Password: "hunter22"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/secret", resp.GetOriginalUrl())
+
+	// записываются только успешные переходы
+	clicks.mu.Lock()
+	defer clicks.mu.Unlock()
+	require.Len(t, clicks.clicks, 2)
+	assert.Equal(t, "one", clicks.clicks[0].ShortURL)
+	assert.Equal(t, "secret", clicks.clicks[1].ShortURL)
+	assert.Equal(t, "https://ref.example", clicks.clicks[0].Referrer)
+	assert.Contains(t, clicks.clicks[0].UserAgent, "grpc-go")
+	assert.Equal(t, "203.0.113.7", clicks.clicks[0].IPHash)
+}
+
+func TestServer_UserURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	authService := auth.NewMockAuthService(ctrl)
+	authService.EXPECT().VerifyUser("").Return("", errors.New("token is empty")).AnyTimes()
+	authService.EXPECT().VerifyUser(testToken).Return(testUser, nil).AnyTimes()
+	worker := workers.NewMockWorker(ctrl)
+
+	client := newTestClient(t, authService, worker)
+	ctx := withToken(context.Background(), testToken)
+
+	_, err := client.ListUserURLs(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.DeleteURLs(context.Background(), &pb.DeleteURLsRequest{ShortUrls: []string{"mine"}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	list, err := client.ListUserURLs(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	assert.Empty(t, list.GetUrls())
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/mine", CustomAlias: "mine"})
+	require.NoError(t, err)
+
+	list, err = client.ListUserURLs(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, list.GetUrls(), 1)
+	assert.Equal(t, "https://example.com/mine", list.GetUrls()[0].GetOriginalUrl())
+
+	worker.EXPECT().SendDeletionRequestToWorker(gomock.Any(), workers.DeletionRequest{User: testUser, URLs: []string{"mine"}}).Return(int64(1), nil)
+	deleted, err := client.DeleteURLs(ctx, &pb.DeleteURLsRequest{ShortUrls: []string{"mine"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted.GetJobId())
+
+	worker.EXPECT().SendDeletionRequestToWorker(gomock.Any(), gomock.Any()).Return(int64(0), &errorscustom.QueueFullError{RetryAfter: 2 * time.Second})
+	_, err = client.DeleteURLs(ctx, &pb.DeleteURLsRequest{ShortUrls: []string{"mine"}})
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, info.GetRetryDelay().AsDuration())
+
+	worker.EXPECT().SendDeletionRequestToWorker(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("storage failed"))
+	_, err = client.DeleteURLs(ctx, &pb.DeleteURLsRequest{ShortUrls: []string{"mine"}})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestServer_GetDeletionJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	authService := auth.NewMockAuthService(ctrl)
+	authService.EXPECT().VerifyUser("").Return("", errors.New("token is empty")).AnyTimes()
+	authService.EXPECT().VerifyUser(testToken).Return(testUser, nil).AnyTimes()
+	authService.EXPECT().VerifyUser("stranger-token").Return("e0c3d5a1-0b8f-4d2e-9c51-7a6f4b2d9e10", nil).AnyTimes()
+
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	urlService := service.NewService(mapstorage.NewMapURL(), logs)
+	client := newServiceClient(t, urlService, authService, workers.NewWorkerDeleted(urlService, 1, 10, 0, logs))
+	ctx := withToken(context.Background(), testToken)
+
+	deleted, err := client.DeleteURLs(ctx, &pb.DeleteURLsRequest{ShortUrls: []string{"aaa", "bbb"}})
+	require.NoError(t, err)
+	require.Positive(t, deleted.GetJobId())
+
+	job, err := client.GetDeletionJob(ctx, &pb.GetDeletionJobRequest{JobId: deleted.GetJobId()})
+	require.NoError(t, err)
+	assert.Equal(t, deleted.GetJobId(), job.GetJobId())
+	assert.Equal(t, models.JobQueued, job.GetStatus())
+	assert.Equal(t, []string{"aaa", "bbb"}, job.GetUrls())
+	assert.Empty(t, job.GetDeleted())
+	assert.Zero(t, job.GetAttempts())
+
+	// чужое задание не отличается от несуществующего
+	_, err = client.GetDeletionJob(withToken(context.Background(), "stranger-token"), &pb.GetDeletionJobRequest{JobId: deleted.GetJobId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetDeletionJob(ctx, &pb.GetDeletionJobRequest{JobId: deleted.GetJobId() + 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetDeletionJob(ctx, &pb.GetDeletionJobRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GetDeletionJob(context.Background(), &pb.GetDeletionJobRequest{JobId: deleted.GetJobId()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_GetStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	authService := auth.NewMockAuthService(ctrl)
+	authService.EXPECT().VerifyUser(testToken).Return(testUser, nil).AnyTimes()
+
+	client := newTestClient(t, authService, workers.NewMockWorker(ctrl))
+	ctx := withToken(context.Background(), testToken)
+
+	_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/stats"})
+	require.NoError(t, err)
+
+	_, err = client.GetStats(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetStats(metadata.AppendToOutgoingContext(context.Background(), realIPKey, "192.168.0.1"), &emptypb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	stats, err := client.GetStats(metadata.AppendToOutgoingContext(context.Background(), realIPKey, "10.1.2.3"), &emptypb.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), stats.GetUrls())
+	assert.Equal(t, int32(1), stats.GetUsers())
+
+	_, err = client.Ping(context.Background(), &emptypb.Empty{})
+	require.NoError(t, err)
+}
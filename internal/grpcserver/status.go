@@ -0,0 +1,107 @@
+package grpcserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// linkOptions собирает параметры ссылки из запроса.
+// expires_at и ttl взаимоисключающие, ttl отсчитывается от текущего момента.
+func linkOptions(req *pb.ShortenRequest) (models.LinkOptions, error) {
+	opts := models.LinkOptions{
+		MaxClicks: int(req.GetMaxClicks()),
+		Password:  req.GetPassword(),
+	}
+
+	switch {
+	case req.GetExpiresAt() != nil && req.GetTtl() != nil:
+		return models.LinkOptions{}, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", errorscustom.ErrInvalidExpiry)
+	case req.GetExpiresAt() != nil:
+		expiresAt := req.GetExpiresAt().AsTime()
+		opts.ExpiresAt = &expiresAt
+	case req.GetTtl() != nil:
+		ttl := req.GetTtl().AsDuration()
+		if ttl <= 0 {
+			return models.LinkOptions{}, fmt.Errorf("%w: ttl must be positive", errorscustom.ErrInvalidExpiry)
+		}
+		expiresAt := time.Now().Add(ttl)
+		opts.ExpiresAt = &expiresAt
+	}
+
+	return opts, nil
+}
+
+// conflictStatus переводит ошибку сохранения URL в статус gRPC. Для уже
+// сокращенного URL возвращает ALREADY_EXISTS с существующей короткой ссылкой.
+func (s *Server) conflictStatus(err error, shortURL string) error {
+	if !errors.Is(err, errorscustom.ErrConflict) {
+		return s.toStatus(err)
+	}
+
+	st, detailsErr := status.New(codes.AlreadyExists, err.Error()).WithDetails(&errdetails.ResourceInfo{
+		ResourceType: "short_url",
+		ResourceName: s.resultURL(shortURL),
+	})
+	if detailsErr != nil {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+
+	return st.Err()
+}
+
+// toStatus переводит ошибку сервиса в статус gRPC, как handlers в коды HTTP:
+// удаленная, истекшая и исчерпанная ссылка - NOT_FOUND, как и несуществующая.
+func (s *Server) toStatus(err error) error {
+	var (
+		aliasErr     *errorscustom.AliasError
+		attemptsErr  *errorscustom.AttemptsError
+		queueFullErr *errorscustom.QueueFullError
+	)
+	switch {
+	case errors.As(err, &aliasErr) && errors.Is(err, errorscustom.ErrAliasTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.As(err, &aliasErr),
+		errors.Is(err, errorscustom.ErrInvalidExpiry),
+		errors.Is(err, errorscustom.ErrInvalidMaxClicks),
+		errors.Is(err, errorscustom.ErrInvalidPassword):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &attemptsErr):
+		return retryStatus(err, attemptsErr.RetryAfter)
+	case errors.As(err, &queueFullErr):
+		return retryStatus(err, queueFullErr.RetryAfter)
+	case errors.Is(err, errorscustom.ErrPasswordRequired):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, errorscustom.ErrWrongPassword):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, errorscustom.ErrNotFound),
+		errors.Is(err, errorscustom.ErrDeletedURL),
+		errors.Is(err, errorscustom.ErrExpiredURL),
+		errors.Is(err, errorscustom.ErrExhaustedURL):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		s.logger.Error("Error internal server = ", logger.ErrAttr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// retryStatus возвращает RESOURCE_EXHAUSTED с паузой до повтора запроса.
+func retryStatus(err error, retryAfter time.Duration) error {
+	st, detailsErr := status.New(codes.ResourceExhausted, err.Error()).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter),
+	})
+	if detailsErr != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// deletionJobPath - путь к состоянию задания на удаление без ID задания.
+const deletionJobPath = "/api/user/urls/deletions/"
+
+// GetDeletionJob godoc
+// @Tags GET
+// @Summary Get deletion job status
+// @Description Get the status of a deletion request of the user: queued, running, done or failed, and which short URLs were deleted or skipped because the user does not own them
+// @Security ApiKeyAuth
+// @Produce json
+// @Param job path int true "Deletion job ID"
+// @Success 200 {object} models.DeletionJobStatus "OK"
+// @Failure 400 "Invalid job ID"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not found or owned by another user"
+// @Failure 500 "Internal server error"
+// @Router /api/user/urls/deletions/{job} [get]
+// GetDeletionJob возвращает состояние задания на удаление пользователя.
+func (h *Handlers) GetDeletionJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDContextKey).(string)
+	if !ok || userID == "" {
+		h.logger.Error("Error = ", logger.ErrAttr(errorscustom.ErrUserIDNotContext))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "job"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.service.DeletionJob(r.Context(), userID, id)
+	switch {
+	case errors.Is(err, errorscustom.ErrNotFound):
+		h.logger.Info("GET/api/user/urls/deletions/{job} =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		h.logger.Error("Error get deletion job = ", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(models.NewDeletionJobStatus(*job)); err != nil {
+		h.logger.Error(`"error": "failed to marshal response", "details": `, logger.ErrAttr(err))
+	}
+}
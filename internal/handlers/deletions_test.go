@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/workers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDeletionJob(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	storage := mapstorage.NewMapURL()
+	urlService := service.NewService(storage, logs)
+	worker := workers.NewWorkerDeleted(urlService, 1, 100, 5*time.Millisecond, logs)
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, worker)
+
+	ctx := context.Background()
+	_, err := storage.SaveURL(ctx, "mine", "https://example.com/mine", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = storage.SaveURL(ctx, "theirs", "https://example.com/theirs", "stranger", models.LinkOptions{})
+	require.NoError(t, err)
+
+	// job добавляет ID задания в параметры маршрута и пользователя в контекст
+	job := func(id, userID string) *httptest.ResponseRecorder {
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("job", id)
+		rRequest := httptest.NewRequest("GET", "/api/user/urls/deletions/"+id, nil)
+		rCtx := context.WithValue(rRequest.Context(), chi.RouteCtxKey, chiCtx)
+		rCtx = context.WithValue(rCtx, middleware.UserIDContextKey, userID)
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.GetDeletionJob(wResonse, rRequest.WithContext(rCtx))
+		return wResonse
+	}
+	decode := func(t *testing.T, wResonse *httptest.ResponseRecorder) models.DeletionJobStatus {
+		var status models.DeletionJobStatus
+		require.NoError(t, json.NewDecoder(wResonse.Body).Decode(&status))
+		return status
+	}
+
+	rRequest := httptest.NewRequest("DELETE", "/api/user/urls", strings.NewReader(`["mine", "theirs"]`))
+	rRequest = rRequest.WithContext(context.WithValue(rRequest.Context(), middleware.UserIDContextKey, "owner"))
+	wResonse := httptest.NewRecorder()
+	shortHandlers.DeletionURLs(wResonse, rRequest)
+	require.Equal(t, http.StatusAccepted, wResonse.Code)
+	require.Equal(t, "/api/user/urls/deletions/1", wResonse.Header().Get("Location"))
+	accepted := decode(t, wResonse)
+	assert.Equal(t, int64(1), accepted.JobID)
+	assert.Equal(t, models.JobQueued, accepted.Status)
+
+	t.Run("queued", func(t *testing.T) {
+		wResonse := job("1", "owner")
+		require.Equal(t, http.StatusOK, wResonse.Code)
+		assert.Equal(t, "application/json", wResonse.Header().Get("Content-Type"))
+		assert.Equal(t, models.DeletionJobStatus{
+			JobID:   1,
+			Status:  models.JobQueued,
+			URLs:    []string{"mine", "theirs"},
+			Deleted: []string{},
+			Skipped: []string{},
+		}, decode(t, wResonse))
+	})
+
+	t.Run("done", func(t *testing.T) {
+		workerCtx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			worker.StartWorkerDeletion(workerCtx)
+			close(done)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		var status models.DeletionJobStatus
+		require.Eventually(t, func() bool {
+			status = decode(t, job("1", "owner"))
+			return status.Status == models.JobDone
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []string{"mine"}, status.Deleted)
+		assert.Equal(t, []string{"theirs"}, status.Skipped)
+	})
+
+	t.Run("foreign", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, job("1", "stranger").Code)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, job("2", "owner").Code)
+	})
+
+	t.Run("invalid_id", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, job("first", "owner").Code)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, job("1", "").Code)
+	})
+}
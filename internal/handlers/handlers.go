@@ -4,8 +4,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
@@ -23,32 +27,67 @@ type Handlers struct {
 	logger  *logger.Logger
 	worker  workers.Worker
 	//worker  *workers.WorkerDeleted
+	clicks    workers.Recorder
+	scheduler workers.JobScheduler
+}
+
+// Option - настройка обработчиков.
+type Option func(*Handlers)
+
+// WithClickRecorder задает запись переходов по коротким ссылкам.
+// Без нее переходы не записываются.
+func WithClickRecorder(recorder workers.Recorder) Option {
+	return func(h *Handlers) {
+		h.clicks = recorder
+	}
+}
+
+// WithScheduler задает планировщик фоновых задач для админского API.
+// Без него список задач пуст, а ручной запуск отвечает 404.
+func WithScheduler(scheduler workers.JobScheduler) Option {
+	return func(h *Handlers) {
+		h.scheduler = scheduler
+	}
 }
 
 // NewHandlers - конструктор обработчиков
-func NewHandlers(service *service.Service, baseURL string, sLog *logger.Logger, worker workers.Worker) *Handlers {
-	return &Handlers{
+func NewHandlers(service *service.Service, baseURL string, sLog *logger.Logger, worker workers.Worker, opts ...Option) *Handlers {
+	h := &Handlers{
 		service: service,
 		baseURL: baseURL,
 		logger:  sLog,
 		worker:  worker,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// recordClick записывает переход по короткой ссылке, если запись включена.
+func (h *Handlers) recordClick(shortURL string, r *http.Request) {
+	if h.clicks != nil {
+		h.clicks.Record(shortURL, r)
+	}
 }
 
 // PostJSON godoc
 // @Tags POST
 // @Summary Create new short URL from JSON request
-// @Description Create a short URL based on the given JSON payload
+// @Description Create a short URL based on the given JSON payload, optionally with a custom alias, expiration, click limit and password
 // @Accept json
 // @Produce json
 // @Param url body models.URL true "URL to shorten"
 // @Success 201 "Created"
-// @Failure 400 "Bad request"
+// @Failure 400 {object} models.ErrorResponse "Bad request, invalid custom alias, expiration, click limit or password"
 // @Failure 404 "URL not found"
-// @Failure 409 "Conflict"
+// @Failure 409 {object} models.ErrorResponse "Conflict or custom alias already taken"
 // @Failure 500 "Internal server error"
 // @Router /api/shorten [post]
 // PostJSON обрабатываем JSON запрос и возвращаем короткую ссылку.
+// Если задан custom_alias, короткой ссылкой становится он.
+// Срок жизни ссылки задается через expires_at или ttl.
 func (h *Handlers) PostJSON(w http.ResponseWriter, r *http.Request) {
 
 	// создаем структуру для сохранения URL
@@ -91,11 +130,29 @@ func (h *Handlers) PostJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//
+	opts, err := linkOptions(url)
+	if err != nil {
+		h.writeOptionsError(w, err)
+		return
+	}
 
-	// создаем короткую ссылку
-	encodeURL, err := h.service.SaveURL(url.URL, userID)
+	// создаем короткую ссылку или сохраняем пользовательскую
+	var encodeURL string
+	if url.CustomAlias != "" {
+		encodeURL, err = h.service.SaveCustomURL(r.Context(), url.URL, url.CustomAlias, userID, opts)
+	} else {
+		encodeURL, err = h.service.SaveURL(r.Context(), url.URL, userID, opts)
+	}
 	if err != nil {
+		var aliasErr *errorscustom.AliasError
+		if errors.As(err, &aliasErr) {
+			h.writeAliasError(w, aliasErr, "")
+			return
+		}
+		if isOptionsError(err) {
+			h.writeOptionsError(w, err)
+			return
+		}
 		if errors.Is(err, errorscustom.ErrConflict) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusConflict)
@@ -169,7 +226,7 @@ func (h *Handlers) PostURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// создаем короткую ссылку
-	encodeURL, err := h.service.SaveURL(string(body), userID)
+	encodeURL, err := h.service.SaveURL(r.Context(), string(body), userID, models.LinkOptions{})
 	if err != nil {
 		if errors.Is(err, errorscustom.ErrConflict) {
 			h.logger.Info("Conflict error: ", logger.ErrAttr(err))
@@ -198,8 +255,9 @@ func (h *Handlers) PostURL(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param url body []models.MultipleURL true "URL to shorten"
 // @Success 201 "Created"
-// @Failure 400 "Bad request"
+// @Failure 400 {object} models.ErrorResponse "Bad request or invalid custom alias"
 // @Failure 404 "Not found"
+// @Failure 409 {object} models.ErrorResponse "Custom alias already taken"
 // @Failure 500 "Internal server error"
 // @Router /api/shorten/batch [post]
 // PostBatchDB записываем запрос в db.
@@ -241,8 +299,25 @@ func (h *Handlers) PostBatchDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resultMultipleURL, err := h.service.SaveSliceOfDB(multipleURL, h.baseURL, userID)
+	resultMultipleURL, err := h.service.SaveSliceOfDB(r.Context(), multipleURL, h.baseURL, userID)
 	if err != nil {
+		if isOptionsError(err) {
+			h.writeOptionsError(w, err)
+			return
+		}
+		var aliasErr *errorscustom.AliasError
+		if errors.As(err, &aliasErr) {
+			// указываем, к какому элементу пакета относится ошибка
+			var correlationID string
+			for _, req := range multipleURL {
+				if req.CustomAlias == aliasErr.Alias {
+					correlationID = req.CorrelationID
+					break
+				}
+			}
+			h.writeAliasError(w, aliasErr, correlationID)
+			return
+		}
 		h.logger.Error("Error shorten URL = ", logger.ErrAttr(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -263,15 +338,19 @@ func (h *Handlers) PostBatchDB(w http.ResponseWriter, r *http.Request) {
 // GetURL godoc
 // @Tags GET
 // @Summary Get short URL
-// @Description Get short URL
+// @Description Get short URL. Password-protected links need the password in the X-Link-Password header, otherwise an HTML password form is served
 // @Accept json
 // @Produce json
 // @Param id path string true "Short URL"
+// @Param X-Link-Password header string false "Link password"
 // @Success 307 "Temporary redirect"
 // @Header 307 {string} Location "URL новой записи"
+// @Failure 401 "Password required: HTML password form"
+// @Failure 403 "Wrong password"
 // @Failure 404 "Not found"
 // @Failure 405 "Method not allowed"
-// @Failure 410 "Gone"
+// @Failure 410 "Gone: deleted, expired or out of clicks"
+// @Failure 429 "Too many password attempts"
 // @Router /{id} [get]
 // GetURL возвращаем информацию по короткой ссылке.
 func (h *Handlers) GetURL(w http.ResponseWriter, r *http.Request) {
@@ -285,23 +364,46 @@ func (h *Handlers) GetURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//ищем в мапе сохраненный url
-	url, err := h.service.GetURL(shortURL)
+	//ищем в мапе сохраненный url, пароль API-клиенты передают заголовком
+	url, err := h.service.OpenURL(r.Context(), shortURL, r.Header.Get(PasswordHeader))
 	if err != nil {
-		if errors.Is(err, errorscustom.ErrDeletedURL) {
-			h.logger.Error("error =", "GET/{id}", errorscustom.ErrDeletedURL)
-			w.WriteHeader(http.StatusGone)
+		if errors.Is(err, errorscustom.ErrPasswordRequired) {
+			h.writePasswordForm(w, http.StatusUnauthorized, "")
 			return
 		}
-		h.logger.Error("GET/{id} =", logger.ErrAttr(err))
-		w.WriteHeader(http.StatusNotFound)
+		if errors.Is(err, errorscustom.ErrWrongPassword) {
+			h.logger.Info("GET/{id} =", logger.ErrAttr(err))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		h.writeGetError(w, err)
 		return
 	}
 
 	// записываем заголовок и статус
 	w.Header().Set("Location", url)
 	w.WriteHeader(http.StatusTemporaryRedirect)
+	h.recordClick(shortURL, r)
+}
 
+// writeGetError отвечает на ошибку перехода по короткой ссылке.
+func (h *Handlers) writeGetError(w http.ResponseWriter, err error) {
+	var attemptsErr *errorscustom.AttemptsError
+	switch {
+	case errors.As(err, &attemptsErr):
+		h.logger.Info("GET/{id} =", logger.ErrAttr(err))
+		setRetryAfter(w, attemptsErr.RetryAfter)
+		w.WriteHeader(http.StatusTooManyRequests)
+	case errors.Is(err, errorscustom.ErrDeletedURL):
+		h.logger.Error("error =", "GET/{id}", errorscustom.ErrDeletedURL)
+		w.WriteHeader(http.StatusGone)
+	case errors.Is(err, errorscustom.ErrExpiredURL), errors.Is(err, errorscustom.ErrExhaustedURL):
+		h.logger.Info("GET/{id} =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusGone)
+	default:
+		h.logger.Error("GET/{id} =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusNotFound)
+	}
 }
 
 // GetPing godoc
@@ -315,7 +417,7 @@ func (h *Handlers) GetURL(w http.ResponseWriter, r *http.Request) {
 // @Router /ping [get]
 // GetPing Проверяем подключение к DB.
 func (h *Handlers) GetPing(w http.ResponseWriter, r *http.Request) {
-	if err := h.service.Ping(); err != nil {
+	if err := h.service.Ping(r.Context()); err != nil {
 		h.logger.Error("Error = ", logger.ErrAttr(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -347,7 +449,7 @@ func (h *Handlers) GetUsersURLs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Получаем список URL пользователя
-	listURLs, err := h.service.GetAllURL(userID, h.baseURL)
+	listURLs, err := h.service.GetAllURL(r.Context(), userID, h.baseURL)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -385,10 +487,13 @@ func (h *Handlers) GetUsersURLs(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param urls body []string true "URLs"
-// @Success 202 "Accepted"
+// @Success 202 {object} models.DeletionJobStatus "Accepted, Location points to the job status"
+// @Failure 429 "Deletion queue is full, Retry-After header is set"
 // @Failure 500 "Internal server error"
 // @Router /api/user/urls [delete]
-// DeletionURLs делает запрос на удаление из базы.
+// DeletionURLs делает запрос на удаление из базы и отвечает ID задания,
+// заголовок Location указывает на состояние задания. Если очередь
+// воркера полна, отвечает 429 с заголовком Retry-After.
 func (h *Handlers) DeletionURLs(w http.ResponseWriter, r *http.Request) {
 	var urls []string
 	dec := json.NewDecoder(r.Body)
@@ -406,13 +511,89 @@ func (h *Handlers) DeletionURLs(w http.ResponseWriter, r *http.Request) {
 		URLs: urls,
 	}
 
-	if err := h.worker.SendDeletionRequestToWorker(req); err != nil {
+	id, err := h.worker.SendDeletionRequestToWorker(r.Context(), req)
+	var queueFullErr *errorscustom.QueueFullError
+	if errors.As(err, &queueFullErr) {
+		h.logger.Info("deletion queue is full", logger.ErrAttr(err))
+		setRetryAfter(w, queueFullErr.RetryAfter)
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
 		h.logger.Error("error send to deletion worker request", "error = ", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", deletionJobPath+strconv.FormatInt(id, 10))
 	w.WriteHeader(http.StatusAccepted)
+	status := models.NewDeletionJobStatus(models.DeletionJob{ID: id, URLs: urls, Status: models.JobQueued})
+	if err = json.NewEncoder(w).Encode(status); err != nil {
+		h.logger.Error(`"error": "failed to marshal response", "details": `, logger.ErrAttr(err))
+	}
+}
+
+// setRetryAfter выставляет заголовок Retry-After в целых секундах с округлением вверх.
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// writeAliasError отвечает на ошибку пользовательской короткой ссылки:
+// 409, если ссылка занята, иначе 400, с подробностями в JSON.
+func (h *Handlers) writeAliasError(w http.ResponseWriter, aliasErr *errorscustom.AliasError, correlationID string) {
+	status := http.StatusBadRequest
+	if errors.Is(aliasErr, errorscustom.ErrAliasTaken) {
+		status = http.StatusConflict
+	}
+	h.logger.Info("Custom alias rejected: ", logger.ErrAttr(aliasErr))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error:         aliasErr.Err.Error(),
+		CustomAlias:   aliasErr.Alias,
+		CorrelationID: correlationID,
+	})
+}
+
+// isOptionsError проверяет, что ошибка - неверный параметр ссылки из запроса.
+func isOptionsError(err error) bool {
+	return errors.Is(err, errorscustom.ErrInvalidExpiry) ||
+		errors.Is(err, errorscustom.ErrInvalidMaxClicks) ||
+		errors.Is(err, errorscustom.ErrInvalidPassword)
+}
+
+// writeOptionsError отвечает 400 на неверный срок жизни ссылки,
+// ограничение числа переходов или пароль.
+func (h *Handlers) writeOptionsError(w http.ResponseWriter, err error) {
+	h.logger.Info("Link options rejected: ", logger.ErrAttr(err))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error: err.Error(),
+	})
+}
+
+// linkOptions собирает параметры ссылки из запроса.
+// ttl переводится в момент истечения, задавать его вместе с expires_at нельзя.
+// max_clicks и пароль проверяет сервис.
+func linkOptions(url models.URL) (models.LinkOptions, error) {
+	if url.TTL == "" {
+		return models.LinkOptions{ExpiresAt: url.ExpiresAt, MaxClicks: url.MaxClicks, Password: url.Password}, nil
+	}
+	if url.ExpiresAt != nil {
+		return models.LinkOptions{}, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", errorscustom.ErrInvalidExpiry)
+	}
+
+	ttl, err := time.ParseDuration(url.TTL)
+	if err != nil || ttl <= 0 {
+		return models.LinkOptions{}, fmt.Errorf("%w: ttl must be a positive duration like \"24h\"", errorscustom.ErrInvalidExpiry)
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	return models.LinkOptions{ExpiresAt: &expiresAt, MaxClicks: url.MaxClicks, Password: url.Password}, nil
 }
 
 // ResultBody собирает ссылку для возврата в body ответа.
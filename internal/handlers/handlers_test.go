@@ -10,9 +10,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
@@ -52,9 +54,9 @@ func TestPostURL(t *testing.T) {
 
 		// Проверяем, что в MapStorage добавлен новый URL
 		encodedURL := strings.TrimPrefix(responseURL, "http://localhost:8080/")
-		originalURL, err := storage.GetURL(encodedURL)
+		record, err := storage.GetURL(context.Background(), encodedURL)
 		assert.NoError(t, err)
-		assert.Equal(t, "http://example.com", originalURL)
+		assert.Equal(t, "http://example.com", record.OriginalURL)
 	})
 
 	//Тест на обработку пустого тела запроса
@@ -106,6 +108,190 @@ func TestHandlersPostJSON(t *testing.T) {
 	})
 }
 
+func TestHandlersPostJSON_CustomAlias(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	storage := mapstorage.NewMapURL()
+
+	urlService := service.NewService(storage, logs)
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, nil)
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		rRequest := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(payload))
+		ctx := context.WithValue(rRequest.Context(), middleware.UserIDContextKey, "userID")
+		rRequest = rRequest.WithContext(ctx)
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.PostJSON(wResonse, rRequest)
+		return wResonse
+	}
+
+	t.Run("created", func(t *testing.T) {
+		wResonse := post(`{"url": "https://example.com/sale", "custom_alias": "spring-sale"}`)
+
+		assert.Equal(t, http.StatusCreated, wResonse.Code)
+		assert.JSONEq(t, `{"result": "http://localhost:8080/spring-sale"}`, wResonse.Body.String())
+
+		record, err := storage.GetURL(context.Background(), "spring-sale")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/sale", record.OriginalURL)
+	})
+
+	t.Run("taken", func(t *testing.T) {
+		wResonse := post(`{"url": "https://example.com/other", "custom_alias": "spring-sale"}`)
+
+		assert.Equal(t, http.StatusConflict, wResonse.Code)
+		assert.JSONEq(t, `{"error": "custom alias already taken", "custom_alias": "spring-sale"}`, wResonse.Body.String())
+	})
+
+	t.Run("reserved", func(t *testing.T) {
+		wResonse := post(`{"url": "https://example.com/other", "custom_alias": "swagger"}`)
+
+		assert.Equal(t, http.StatusBadRequest, wResonse.Code)
+		assert.JSONEq(t, `{"error": "custom alias is reserved", "custom_alias": "swagger"}`, wResonse.Body.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		wResonse := post(`{"url": "https://example.com/other", "custom_alias": "sale?"}`)
+
+		assert.Equal(t, http.StatusBadRequest, wResonse.Code)
+	})
+}
+
+func TestHandlersPostJSON_Expiry(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	storage := mapstorage.NewMapURL()
+
+	urlService := service.NewService(storage, logs, service.WithMaxTTL(24*time.Hour))
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, nil)
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		rRequest := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(payload))
+		ctx := context.WithValue(rRequest.Context(), middleware.UserIDContextKey, "userID")
+		rRequest = rRequest.WithContext(ctx)
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.PostJSON(wResonse, rRequest)
+		return wResonse
+	}
+
+	t.Run("ttl", func(t *testing.T) {
+		wResonse := post(`{"url": "https://example.com/ttl", "custom_alias": "with-ttl", "ttl": "1h"}`)
+		assert.Equal(t, http.StatusCreated, wResonse.Code)
+
+		record, err := storage.GetURL(context.Background(), "with-ttl")
+		assert.NoError(t, err)
+		if assert.NotNil(t, record.ExpiresAt) {
+			assert.WithinDuration(t, time.Now().Add(time.Hour), *record.ExpiresAt, time.Minute)
+		}
+	})
+
+	t.Run("expires_at", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		wResonse := post(`{"url": "https://example.com/at", "custom_alias": "with-at", "expires_at": "` + expiresAt.Format(time.RFC3339) + `"}`)
+		assert.Equal(t, http.StatusCreated, wResonse.Code)
+
+		record, err := storage.GetURL(context.Background(), "with-at")
+		assert.NoError(t, err)
+		if assert.NotNil(t, record.ExpiresAt) {
+			assert.True(t, expiresAt.Equal(*record.ExpiresAt))
+		}
+	})
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "both", payload: `{"url": "https://example.com/both", "ttl": "1h", "expires_at": "2099-01-01T00:00:00Z"}`},
+		{name: "invalid_ttl", payload: `{"url": "https://example.com/invalid", "ttl": "soon"}`},
+		{name: "negative_ttl", payload: `{"url": "https://example.com/negative", "ttl": "-1h"}`},
+		{name: "in_past", payload: `{"url": "https://example.com/past", "expires_at": "2000-01-01T00:00:00Z"}`},
+		{name: "over_max", payload: `{"url": "https://example.com/max", "ttl": "48h"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wResonse := post(tt.payload)
+
+			assert.Equal(t, http.StatusBadRequest, wResonse.Code)
+			var response models.ErrorResponse
+			assert.NoError(t, json.NewDecoder(wResonse.Body).Decode(&response))
+			assert.Contains(t, response.Error, "invalid link expiration")
+		})
+	}
+}
+
+func TestGetURL_Expired(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	storage := mapstorage.NewMapURL()
+
+	urlService := service.NewService(storage, logs)
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, nil)
+
+	expiresAt := time.Now().Add(-time.Minute)
+	_, err := storage.SaveURL(context.Background(), "expired", "https://example.com", "userID", models.LinkOptions{ExpiresAt: &expiresAt})
+	assert.NoError(t, err)
+
+	rRequest := httptest.NewRequest("GET", "http://localhost:8080/", nil)
+	chiCtx := chi.NewRouteContext()
+	rRequest = rRequest.WithContext(context.WithValue(rRequest.Context(), chi.RouteCtxKey, chiCtx))
+	chiCtx.URLParams.Add("id", "expired")
+	wResonse := httptest.NewRecorder()
+
+	shortHandlers.GetURL(wResonse, rRequest)
+
+	assert.Equal(t, http.StatusGone, wResonse.Code)
+	assert.Empty(t, wResonse.Header().Get("Location"))
+}
+
+func TestGetURL_MaxClicks(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	storage := mapstorage.NewMapURL()
+
+	urlService := service.NewService(storage, logs)
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, nil)
+
+	post := func(payload string) *httptest.ResponseRecorder {
+		rRequest := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(payload))
+		ctx := context.WithValue(rRequest.Context(), middleware.UserIDContextKey, "userID")
+		rRequest = rRequest.WithContext(ctx)
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.PostJSON(wResonse, rRequest)
+		return wResonse
+	}
+	get := func(shortURL string) *httptest.ResponseRecorder {
+		rRequest := httptest.NewRequest("GET", "http://localhost:8080/", nil)
+		chiCtx := chi.NewRouteContext()
+		rRequest = rRequest.WithContext(context.WithValue(rRequest.Context(), chi.RouteCtxKey, chiCtx))
+		chiCtx.URLParams.Add("id", shortURL)
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.GetURL(wResonse, rRequest)
+		return wResonse
+	}
+
+	t.Run("one_time", func(t *testing.T) {
+		wResonse := post(`{"url": "https://example.com/once", "custom_alias": "once", "max_clicks": 1}`)
+		assert.Equal(t, http.StatusCreated, wResonse.Code)
+
+		wResonse = get("once")
+		assert.Equal(t, http.StatusTemporaryRedirect, wResonse.Code)
+		assert.Equal(t, "https://example.com/once", wResonse.Header().Get("Location"))
+
+		wResonse = get("once")
+		assert.Equal(t, http.StatusGone, wResonse.Code)
+		assert.Empty(t, wResonse.Header().Get("Location"))
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		wResonse := post(`{"url": "https://example.com/negative", "max_clicks": -1}`)
+
+		assert.Equal(t, http.StatusBadRequest, wResonse.Code)
+		var response models.ErrorResponse
+		assert.NoError(t, json.NewDecoder(wResonse.Body).Decode(&response))
+		assert.Contains(t, response.Error, "invalid max_clicks")
+	})
+}
+
 func TestGetURL(t *testing.T) {
 	// Тест на успешное декодирование URL
 	logs := logger.NewLogger(logger.WithLevel("info"))
@@ -139,9 +325,9 @@ func TestGetURL(t *testing.T) {
 		assert.Equal(t, http.StatusTemporaryRedirect, wResonse.Code)
 
 		// Проверяем, что в MapStorage добавлен новый URL
-		originalURL, err := storage.GetURL(encodedURL)
+		record, err := storage.GetURL(context.Background(), encodedURL)
 		assert.NoError(t, err)
-		assert.Equal(t, "http://example.com", originalURL)
+		assert.Equal(t, "http://example.com", record.OriginalURL)
 
 		// Проверяем, что в MapStorage нет URL
 		rRequest = httptest.NewRequest("GET", "http://localhost:8080/", nil)
@@ -160,7 +346,7 @@ func TestGetPing(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockPostgre := mocks.NewMockStorage(ctrl)
-	mockPostgre.EXPECT().Ping().Return(nil)
+	mockPostgre.EXPECT().Ping(gomock.Any()).Return(nil)
 
 	logger := logger.NewLogger(logger.WithLevel("info"))
 
@@ -203,7 +389,7 @@ func TestPostBatchDB_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockPostgre := mocks.NewMockStorage(ctrl)
-	mockPostgre.EXPECT().SaveSliceOfDB(gomock.Any(), gomock.Any(), gomock.Any()).Return(reseltMultip, nil)
+	mockPostgre.EXPECT().SaveSlice(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(reseltMultip, nil)
 	logger := logger.NewLogger(logger.WithLevel("info"))
 
 	service := service.NewService(mockPostgre, logger)
@@ -224,6 +410,28 @@ func TestPostBatchDB_Success(t *testing.T) {
 	}
 }
 
+func TestPostBatchDB_AliasTaken(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	storage := mapstorage.NewMapURL()
+	_, err := storage.SaveURL(context.Background(), "spring-sale", "https://example.com/sale", "other", models.LinkOptions{})
+	assert.NoError(t, err)
+
+	handlers := NewHandlers(service.NewService(storage, logs), "http://localhost:8080", logs, nil)
+
+	payload := `[
+		{"correlation_id": "1", "original_url": "https://example.com/1"},
+		{"correlation_id": "2", "original_url": "https://example.com/2", "custom_alias": "spring-sale"}
+	]`
+	req := httptest.NewRequest("POST", "/api/shorten/batch", strings.NewReader(payload))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDContextKey, "userID"))
+	w := httptest.NewRecorder()
+
+	handlers.PostBatchDB(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error": "custom alias already taken", "custom_alias": "spring-sale", "correlation_id": "2"}`, w.Body.String())
+}
+
 func TestPostBatchDB_InCorrectRequest(t *testing.T) {
 
 	buf := bytes.NewReader([]byte("lololo"))
@@ -272,7 +480,7 @@ func TestPostBatchDB_StorageError(t *testing.T) {
 
 	mockPostgre := mocks.NewMockStorage(ctrl)
 	mockErr := errors.New("Some error")
-	mockPostgre.EXPECT().SaveSliceOfDB(gomock.Any(), gomock.Any(), gomock.Any()).Return(reseltMultip, mockErr)
+	mockPostgre.EXPECT().SaveSlice(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(reseltMultip, mockErr)
 
 	logger := logger.NewLogger(logger.WithLevel("info"))
 
@@ -379,7 +587,7 @@ func TestHandlers_GetUsersURLs(t *testing.T) {
 				req = req.WithContext(ctx)
 			}
 
-			mockPostgre.EXPECT().GetAllURL(gomock.Any(), gomock.Any()).Return(tt.userURLs, tt.expectedErr).AnyTimes()
+			mockPostgre.EXPECT().GetAllURL(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.userURLs, tt.expectedErr).AnyTimes()
 
 			resp := httptest.NewRecorder()
 
@@ -398,13 +606,16 @@ func TestHandlers_DeletionURLs(t *testing.T) {
 		name              string
 		body              string
 		expectedCode      int
+		expectedLocation  string
+		expectedRetry     string
 		expectedWorkerErr error
 		ctx               bool
 	}{
 		{
-			name:         "successful",
-			body:         `["http://example.com", "http://example2.com"]`,
-			expectedCode: 202,
+			name:             "successful",
+			body:             `["http://example.com", "http://example2.com"]`,
+			expectedCode:     202,
+			expectedLocation: "/api/user/urls/deletions/7",
 		},
 		{
 			name:         "invalid_body",
@@ -416,6 +627,13 @@ func TestHandlers_DeletionURLs(t *testing.T) {
 			expectedWorkerErr: errors.New("invalid_worker"),
 			expectedCode:      500,
 		},
+		{
+			name:              "queue_full",
+			body:              `["http://example.com"]`,
+			expectedWorkerErr: &errorscustom.QueueFullError{RetryAfter: 1500 * time.Millisecond},
+			expectedCode:      429,
+			expectedRetry:     "2",
+		},
 	}
 
 	for _, tt := range tests {
@@ -434,7 +652,7 @@ func TestHandlers_DeletionURLs(t *testing.T) {
 
 			//создаем заглушку worker.
 			workerMock := workers.NewMockWorker(ctrl)
-			workerMock.EXPECT().SendDeletionRequestToWorker(gomock.Any()).Return(tt.expectedWorkerErr).AnyTimes()
+			workerMock.EXPECT().SendDeletionRequestToWorker(gomock.Any(), gomock.Any()).Return(int64(7), tt.expectedWorkerErr).AnyTimes()
 
 			//создаем запрос.
 			req := httptest.NewRequest(http.MethodDelete, "/", bytes.NewBuffer([]byte(tt.body)))
@@ -460,6 +678,12 @@ func TestHandlers_DeletionURLs(t *testing.T) {
 			if resp.Code != tt.expectedCode {
 				t.Errorf("ожидался статус %d, но получен %d", tt.expectedCode, resp.Code)
 			}
+			if location := resp.Header().Get("Location"); location != tt.expectedLocation {
+				t.Errorf("ожидался Location %q, но получен %q", tt.expectedLocation, location)
+			}
+			if retry := resp.Header().Get("Retry-After"); retry != tt.expectedRetry {
+				t.Errorf("ожидался Retry-After %q, но получен %q", tt.expectedRetry, retry)
+			}
 		})
 	}
 }
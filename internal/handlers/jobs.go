@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// GetJobs godoc
+// @Tags GET
+// @Summary List background jobs
+// @Description List background jobs with their schedules, run counters and recent runs, allowed only from the trusted subnet
+// @Produce json
+// @Param X-Real-IP header string true "Client IP"
+// @Success 200 {array} models.ScheduledJob "OK"
+// @Failure 403 "Client IP is not in the trusted subnet"
+// @Router /api/internal/jobs [get]
+// GetJobs возвращает фоновые задачи планировщика и их последние запуски.
+func (h *Handlers) GetJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := []models.ScheduledJob{}
+	if h.scheduler != nil {
+		jobs = h.scheduler.Jobs()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(jobs); err != nil {
+		h.logger.Error(`"error": "failed to marshal response", "details": `, logger.ErrAttr(err))
+	}
+}
+
+// RunJob godoc
+// @Tags POST
+// @Summary Run background job
+// @Description Start a background job now without waiting for it to finish, allowed only from the trusted subnet. The route is registered only with -job-trigger
+// @Param X-Real-IP header string true "Client IP"
+// @Param name path string true "Job name"
+// @Success 202 "Accepted"
+// @Failure 403 "Client IP is not in the trusted subnet"
+// @Failure 404 "Job not found"
+// @Failure 409 "Job is already running"
+// @Failure 503 "Scheduler is not running"
+// @Router /api/internal/jobs/{name}/run [post]
+// RunJob запускает фоновую задачу вручную. Результат запуска появится
+// в истории задачи.
+func (h *Handlers) RunJob(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	err := errorscustom.ErrJobNotFound
+	if h.scheduler != nil {
+		err = h.scheduler.Trigger(name)
+	}
+
+	switch {
+	case err == nil:
+		h.logger.Info("Job triggered", logger.StringAttr("job", name))
+		w.WriteHeader(http.StatusAccepted)
+	case errors.Is(err, errorscustom.ErrJobNotFound):
+		h.logger.Info("POST/api/internal/jobs/{name}/run =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errorscustom.ErrJobRunning):
+		h.logger.Info("POST/api/internal/jobs/{name}/run =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, errorscustom.ErrSchedulerStopped):
+		h.logger.Info("POST/api/internal/jobs/{name}/run =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		h.logger.Error("POST/api/internal/jobs/{name}/run =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/workers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	urlService := service.NewService(mapstorage.NewMapURL(), logs)
+
+	release := make(chan struct{})
+	scheduler := workers.NewScheduler(logs)
+	require.NoError(t, scheduler.Register("reap_expired", workers.Every(time.Hour), func(ctx context.Context) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}))
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, nil, WithScheduler(scheduler))
+
+	// run запускает задачу name вручную
+	run := func(h *Handlers, name string) int {
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("name", name)
+		rRequest := httptest.NewRequest("POST", "/api/internal/jobs/"+name+"/run", nil)
+		rRequest = rRequest.WithContext(context.WithValue(rRequest.Context(), chi.RouteCtxKey, chiCtx))
+		wResonse := httptest.NewRecorder()
+
+		h.RunJob(wResonse, rRequest)
+		return wResonse.Code
+	}
+	// list возвращает список задач
+	list := func(t *testing.T, h *Handlers) []models.ScheduledJob {
+		wResonse := httptest.NewRecorder()
+		h.GetJobs(wResonse, httptest.NewRequest("GET", "/api/internal/jobs", nil))
+		require.Equal(t, http.StatusOK, wResonse.Code)
+		assert.Equal(t, "application/json", wResonse.Header().Get("Content-Type"))
+
+		var jobs []models.ScheduledJob
+		require.NoError(t, json.NewDecoder(wResonse.Body).Decode(&jobs))
+		return jobs
+	}
+
+	t.Run("without_scheduler", func(t *testing.T) {
+		h := NewHandlers(urlService, "http://localhost:8080", logs, nil)
+		assert.Empty(t, list(t, h))
+		assert.Equal(t, http.StatusNotFound, run(h, "reap_expired"))
+	})
+
+	t.Run("not_started", func(t *testing.T) {
+		assert.Equal(t, http.StatusServiceUnavailable, run(shortHandlers, "reap_expired"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.StartScheduler(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	t.Run("run", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return run(shortHandlers, "reap_expired") == http.StatusAccepted
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, http.StatusConflict, run(shortHandlers, "reap_expired"))
+		assert.Equal(t, http.StatusNotFound, run(shortHandlers, "missing"))
+
+		jobs := list(t, shortHandlers)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "reap_expired", jobs[0].Name)
+		assert.Equal(t, "@every 1h0m0s", jobs[0].Schedule)
+		assert.True(t, jobs[0].Running)
+
+		close(release)
+		require.Eventually(t, func() bool {
+			jobs = list(t, shortHandlers)
+			return len(jobs[0].History) == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, models.TriggerManual, jobs[0].History[0].Trigger)
+		assert.Equal(t, uint64(1), jobs[0].Runs)
+	})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+)
+
+// PasswordHeader - заголовок, в котором API-клиенты передают пароль ссылки.
+const PasswordHeader = "X-Link-Password"
+
+// passwordForm - форма ввода пароля защищенной ссылки.
+// Форма отправляется POST-запросом на ту же короткую ссылку.
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is password protected.</p>
+{{if .}}<p>{{.}}</p>
+{{end}}<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// UnlockURL godoc
+// @Tags POST
+// @Summary Open password-protected short URL
+// @Description Check the password from the HTML form and redirect to the original URL
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param id path string true "Short URL"
+// @Param password formData string true "Link password"
+// @Success 303 "See other"
+// @Header 303 {string} Location "URL новой записи"
+// @Failure 401 "Password required: HTML password form"
+// @Failure 403 "Wrong password: HTML password form"
+// @Failure 404 "Not found"
+// @Failure 410 "Gone: deleted, expired or out of clicks"
+// @Failure 429 "Too many password attempts"
+// @Router /{id} [post]
+// UnlockURL проверяет пароль из формы и перенаправляет по короткой ссылке.
+func (h *Handlers) UnlockURL(w http.ResponseWriter, r *http.Request) {
+	shortURL := chi.URLParam(r, "id")
+	if shortURL == "" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	url, err := h.service.OpenURL(r.Context(), shortURL, r.PostFormValue("password"))
+	if err != nil {
+		if errors.Is(err, errorscustom.ErrPasswordRequired) {
+			h.writePasswordForm(w, http.StatusUnauthorized, "")
+			return
+		}
+		if errors.Is(err, errorscustom.ErrWrongPassword) {
+			h.logger.Info("POST/{id} =", logger.ErrAttr(err))
+			h.writePasswordForm(w, http.StatusForbidden, "Wrong password, try again.")
+			return
+		}
+		h.writeGetError(w, err)
+		return
+	}
+
+	// после POST браузер должен перейти по ссылке GET-запросом
+	w.Header().Set("Location", url)
+	w.WriteHeader(http.StatusSeeOther)
+	h.recordClick(shortURL, r)
+}
+
+// writePasswordForm отвечает формой ввода пароля с необязательным сообщением.
+func (h *Handlers) writePasswordForm(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := passwordForm.Execute(w, message); err != nil {
+		h.logger.Error("Error render password form = ", logger.ErrAttr(err))
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordProtected(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	urlService := service.NewService(mapstorage.NewMapURL(), logs, service.WithPasswordAttempts(3, time.Minute))
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, nil)
+
+	// withID добавляет короткую ссылку в параметры маршрута
+	withID := func(r *http.Request, shortURL string) *http.Request {
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("id", shortURL)
+		return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+	}
+	get := func(shortURL, password string) *httptest.ResponseRecorder {
+		rRequest := withID(httptest.NewRequest("GET", "/"+shortURL, nil), shortURL)
+		if password != "" {
+			rRequest.Header.Set(PasswordHeader, password)
+		}
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.GetURL(wResonse, rRequest)
+		return wResonse
+	}
+	unlock := func(shortURL, password string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		rRequest := withID(httptest.NewRequest("POST", "/"+shortURL, strings.NewReader(form.Encode())), shortURL)
+		rRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.UnlockURL(wResonse, rRequest)
+		return wResonse
+	}
+
+	rRequest := httptest.NewRequest("POST", "/api/shorten",
+		strings.NewReader(`{"url": "https://example.com/secret", "custom_alias": "secret", "password": "qwerty"}`))
+	rRequest = rRequest.WithContext(context.WithValue(rRequest.Context(), middleware.UserIDContextKey, "userID"))
+	wResonse := httptest.NewRecorder()
+	shortHandlers.PostJSON(wResonse, rRequest)
+	require.Equal(t, http.StatusCreated, wResonse.Code)
+
+	t.Run("form", func(t *testing.T) {
+		wResonse := get("secret", "")
+
+		assert.Equal(t, http.StatusUnauthorized, wResonse.Code)
+		assert.Equal(t, "text/html; charset=utf-8", wResonse.Header().Get("Content-Type"))
+		assert.Contains(t, wResonse.Body.String(), `<form method="post">`)
+		assert.Empty(t, wResonse.Header().Get("Location"))
+	})
+
+	t.Run("header", func(t *testing.T) {
+		wResonse := get("secret", "qwerty")
+
+		assert.Equal(t, http.StatusTemporaryRedirect, wResonse.Code)
+		assert.Equal(t, "https://example.com/secret", wResonse.Header().Get("Location"))
+	})
+
+	t.Run("header_wrong", func(t *testing.T) {
+		wResonse := get("secret", "wrong")
+
+		assert.Equal(t, http.StatusForbidden, wResonse.Code)
+		assert.Empty(t, wResonse.Header().Get("Location"))
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		wResonse := unlock("secret", "qwerty")
+
+		assert.Equal(t, http.StatusSeeOther, wResonse.Code)
+		assert.Equal(t, "https://example.com/secret", wResonse.Header().Get("Location"))
+	})
+
+	t.Run("unlock_wrong", func(t *testing.T) {
+		wResonse := unlock("secret", "wrong")
+
+		assert.Equal(t, http.StatusForbidden, wResonse.Code)
+		assert.Contains(t, wResonse.Body.String(), "Wrong password")
+		assert.Empty(t, wResonse.Header().Get("Location"))
+	})
+
+	t.Run("too_many_attempts", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			unlock("secret", "wrong")
+		}
+
+		wResonse := unlock("secret", "qwerty")
+		assert.Equal(t, http.StatusTooManyRequests, wResonse.Code)
+		assert.NotEmpty(t, wResonse.Header().Get("Retry-After"))
+
+		wResonse = get("secret", "qwerty")
+		assert.Equal(t, http.StatusTooManyRequests, wResonse.Code)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		wResonse := unlock("missing", "qwerty")
+
+		assert.Equal(t, http.StatusNotFound, wResonse.Code)
+	})
+}
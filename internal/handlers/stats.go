@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// defaultStatsBucket - интервал статистики, если он не задан в запросе.
+const defaultStatsBucket = "day"
+
+// GetURLStats godoc
+// @Tags GET
+// @Summary Get short URL click stats
+// @Description Get total, unique and time-bucketed click counts for a short URL owned by the user
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Short URL"
+// @Param bucket query string false "Bucket size: minute, hour or day" default(day)
+// @Param since query string false "Start of buckets in RFC3339, last 30 buckets by default"
+// @Success 200 {object} models.LinkStats "OK"
+// @Failure 400 {object} models.ErrorResponse "Invalid bucket or since"
+// @Failure 401 "Unauthorized"
+// @Failure 404 "Not found or owned by another user"
+// @Failure 410 "Gone"
+// @Failure 500 "Internal server error"
+// @Router /api/user/urls/{id}/stats [get]
+// GetURLStats возвращает статистику переходов по ссылке пользователя.
+func (h *Handlers) GetURLStats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDContextKey).(string)
+	if !ok || userID == "" {
+		h.logger.Error("Error = ", logger.ErrAttr(errorscustom.ErrUserIDNotContext))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	shortURL := chi.URLParam(r, "id")
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = defaultStatsBucket
+	}
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			h.writeStatsError(w, fmt.Errorf("%w: since must be RFC3339", errorscustom.ErrInvalidStats))
+			return
+		}
+	}
+
+	stats, err := h.service.LinkStats(r.Context(), userID, shortURL, bucket, since)
+	if err != nil {
+		h.writeStatsError(w, err)
+		return
+	}
+	stats.ShortURL = h.baseURL + "/" + shortURL
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error(`"error": "failed to marshal response", "details": `, logger.ErrAttr(err))
+	}
+}
+
+// writeStatsError отвечает на ошибку запроса статистики.
+func (h *Handlers) writeStatsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errorscustom.ErrInvalidStats):
+		h.logger.Info("Stats request rejected: ", logger.ErrAttr(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, errorscustom.ErrNotFound):
+		h.logger.Info("GET/api/user/urls/{id}/stats =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errorscustom.ErrDeletedURL):
+		h.logger.Info("GET/api/user/urls/{id}/stats =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusGone)
+	default:
+		h.logger.Error("GET/api/user/urls/{id}/stats =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// GetInternalStats godoc
+// @Tags GET
+// @Summary Get service stats
+// @Description Get the number of shortened URLs and users, allowed only from the trusted subnet
+// @Produce json
+// @Param X-Real-IP header string true "Client IP"
+// @Success 200 {object} models.InternalStats "OK"
+// @Failure 403 "Client IP is not in the trusted subnet"
+// @Failure 500 "Internal server error"
+// @Router /api/internal/stats [get]
+// GetInternalStats возвращает число сокращенных URL и пользователей сервиса.
+func (h *Handlers) GetInternalStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		h.logger.Error("GET/api/internal/stats =", logger.ErrAttr(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error(`"error": "failed to marshal response", "details": `, logger.ErrAttr(err))
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncRecorder сохраняет переходы сразу, без буфера и воркера, с временем at.
+type syncRecorder struct {
+	service *service.Service
+	at      time.Time
+}
+
+func (s *syncRecorder) Record(shortURL string, r *http.Request) {
+	s.RecordClick(shortURL, r.Referer(), r.UserAgent(), r.RemoteAddr)
+}
+
+func (s *syncRecorder) RecordClick(shortURL, _, _, remoteAddr string) {
+	s.service.SaveClicks(context.Background(), []models.Click{{ShortURL: shortURL, At: s.at, IPHash: remoteAddr}})
+}
+
+func TestGetURLStats(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	urlService := service.NewService(mapstorage.NewMapURL(), logs)
+	shortHandlers := NewHandlers(urlService, "http://localhost:8080", logs, nil,
+		WithClickRecorder(&syncRecorder{service: urlService, at: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)}))
+
+	// withRoute добавляет короткую ссылку в параметры маршрута и пользователя в контекст
+	withRoute := func(r *http.Request, shortURL, userID string) *http.Request {
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("id", shortURL)
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx)
+		ctx = context.WithValue(ctx, middleware.UserIDContextKey, userID)
+		return r.WithContext(ctx)
+	}
+	stats := func(shortURL, userID, query string) *httptest.ResponseRecorder {
+		rRequest := withRoute(httptest.NewRequest("GET", "/api/user/urls/"+shortURL+"/stats"+query, nil), shortURL, userID)
+		wResonse := httptest.NewRecorder()
+
+		shortHandlers.GetURLStats(wResonse, rRequest)
+		return wResonse
+	}
+
+	rRequest := httptest.NewRequest("POST", "/api/shorten",
+		strings.NewReader(`{"url": "https://example.com/stats", "custom_alias": "stats"}`))
+	rRequest = rRequest.WithContext(context.WithValue(rRequest.Context(), middleware.UserIDContextKey, "owner"))
+	wResonse := httptest.NewRecorder()
+	shortHandlers.PostJSON(wResonse, rRequest)
+	require.Equal(t, http.StatusCreated, wResonse.Code)
+
+	// каждый переход записывается
+	for _, addr := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		rRequest := withRoute(httptest.NewRequest("GET", "/stats", nil), "stats", "")
+		rRequest.RemoteAddr = addr
+		wResonse := httptest.NewRecorder()
+		shortHandlers.GetURL(wResonse, rRequest)
+		require.Equal(t, http.StatusTemporaryRedirect, wResonse.Code)
+	}
+
+	t.Run("owner", func(t *testing.T) {
+		wResonse := stats("stats", "owner", "?bucket=hour&since=2024-01-01T00:00:00Z")
+		require.Equal(t, http.StatusOK, wResonse.Code)
+		assert.Equal(t, "application/json", wResonse.Header().Get("Content-Type"))
+
+		var result models.LinkStats
+		require.NoError(t, json.NewDecoder(wResonse.Body).Decode(&result))
+		assert.Equal(t, "http://localhost:8080/stats", result.ShortURL)
+		assert.Equal(t, 3, result.Total)
+		assert.Equal(t, 2, result.Unique)
+		assert.Equal(t, "hour", result.Bucket)
+		assert.Equal(t, []models.ClicksBucket{
+			{Start: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), Clicks: 3},
+		}, result.Buckets)
+	})
+
+	t.Run("default_bucket", func(t *testing.T) {
+		wResonse := stats("stats", "owner", "")
+		require.Equal(t, http.StatusOK, wResonse.Code)
+		assert.Contains(t, wResonse.Body.String(), `"bucket":"day"`)
+	})
+
+	t.Run("foreign", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, stats("stats", "stranger", "").Code)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, stats("missing", "owner", "").Code)
+	})
+
+	t.Run("invalid_bucket", func(t *testing.T) {
+		wResonse := stats("stats", "owner", "?bucket=week")
+		assert.Equal(t, http.StatusBadRequest, wResonse.Code)
+		assert.Contains(t, wResonse.Body.String(), "invalid stats request")
+	})
+
+	t.Run("invalid_since", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, stats("stats", "owner", "?since=yesterday").Code)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, stats("stats", "", "").Code)
+	})
+}
+
+func TestGetInternalStats(t *testing.T) {
+	logs := logger.NewLogger(logger.WithLevel("info"))
+	storage := mapstorage.NewMapURL()
+	shortHandlers := NewHandlers(service.NewService(storage, logs), "http://localhost:8080", logs, nil)
+
+	ctx := context.Background()
+	_, err := storage.SaveURL(ctx, "first", "https://example.com/1", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = storage.SaveURL(ctx, "second", "https://example.com/2", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+
+	wResonse := httptest.NewRecorder()
+	shortHandlers.GetInternalStats(wResonse, httptest.NewRequest("GET", "/api/internal/stats", nil))
+
+	require.Equal(t, http.StatusOK, wResonse.Code)
+	assert.Equal(t, "application/json", wResonse.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"urls": 2, "users": 1}`, wResonse.Body.String())
+}
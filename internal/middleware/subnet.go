@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// RealIPHeader - заголовок с IP-адресом клиента, который выставляет прокси.
+const RealIPHeader = "X-Real-IP"
+
+// TrustedSubnet пропускает только запросы, у которых IP из заголовка
+// X-Real-IP входит в доверенную подсеть, остальным отвечает 403.
+// Если подсеть не задана, доступ запрещен всем.
+func TrustedSubnet(subnet *net.IPNet) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if !InSubnet(subnet, r.Header.Get(RealIPHeader)) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// InSubnet проверяет, что адрес ip входит в подсеть; без подсети - false.
+func InSubnet(subnet *net.IPNet, ip string) bool {
+	if subnet == nil {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	// IPv4, записанный как IPv6 (::ffff:10.0.0.1), сравнивается как IPv4
+	return subnet.Contains(net.IP(addr.Unmap().AsSlice()))
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		subnet       *net.IPNet
+		realIP       string
+		expectedCode int
+	}{
+		{name: "trusted", subnet: subnet, realIP: "192.168.1.10", expectedCode: http.StatusOK},
+		{name: "mapped_ipv4", subnet: subnet, realIP: "::ffff:192.168.1.10", expectedCode: http.StatusOK},
+		{name: "foreign", subnet: subnet, realIP: "192.168.2.10", expectedCode: http.StatusForbidden},
+		{name: "no_header", subnet: subnet, realIP: "", expectedCode: http.StatusForbidden},
+		{name: "invalid_ip", subnet: subnet, realIP: "192.168.1.10, 10.0.0.1", expectedCode: http.StatusForbidden},
+		{name: "no_subnet", subnet: nil, realIP: "192.168.1.10", expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/internal/stats", nil)
+			if tt.realIP != "" {
+				r.Header.Set(RealIPHeader, tt.realIP)
+			}
+			w := httptest.NewRecorder()
+
+			handler := TrustedSubnet(tt.subnet)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
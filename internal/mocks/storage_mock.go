@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/kamencov/go-musthave-shortener-tpl/internal/models"
@@ -35,18 +37,48 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 }
 
 // CheckURL mocks base method.
-func (m *MockStorage) CheckURL(arg0 string) (string, error) {
+func (m *MockStorage) CheckURL(ctx context.Context, originalURL string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckURL", arg0)
+	ret := m.ctrl.Call(m, "CheckURL", ctx, originalURL)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CheckURL indicates an expected call of CheckURL.
-func (mr *MockStorageMockRecorder) CheckURL(arg0 interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) CheckURL(ctx, originalURL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckURL", reflect.TypeOf((*MockStorage)(nil).CheckURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckURL", reflect.TypeOf((*MockStorage)(nil).CheckURL), ctx, originalURL)
+}
+
+// ClaimDeletionJobs mocks base method.
+func (m *MockStorage) ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDeletionJobs", ctx, before, until, limit)
+	ret0, _ := ret[0].([]models.DeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDeletionJobs indicates an expected call of ClaimDeletionJobs.
+func (mr *MockStorageMockRecorder) ClaimDeletionJobs(ctx, before, until, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDeletionJobs", reflect.TypeOf((*MockStorage)(nil).ClaimDeletionJobs), ctx, before, until, limit)
+}
+
+// ClickStats mocks base method.
+func (m *MockStorage) ClickStats(ctx context.Context, shortURL string, since time.Time, bucket time.Duration) (*models.LinkStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClickStats", ctx, shortURL, since, bucket)
+	ret0, _ := ret[0].(*models.LinkStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClickStats indicates an expected call of ClickStats.
+func (mr *MockStorageMockRecorder) ClickStats(ctx, shortURL, since, bucket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClickStats", reflect.TypeOf((*MockStorage)(nil).ClickStats), ctx, shortURL, since, bucket)
 }
 
 // Close mocks base method.
@@ -63,89 +95,253 @@ func (mr *MockStorageMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStorage)(nil).Close))
 }
 
+// DeleteURLsBatch mocks base method.
+func (m *MockStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteURLsBatch", ctx, urls)
+	ret0, _ := ret[0].([]models.DeletedURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteURLsBatch indicates an expected call of DeleteURLsBatch.
+func (mr *MockStorageMockRecorder) DeleteURLsBatch(ctx, urls interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURLsBatch", reflect.TypeOf((*MockStorage)(nil).DeleteURLsBatch), ctx, urls)
+}
+
 // DeletedURLs mocks base method.
-func (m *MockStorage) DeletedURLs(urls []string, userID string) error {
+func (m *MockStorage) DeletedURLs(ctx context.Context, urls []string, userID string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletedURLs", urls, userID)
+	ret := m.ctrl.Call(m, "DeletedURLs", ctx, urls, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeletedURLs indicates an expected call of DeletedURLs.
-func (mr *MockStorageMockRecorder) DeletedURLs(urls, userID interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) DeletedURLs(ctx, urls, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletedURLs", reflect.TypeOf((*MockStorage)(nil).DeletedURLs), ctx, urls, userID)
+}
+
+// DueDeletionJobs mocks base method.
+func (m *MockStorage) DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DueDeletionJobs", ctx, before, afterID, limit)
+	ret0, _ := ret[0].([]models.DeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DueDeletionJobs indicates an expected call of DueDeletionJobs.
+func (mr *MockStorageMockRecorder) DueDeletionJobs(ctx, before, afterID, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletedURLs", reflect.TypeOf((*MockStorage)(nil).DeletedURLs), urls, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DueDeletionJobs", reflect.TypeOf((*MockStorage)(nil).DueDeletionJobs), ctx, before, afterID, limit)
 }
 
 // GetAllURL mocks base method.
-func (m *MockStorage) GetAllURL(userID, baseURL string) ([]*models.UserURLs, error) {
+func (m *MockStorage) GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllURL", userID, baseURL)
+	ret := m.ctrl.Call(m, "GetAllURL", ctx, userID, baseURL)
 	ret0, _ := ret[0].([]*models.UserURLs)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllURL indicates an expected call of GetAllURL.
-func (mr *MockStorageMockRecorder) GetAllURL(userID, baseURL interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetAllURL(ctx, userID, baseURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllURL", reflect.TypeOf((*MockStorage)(nil).GetAllURL), ctx, userID, baseURL)
+}
+
+// GetDeletionJob mocks base method.
+func (m *MockStorage) GetDeletionJob(ctx context.Context, id int64) (*models.DeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletionJob", ctx, id)
+	ret0, _ := ret[0].(*models.DeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletionJob indicates an expected call of GetDeletionJob.
+func (mr *MockStorageMockRecorder) GetDeletionJob(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllURL", reflect.TypeOf((*MockStorage)(nil).GetAllURL), userID, baseURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletionJob", reflect.TypeOf((*MockStorage)(nil).GetDeletionJob), ctx, id)
+}
+
+// GetStats mocks base method.
+func (m *MockStorage) GetStats(ctx context.Context) (*models.InternalStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", ctx)
+	ret0, _ := ret[0].(*models.InternalStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockStorageMockRecorder) GetStats(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockStorage)(nil).GetStats), ctx)
 }
 
 // GetURL mocks base method.
-func (m *MockStorage) GetURL(arg0 string) (string, error) {
+func (m *MockStorage) GetURL(ctx context.Context, shortURL string) (*models.Storage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetURL", arg0)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GetURL", ctx, shortURL)
+	ret0, _ := ret[0].(*models.Storage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetURL indicates an expected call of GetURL.
-func (mr *MockStorageMockRecorder) GetURL(arg0 interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetURL(ctx, shortURL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockStorage)(nil).GetURL), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockStorage)(nil).GetURL), ctx, shortURL)
 }
 
 // Ping mocks base method.
-func (m *MockStorage) Ping() error {
+func (m *MockStorage) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
+	ret := m.ctrl.Call(m, "Ping", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Ping indicates an expected call of Ping.
-func (mr *MockStorageMockRecorder) Ping() *gomock.Call {
+func (mr *MockStorageMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping), ctx)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockStorage) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockStorageMockRecorder) PurgeDeleted(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockStorage)(nil).PurgeDeleted), ctx, limit)
+}
+
+// PurgeDeletionJobs mocks base method.
+func (m *MockStorage) PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletionJobs", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletionJobs indicates an expected call of PurgeDeletionJobs.
+func (mr *MockStorageMockRecorder) PurgeDeletionJobs(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletionJobs", reflect.TypeOf((*MockStorage)(nil).PurgeDeletionJobs), ctx, before)
+}
+
+// PurgeExpired mocks base method.
+func (m *MockStorage) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpired", ctx, before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpired indicates an expected call of PurgeExpired.
+func (mr *MockStorageMockRecorder) PurgeExpired(ctx, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpired", reflect.TypeOf((*MockStorage)(nil).PurgeExpired), ctx, before, limit)
+}
+
+// SaveClicks mocks base method.
+func (m *MockStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveClicks", ctx, clicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveClicks indicates an expected call of SaveClicks.
+func (mr *MockStorageMockRecorder) SaveClicks(ctx, clicks interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveClicks", reflect.TypeOf((*MockStorage)(nil).SaveClicks), ctx, clicks)
 }
 
-// SaveSliceOfDB mocks base method.
-func (m *MockStorage) SaveSlice(urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+// SaveDeletionJob mocks base method.
+func (m *MockStorage) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveSlice", urls, baseURL, userID)
+	ret := m.ctrl.Call(m, "SaveDeletionJob", ctx, job)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveDeletionJob indicates an expected call of SaveDeletionJob.
+func (mr *MockStorageMockRecorder) SaveDeletionJob(ctx, job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDeletionJob", reflect.TypeOf((*MockStorage)(nil).SaveDeletionJob), ctx, job)
+}
+
+// SaveSlice mocks base method.
+func (m *MockStorage) SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSlice", ctx, urls, baseURL, userID)
 	ret0, _ := ret[0].([]models.ResultMultipleURL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// SaveSliceOfDB indicates an expected call of SaveSliceOfDB.
-func (mr *MockStorageMockRecorder) SaveSliceOfDB(urls, baseURL, userID interface{}) *gomock.Call {
+// SaveSlice indicates an expected call of SaveSlice.
+func (mr *MockStorageMockRecorder) SaveSlice(ctx, urls, baseURL, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSlice", reflect.TypeOf((*MockStorage)(nil).SaveSlice), urls, baseURL, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSlice", reflect.TypeOf((*MockStorage)(nil).SaveSlice), ctx, urls, baseURL, userID)
 }
 
 // SaveURL mocks base method.
-func (m *MockStorage) SaveURL(shortURL, originalURL, userID string) error {
+func (m *MockStorage) SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveURL", shortURL, originalURL, userID)
+	ret := m.ctrl.Call(m, "SaveURL", ctx, shortURL, originalURL, userID, opts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveURL indicates an expected call of SaveURL.
+func (mr *MockStorageMockRecorder) SaveURL(ctx, shortURL, originalURL, userID, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockStorage)(nil).SaveURL), ctx, shortURL, originalURL, userID, opts)
+}
+
+// UpdateDeletionJobs mocks base method.
+func (m *MockStorage) UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeletionJobs", ctx, jobs)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveURL indicates an expected call of SaveURL.
-func (mr *MockStorageMockRecorder) SaveURL(shortURL, originalURL, userID interface{}) *gomock.Call {
+// UpdateDeletionJobs indicates an expected call of UpdateDeletionJobs.
+func (mr *MockStorageMockRecorder) UpdateDeletionJobs(ctx, jobs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeletionJobs", reflect.TypeOf((*MockStorage)(nil).UpdateDeletionJobs), ctx, jobs)
+}
+
+// UseClick mocks base method.
+func (m *MockStorage) UseClick(ctx context.Context, shortURL string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseClick", ctx, shortURL)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseClick indicates an expected call of UseClick.
+func (mr *MockStorageMockRecorder) UseClick(ctx, shortURL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockStorage)(nil).SaveURL), shortURL, originalURL, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseClick", reflect.TypeOf((*MockStorage)(nil).UseClick), ctx, shortURL)
 }
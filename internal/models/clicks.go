@@ -0,0 +1,67 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Click - переход по короткой ссылке.
+// IPHash - HMAC-хеш IP-адреса клиента, сам адрес не хранится.
+type Click struct {
+	ShortURL  string    `db:"short_url" json:"short_url"`
+	At        time.Time `db:"clicked_at" json:"at"`
+	Referrer  string    `db:"referrer" json:"referrer,omitempty"`
+	UserAgent string    `db:"user_agent" json:"user_agent,omitempty"`
+	IPHash    string    `db:"ip_hash" json:"ip_hash,omitempty"`
+}
+
+// ClicksBucket - число переходов за интервал, начинающийся в Start.
+type ClicksBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int       `json:"clicks"`
+}
+
+// LinkStats - статистика переходов по короткой ссылке.
+// Total и Unique - число переходов и уникальных IP за все время,
+// Buckets - непустые интервалы длины Bucket по возрастанию времени.
+type LinkStats struct {
+	ShortURL string         `json:"short_url"`
+	Total    int            `json:"total"`
+	Unique   int            `json:"unique_visitors"`
+	Bucket   string         `json:"bucket"`
+	Buckets  []ClicksBucket `json:"buckets"`
+}
+
+// BucketStart возвращает начало интервала длины bucket, в который попадает t.
+// Интервалы отсчитываются от начала эпохи Unix в UTC.
+func BucketStart(t time.Time, bucket time.Duration) time.Time {
+	ms := t.UnixMilli()
+	return time.UnixMilli(ms - ms%bucket.Milliseconds()).UTC()
+}
+
+// CountClicks считает статистику по переходам одной ссылки:
+// Total и Unique - по всем переходам, интервалы - по переходам начиная с since.
+func CountClicks(clicks []Click, since time.Time, bucket time.Duration) *LinkStats {
+	stats := &LinkStats{}
+	visitors := make(map[string]struct{})
+	buckets := make(map[time.Time]int)
+	for _, click := range clicks {
+		stats.Total++
+		if click.IPHash != "" {
+			visitors[click.IPHash] = struct{}{}
+		}
+		if !click.At.Before(since) {
+			buckets[BucketStart(click.At, bucket)]++
+		}
+	}
+	stats.Unique = len(visitors)
+
+	for start, count := range buckets {
+		stats.Buckets = append(stats.Buckets, ClicksBucket{Start: start, Clicks: count})
+	}
+	slices.SortFunc(stats.Buckets, func(a, b ClicksBucket) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return stats
+}
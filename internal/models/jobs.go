@@ -0,0 +1,157 @@
+package models
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Статусы задания на удаление.
+const (
+	// JobQueued - задание ждет выполнения или повтора после неудачной попытки.
+	JobQueued = "queued"
+	// JobRunning - воркер удаляет ссылки задания.
+	JobRunning = "running"
+	// JobDone - ссылки задания удалены.
+	JobDone = "done"
+	// JobFailed - задание отложено после исчерпания попыток.
+	JobFailed = "failed"
+)
+
+// DeletionJob - сохраненное задание на удаление ссылок пользователя.
+//
+// Attempts - число неудачных попыток, NextAttempt - момент, с которого
+// задание можно выполнять снова, LastError - ошибка последней попытки.
+// Deleted и Skipped - ссылки выполненного задания, которые удалены и
+// которые пропущены, потому что пользователю не принадлежат.
+// FinishedAt - момент, когда задание выполнено или отложено.
+type DeletionJob struct {
+	ID          int64      `json:"id"`
+	UserID      string     `json:"user_id"`
+	URLs        []string   `json:"urls"`
+	Status      string     `json:"status,omitempty"`
+	Attempts    int        `json:"attempts,omitempty"`
+	NextAttempt time.Time  `json:"next_attempt"`
+	LastError   string     `json:"last_error,omitempty"`
+	Deleted     []string   `json:"deleted,omitempty"`
+	Skipped     []string   `json:"skipped,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// Finished сообщает, что задание выполнено или отложено и больше не выполняется.
+func (j DeletionJob) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+// Clone возвращает копию задания, не разделяющую с ним срезы.
+func (j DeletionJob) Clone() DeletionJob {
+	j.URLs = slices.Clone(j.URLs)
+	j.Deleted = slices.Clone(j.Deleted)
+	j.Skipped = slices.Clone(j.Skipped)
+	if j.FinishedAt != nil {
+		finishedAt := *j.FinishedAt
+		j.FinishedAt = &finishedAt
+	}
+
+	return j
+}
+
+// DeletionJobStatus - ответ о состоянии задания на удаление.
+type DeletionJobStatus struct {
+	JobID    int64    `json:"job_id"`
+	Status   string   `json:"status"`
+	URLs     []string `json:"urls,omitempty"`
+	Deleted  []string `json:"deleted"`
+	Skipped  []string `json:"skipped"`
+	Attempts int      `json:"attempts"`
+}
+
+// NewDeletionJobStatus собирает ответ о состоянии задания.
+// Пустое состояние задания прошлых версий считается JobQueued.
+func NewDeletionJobStatus(job DeletionJob) DeletionJobStatus {
+	status := DeletionJobStatus{
+		JobID:    job.ID,
+		Status:   job.Status,
+		URLs:     job.URLs,
+		Deleted:  job.Deleted,
+		Skipped:  job.Skipped,
+		Attempts: job.Attempts,
+	}
+	if status.Status == "" {
+		status.Status = JobQueued
+	}
+	if status.Deleted == nil {
+		status.Deleted = []string{}
+	}
+	if status.Skipped == nil {
+		status.Skipped = []string{}
+	}
+
+	return status
+}
+
+// UpdateJob возвращает сохраненное задание stored с изменяемыми полями
+// из job: Status, Attempts, NextAttempt, LastError, Deleted, Skipped и
+// FinishedAt. Используется хранилищами, которые держат задания целиком.
+func UpdateJob(stored, job DeletionJob) DeletionJob {
+	job = job.Clone()
+	stored.Status = job.Status
+	stored.Attempts = job.Attempts
+	stored.NextAttempt = job.NextAttempt
+	stored.LastError = job.LastError
+	stored.Deleted = job.Deleted
+	stored.Skipped = job.Skipped
+	stored.FinishedAt = job.FinishedAt
+
+	return stored
+}
+
+// DueJobs выбирает из jobs не больше limit невыполненных заданий с ID
+// больше afterID и NextAttempt не позже before в порядке ID.
+// Используется хранилищами, которые держат задания в памяти.
+func DueJobs(jobs map[int64]DeletionJob, before time.Time, afterID int64, limit int) []DeletionJob {
+	due := make([]DeletionJob, 0)
+	for id, job := range jobs {
+		if id > afterID && !job.Finished() && !job.NextAttempt.After(before) {
+			due = append(due, job.Clone())
+		}
+	}
+
+	slices.SortFunc(due, func(a, b DeletionJob) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due
+}
+
+// ClaimJobs выбирает из jobs не больше limit невыполненных заданий с
+// NextAttempt не позже before, переносит их NextAttempt на until и
+// возвращает в порядке ID. Используется хранилищами, которые держат
+// задания в памяти.
+func ClaimJobs(jobs map[int64]DeletionJob, before, until time.Time, limit int) []DeletionJob {
+	claimed := DueJobs(jobs, before, 0, limit)
+	for i := range claimed {
+		claimed[i].NextAttempt = until
+		jobs[claimed[i].ID] = claimed[i].Clone()
+	}
+
+	return claimed
+}
+
+// FinishedJobs возвращает ID заданий из jobs, которые выполнены или
+// отложены раньше before. Используется хранилищами, которые держат
+// задания в памяти.
+func FinishedJobs(jobs map[int64]DeletionJob, before time.Time) []int64 {
+	ids := make([]int64, 0)
+	for id, job := range jobs {
+		if job.Finished() && job.FinishedAt != nil && job.FinishedAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	return ids
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Причины запуска фоновой задачи планировщика.
+const (
+	// TriggerSchedule - задача запущена по расписанию.
+	TriggerSchedule = "schedule"
+	// TriggerManual - задача запущена вручную через админский API.
+	TriggerManual = "manual"
+)
+
+// JobRun - запуск фоновой задачи планировщика.
+// Error - ошибка или паника задачи, пустая при успешном запуске.
+type JobRun struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ScheduledJob - состояние фоновой задачи планировщика.
+//
+// Schedule - описание расписания, manual для задач, которые запускаются
+// только вручную. NextRun - время следующего запуска по расписанию.
+// Runs и Failures - число запусков и неудачных запусков с момента старта
+// процесса, History - последние запуски, начиная с самого нового.
+type ScheduledJob struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	Running  bool       `json:"running"`
+	NextRun  *time.Time `json:"next_run,omitempty"`
+	Runs     uint64     `json:"runs"`
+	Failures uint64     `json:"failures"`
+	History  []JobRun   `json:"history"`
+}
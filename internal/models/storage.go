@@ -1,9 +1,54 @@
 package models
 
+import "time"
+
 // Storage - структура для хранения в базе данных.
+// ExpiresAt - момент истечения ссылки, nil - бессрочная ссылка.
+// ClicksLeft - оставшееся число переходов, nil - без ограничения.
+// PasswordHash - bcrypt-хеш пароля ссылки, пустой - ссылка без пароля.
 type Storage struct {
-	UUID        string `db:"user_id" json:"user_id"`
-	ShortURL    string `db:"short_url" json:"short_url"`
-	OriginalURL string `db:"original_url" json:"original_url"`
-	DeletedFlag bool   `db:"is_deleted" json:"is_deleted"`
+	UUID         string     `db:"user_id" json:"user_id"`
+	ShortURL     string     `db:"short_url" json:"short_url"`
+	OriginalURL  string     `db:"original_url" json:"original_url"`
+	DeletedFlag  bool       `db:"is_deleted" json:"is_deleted"`
+	ExpiresAt    *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	ClicksLeft   *int       `db:"clicks_left" json:"clicks_left,omitempty"`
+	PasswordHash string     `db:"password_hash" json:"password_hash,omitempty"`
+}
+
+// Expired проверяет, истекла ли ссылка к моменту now.
+func (s *Storage) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// Protected проверяет, защищена ли ссылка паролем.
+func (s *Storage) Protected() bool {
+	return s.PasswordHash != ""
+}
+
+// Limited проверяет, ограничено ли число переходов по ссылке.
+func (s *Storage) Limited() bool {
+	return s.ClicksLeft != nil
+}
+
+// LinkOptions - необязательные параметры сохраняемой ссылки.
+// ExpiresAt - момент истечения ссылки, nil - бессрочная ссылка.
+// MaxClicks - допустимое число переходов, 0 - без ограничения.
+// Password - пароль из запроса: сервис заменяет его хешем PasswordHash,
+// хранилище сохраняет только хеш.
+type LinkOptions struct {
+	ExpiresAt    *time.Time
+	MaxClicks    int
+	Password     string
+	PasswordHash string
+}
+
+// ClicksLeft возвращает начальный остаток переходов для записи,
+// nil - без ограничения.
+func (o LinkOptions) ClicksLeft() *int {
+	if o.MaxClicks <= 0 {
+		return nil
+	}
+	clicks := o.MaxClicks
+	return &clicks
 }
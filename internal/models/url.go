@@ -1,8 +1,20 @@
 package models
 
+import "time"
+
 // URL - структура для хранения URL.
+// CustomAlias - необязательная пользовательская короткая ссылка.
+// ExpiresAt и TTL - необязательный срок жизни ссылки: момент истечения
+// или длительность вида "24h", задается не больше одного из них.
+// MaxClicks - необязательное допустимое число переходов по ссылке,
+// Password - необязательный пароль для перехода по ссылке.
 type URL struct {
-	URL string `json:"url"`
+	URL         string     `json:"url"`
+	CustomAlias string     `json:"custom_alias,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	TTL         string     `json:"ttl,omitempty"`
+	MaxClicks   int        `json:"max_clicks,omitempty"`
+	Password    string     `json:"password,omitempty"`
 }
 
 // ResultURL - структура для возвращения URL.
@@ -11,9 +23,20 @@ type ResultURL struct {
 }
 
 // MultipleURL - структура для хранения URL.
+// CustomAlias - необязательная пользовательская короткая ссылка,
+// ExpiresAt - необязательный момент истечения ссылки,
+// MaxClicks - необязательное допустимое число переходов.
 type MultipleURL struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	CustomAlias   string     `json:"custom_alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	MaxClicks     int        `json:"max_clicks,omitempty"`
+}
+
+// LinkOptions возвращает параметры ссылки элемента пакета.
+func (m MultipleURL) LinkOptions() LinkOptions {
+	return LinkOptions{ExpiresAt: m.ExpiresAt, MaxClicks: m.MaxClicks}
 }
 
 // ResultMultipleURL - структура для возвращения URL.
@@ -21,3 +44,16 @@ type ResultMultipleURL struct {
 	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
 }
+
+// ErrorResponse - структура для возвращения подробностей ошибки.
+type ErrorResponse struct {
+	Error         string `json:"error"`
+	CustomAlias   string `json:"custom_alias,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// DeletedURL - ссылка пользователя в пакете на удаление.
+type DeletedURL struct {
+	ShortURL string
+	UserID   string
+}
@@ -21,3 +21,37 @@ type UserURLs struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
 }
+
+// InternalStats - статистика сервиса: число сокращенных URL и пользователей.
+type InternalStats struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+}
+
+// StatsCounter считает InternalStats по записям хранилища:
+// учитываются только неудаленные ссылки и их владельцы.
+// Нулевое значение готово к использованию.
+type StatsCounter struct {
+	urls  int
+	users map[string]struct{}
+}
+
+// Add учитывает запись.
+func (c *StatsCounter) Add(record *Storage) {
+	if record.DeletedFlag {
+		return
+	}
+	c.urls++
+	if record.UUID == "" {
+		return
+	}
+	if c.users == nil {
+		c.users = make(map[string]struct{})
+	}
+	c.users[record.UUID] = struct{}{}
+}
+
+// Stats возвращает подсчитанную статистику.
+func (c *StatsCounter) Stats() *InternalStats {
+	return &InternalStats{URLs: c.urls, Users: len(c.users)}
+}
@@ -0,0 +1,7 @@
+// Package pb - сгенерированный код gRPC API сокращателя ссылок.
+//
+// Описание сервиса - в shortener.proto, код генерируется protoc
+// с плагинами protoc-gen-go и protoc-gen-go-grpc.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shortener.proto
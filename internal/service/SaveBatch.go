@@ -1,8 +1,31 @@
 package service
 
-import "github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+import (
+	"context"
 
-// SaveSliceOfDB сохраняет массив коротких ссылок в базу данных
-func (s *Service) SaveSliceOfDB(urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
-	return s.storage.SaveSlice(urls, baseURL, userID)
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// SaveSliceOfDB сохраняет массив коротких ссылок в базу данных.
+// Пользовательские ссылки, сроки жизни и ограничения переходов пакета
+// проверяются до обращения к хранилищу.
+func (s *Service) SaveSliceOfDB(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	if err := validateAliases(urls); err != nil {
+		return nil, err
+	}
+	for i := range urls {
+		if err := validateMaxClicks(urls[i].MaxClicks); err != nil {
+			return nil, err
+		}
+		expiresAt, err := s.expiresAt(userID, urls[i].ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		urls[i].ExpiresAt = expiresAt
+	}
+
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.SaveSlice(ctx, urls, baseURL, userID)
 }
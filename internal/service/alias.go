@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// Ограничения длины пользовательской короткой ссылки.
+const (
+	minAliasLength = 3
+	maxAliasLength = 64
+)
+
+// reservedAliases - первые сегменты маршрутов сервиса, которые
+// нельзя занять пользовательской ссылкой.
+var reservedAliases = map[string]bool{
+	"ping":    true,
+	"api":     true,
+	"swagger": true,
+	"debug":   true,
+}
+
+// ValidateAlias проверяет пользовательскую короткую ссылку: длина от 3 до 64
+// символов, только латинские буквы, цифры, '-' и '_', не маршрут сервиса.
+func ValidateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return &errors2.AliasError{Alias: alias, Err: errors2.ErrInvalidAlias}
+	}
+
+	for _, c := range alias {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return &errors2.AliasError{Alias: alias, Err: errors2.ErrInvalidAlias}
+		}
+	}
+
+	if reservedAliases[strings.ToLower(alias)] {
+		return &errors2.AliasError{Alias: alias, Err: errors2.ErrReservedAlias}
+	}
+
+	return nil
+}
+
+// SaveCustomURL сохраняет URL под пользовательской короткой ссылкой.
+// Для уже сохраненного URL возвращает его короткую ссылку и ErrConflict,
+// для занятой ссылки - AliasError с ErrAliasTaken.
+func (s *Service) SaveCustomURL(ctx context.Context, url, alias, userID string, opts models.LinkOptions) (string, error) {
+	if err := ValidateAlias(alias); err != nil {
+		return "", err
+	}
+	if err := validateMaxClicks(opts.MaxClicks); err != nil {
+		return "", err
+	}
+	if err := s.protect(&opts); err != nil {
+		return "", err
+	}
+
+	var err error
+	if opts.ExpiresAt, err = s.expiresAt(userID, opts.ExpiresAt); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	shortURL, err := s.storage.SaveURL(ctx, alias, url, userID, opts)
+	switch {
+	case errors.Is(err, errors2.ErrConflict) && shortURL != "":
+		return shortURL, errors2.ErrConflict
+	case errors.Is(err, errors2.ErrShortURLCollision):
+		return "", &errors2.AliasError{Alias: alias, Err: errors2.ErrAliasTaken}
+	case err != nil:
+		s.logger.Error("Error = ", logger.ErrAttr(err))
+		return "", err
+	}
+
+	return shortURL, nil
+}
+
+// validateAliases проверяет пользовательские ссылки пакета:
+// одна ссылка не может достаться разным URL.
+func validateAliases(urls []models.MultipleURL) error {
+	owners := make(map[string]string)
+	for _, req := range urls {
+		if req.CustomAlias == "" {
+			continue
+		}
+		if err := ValidateAlias(req.CustomAlias); err != nil {
+			return err
+		}
+
+		if original, ok := owners[req.CustomAlias]; ok && original != req.OriginalURL {
+			return &errors2.AliasError{Alias: req.CustomAlias, Err: errors2.ErrAliasTaken}
+		}
+		owners[req.CustomAlias] = req.OriginalURL
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		alias   string
+		wantErr error
+	}{
+		{alias: "spring-sale"},
+		{alias: "Sale_2024"},
+		{alias: "ab", wantErr: errors2.ErrInvalidAlias},
+		{alias: strings.Repeat("a", maxAliasLength+1), wantErr: errors2.ErrInvalidAlias},
+		{alias: "spring sale", wantErr: errors2.ErrInvalidAlias},
+		{alias: "spring/sale", wantErr: errors2.ErrInvalidAlias},
+		{alias: "распродажа", wantErr: errors2.ErrInvalidAlias},
+		{alias: "ping", wantErr: errors2.ErrReservedAlias},
+		{alias: "API", wantErr: errors2.ErrReservedAlias},
+		{alias: "swagger", wantErr: errors2.ErrReservedAlias},
+		{alias: "debug", wantErr: errors2.ErrReservedAlias},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestService_SaveCustomURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	service := NewService(storage, logger.NewLogger(logger.WithLevel("info")))
+	ctx := context.Background()
+
+	t.Run("saved", func(t *testing.T) {
+		storage.EXPECT().SaveURL(gomock.Any(), "spring-sale", "https://example.com", "user", gomock.Any()).
+			Return("spring-sale", nil)
+
+		shortURL, err := service.SaveCustomURL(ctx, "https://example.com", "spring-sale", "user", models.LinkOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "spring-sale", shortURL)
+	})
+
+	t.Run("taken", func(t *testing.T) {
+		storage.EXPECT().SaveURL(gomock.Any(), "spring-sale", "https://example.com", "user", gomock.Any()).
+			Return("", errors2.ErrShortURLCollision)
+
+		_, err := service.SaveCustomURL(ctx, "https://example.com", "spring-sale", "user", models.LinkOptions{})
+		assert.ErrorIs(t, err, errors2.ErrAliasTaken)
+	})
+
+	t.Run("existing_url", func(t *testing.T) {
+		storage.EXPECT().SaveURL(gomock.Any(), "spring-sale", "https://example.com", "user", gomock.Any()).
+			Return("oldShort", errors2.ErrConflict)
+
+		shortURL, err := service.SaveCustomURL(ctx, "https://example.com", "spring-sale", "user", models.LinkOptions{})
+		assert.ErrorIs(t, err, errors2.ErrConflict)
+		assert.Equal(t, "oldShort", shortURL)
+	})
+
+	t.Run("reserved", func(t *testing.T) {
+		// хранилище не вызывается
+		_, err := service.SaveCustomURL(ctx, "https://example.com", "ping", "user", models.LinkOptions{})
+		assert.ErrorIs(t, err, errors2.ErrReservedAlias)
+	})
+}
+
+func TestService_SaveSliceOfDB_Aliases(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	service := NewService(storage, logger.NewLogger(logger.WithLevel("info")))
+	ctx := context.Background()
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := service.SaveSliceOfDB(ctx, []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://example.com", CustomAlias: "a b"},
+		}, "http://localhost:8080", "user")
+		assert.ErrorIs(t, err, errors2.ErrInvalidAlias)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := service.SaveSliceOfDB(ctx, []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://example.com/1", CustomAlias: "spring-sale"},
+			{CorrelationID: "2", OriginalURL: "https://example.com/2", CustomAlias: "spring-sale"},
+		}, "http://localhost:8080", "user")
+		assert.ErrorIs(t, err, errors2.ErrAliasTaken)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		urls := []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://example.com/1", CustomAlias: "spring-sale"},
+			{CorrelationID: "2", OriginalURL: "https://example.com/1", CustomAlias: "spring-sale"},
+		}
+		storage.EXPECT().SaveSlice(gomock.Any(), urls, "http://localhost:8080", "user").
+			Return([]models.ResultMultipleURL{}, nil)
+
+		_, err := service.SaveSliceOfDB(ctx, urls, "http://localhost:8080", "user")
+		assert.NoError(t, err)
+	})
+}
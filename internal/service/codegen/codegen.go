@@ -0,0 +1,213 @@
+// Package codegen - стратегии генерации коротких ссылок.
+//
+// Доступны стратегии:
+//
+//	random     - случайная ссылка из crypto/rand с настраиваемым алфавитом и длиной
+//	sequential - base62 от счетчика в хранилище
+//	hash       - детерминированный хэш оригинального URL
+//	hashids    - счетчик хранилища, обфусцированный в стиле Hashids
+//
+// Уникальность ссылок обеспечивает хранилище: при коллизии Unique
+// запрашивает у стратегии следующую попытку.
+package codegen
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+)
+
+// Стратегии генерации.
+const (
+	StrategyRandom     = "random"
+	StrategySequential = "sequential"
+	StrategyHash       = "hash"
+	StrategyHashids    = "hashids"
+)
+
+// Алфавиты.
+const (
+	// Letters - латинские буквы, исторический алфавит коротких ссылок.
+	Letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// Base62 - цифры и латинские буквы.
+	Base62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// MaxAttempts - сколько раз генерировать новую ссылку при коллизиях.
+const MaxAttempts = 10
+
+// ErrEmptyURL - ошибка, если передан пустой URL.
+var ErrEmptyURL = errors.New("URL is empty")
+
+// ErrUnknownStrategy - ошибка, если стратегия не поддерживается.
+var ErrUnknownStrategy = errors.New("unknown short code strategy")
+
+// ErrCounterRequired - ошибка, если стратегии нужен счетчик, а хранилище его не предоставляет.
+var ErrCounterRequired = errors.New("short code strategy requires a storage counter")
+
+// Метрики генерации коротких ссылок, доступны в /debug/vars.
+var (
+	codesGenerated = expvar.NewInt("short_codes_generated")
+	codeCollisions = expvar.NewInt("short_code_collisions")
+	codeLength     = expvar.NewInt("short_code_length")
+)
+
+func init() {
+	expvar.Publish("short_code_collision_rate", expvar.Func(func() any {
+		generated := codesGenerated.Value()
+		if generated == 0 {
+			return 0.0
+		}
+		return float64(codeCollisions.Value()) / float64(generated)
+	}))
+}
+
+// CodeGenerator - стратегия генерации коротких ссылок.
+type CodeGenerator interface {
+	// Generate возвращает короткую ссылку для url. attempt - номер попытки,
+	// начиная с 0: при коллизии детерминированная стратегия должна
+	// вернуть для следующей попытки другую ссылку.
+	Generate(ctx context.Context, url string, attempt int) (string, error)
+}
+
+// Counter - монотонный счетчик в хранилище для последовательных стратегий.
+type Counter interface {
+	NextID(ctx context.Context) (uint64, error)
+}
+
+// Receiver - хранилище, которое само генерирует ссылки для пакетов URL.
+type Receiver interface {
+	SetCodeGenerator(gen CodeGenerator)
+}
+
+// collisionObserver - стратегия, которая подстраивается под частоту коллизий.
+type collisionObserver interface {
+	observeCollision()
+}
+
+// Config - параметры стратегии. Нулевые значения заменяются
+// значениями по умолчанию для выбранной стратегии.
+type Config struct {
+	Strategy string
+	Alphabet string
+	Length   int
+	Salt     string
+}
+
+// New создает генератор по конфигурации. counter нужен только
+// стратегиям sequential и hashids и может быть nil для остальных.
+func New(cfg Config, counter Counter) (CodeGenerator, error) {
+	switch cfg.Strategy {
+	case "", StrategyRandom:
+		return NewRandom(orDefault(cfg.Alphabet, Letters), orDefaultInt(cfg.Length, defaultRandomLength))
+	case StrategyHash:
+		return NewHash(orDefault(cfg.Alphabet, Letters), orDefaultInt(cfg.Length, defaultHashLength), cfg.Salt)
+	case StrategySequential:
+		if counter == nil {
+			return nil, fmt.Errorf("%w: %s", ErrCounterRequired, cfg.Strategy)
+		}
+		return NewSequential(counter, orDefault(cfg.Alphabet, Base62), cfg.Length)
+	case StrategyHashids:
+		if counter == nil {
+			return nil, fmt.Errorf("%w: %s", ErrCounterRequired, cfg.Strategy)
+		}
+		return NewHashids(counter, cfg.Alphabet, orDefaultInt(cfg.Length, defaultHashidsLength), cfg.Salt)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, cfg.Strategy)
+	}
+}
+
+// Default возвращает генератор по умолчанию: случайные ссылки из 5 латинских букв.
+func Default() CodeGenerator {
+	gen, _ := NewRandom(Letters, defaultRandomLength)
+	return gen
+}
+
+// Unique генерирует короткую ссылку для url и передает ее в save.
+// Пока save возвращает ErrShortURLCollision, ссылка генерируется заново,
+// не больше MaxAttempts раз. Возвращает последнюю ссылку и ошибку save.
+func Unique(ctx context.Context, gen CodeGenerator, url string, save func(shortURL string) error) (string, error) {
+	for attempt := 0; attempt < MaxAttempts; attempt++ {
+		shortURL, err := gen.Generate(ctx, url, attempt)
+		if err != nil {
+			return "", err
+		}
+
+		err = save(shortURL)
+		if !errors.Is(err, errors2.ErrShortURLCollision) {
+			return shortURL, err
+		}
+		Collision(gen)
+	}
+
+	return "", fmt.Errorf("%w: no free short URL after %d attempts", errors2.ErrShortURLCollision, MaxAttempts)
+}
+
+// UniqueOrAlias сохраняет url под пользовательской ссылкой alias, если она задана,
+// иначе под ссылкой из Unique. Занятая alias не генерируется заново,
+// а возвращается ошибкой AliasError с ErrAliasTaken.
+func UniqueOrAlias(ctx context.Context, gen CodeGenerator, url, alias string, save func(shortURL string) error) (string, error) {
+	if alias == "" {
+		return Unique(ctx, gen, url, save)
+	}
+
+	err := save(alias)
+	if errors.Is(err, errors2.ErrShortURLCollision) {
+		return "", &errors2.AliasError{Alias: alias, Err: errors2.ErrAliasTaken}
+	}
+
+	return alias, err
+}
+
+// Collision учитывает, что сгенерированная ссылка оказалась занята.
+func Collision(gen CodeGenerator) {
+	codeCollisions.Add(1)
+	if observer, ok := gen.(collisionObserver); ok {
+		observer.observeCollision()
+	}
+}
+
+// generated учитывает сгенерированную ссылку в метриках.
+func generated() {
+	codesGenerated.Add(1)
+}
+
+// orDefault возвращает value или def, если value пустое.
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// orDefaultInt возвращает value или def, если value не задано.
+func orDefaultInt(value, def int) int {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
+
+// validateAlphabet проверяет, что алфавит из однобайтовых неповторяющихся символов.
+func validateAlphabet(alphabet string) error {
+	if len(alphabet) < 2 {
+		return fmt.Errorf("alphabet must contain at least 2 characters")
+	}
+
+	seen := make(map[byte]bool, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c <= ' ' || c >= 0x7f || c == '/' || c == '?' || c == '#' {
+			return fmt.Errorf("alphabet contains invalid character %q", c)
+		}
+		if seen[c] {
+			return fmt.Errorf("alphabet contains duplicate character %q", c)
+		}
+		seen[c] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counter - счетчик в памяти для тестов.
+type counter struct {
+	id  atomic.Uint64
+	err error
+}
+
+func (c *counter) NextID(ctx context.Context) (uint64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.id.Add(1), nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		counter Counter
+		want    any
+		wantErr error
+	}{
+		{name: "default", cfg: Config{}, want: &Random{}},
+		{name: "random", cfg: Config{Strategy: StrategyRandom, Alphabet: "01", Length: 8}, want: &Random{}},
+		{name: "hash", cfg: Config{Strategy: StrategyHash, Salt: "salt"}, want: &Hash{}},
+		{name: "sequential", cfg: Config{Strategy: StrategySequential}, counter: &counter{}, want: &Sequential{}},
+		{name: "hashids", cfg: Config{Strategy: StrategyHashids, Salt: "salt"}, counter: &counter{}, want: &Hashids{}},
+		{name: "sequential_without_counter", cfg: Config{Strategy: StrategySequential}, wantErr: ErrCounterRequired},
+		{name: "hashids_without_counter", cfg: Config{Strategy: StrategyHashids}, wantErr: ErrCounterRequired},
+		{name: "unknown", cfg: Config{Strategy: "uuid"}, wantErr: ErrUnknownStrategy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := New(tt.cfg, tt.counter)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, gen)
+		})
+	}
+
+	t.Run("invalid_alphabet", func(t *testing.T) {
+		for _, alphabet := range []string{"a", "aab", "ab/", "ab c"} {
+			_, err := New(Config{Strategy: StrategyRandom, Alphabet: alphabet}, nil)
+			assert.Error(t, err, alphabet)
+		}
+	})
+
+	t.Run("invalid_length", func(t *testing.T) {
+		_, err := New(Config{Strategy: StrategyRandom, Length: maxRandomLength + 1}, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestUnique(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retry_on_collision", func(t *testing.T) {
+		collisionsBefore := codeCollisions.Value()
+
+		var tried []string
+		shortURL, err := Unique(ctx, Default(), "https://example.com", func(shortURL string) error {
+			tried = append(tried, shortURL)
+			if len(tried) < 3 {
+				return errors2.ErrShortURLCollision
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, tried, 3)
+		assert.Equal(t, tried[2], shortURL)
+		assert.Equal(t, int64(2), codeCollisions.Value()-collisionsBefore)
+	})
+
+	t.Run("hash_attempts_differ", func(t *testing.T) {
+		gen, err := NewHash(Letters, defaultHashLength, "")
+		require.NoError(t, err)
+
+		var tried []string
+		_, err = Unique(ctx, gen, "https://example.com", func(shortURL string) error {
+			tried = append(tried, shortURL)
+			if len(tried) < 2 {
+				return errors2.ErrShortURLCollision
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		require.Len(t, tried, 2)
+		assert.NotEqual(t, tried[0], tried[1])
+	})
+
+	t.Run("other_error", func(t *testing.T) {
+		calls := 0
+		shortURL, err := Unique(ctx, Default(), "https://example.com", func(string) error {
+			calls++
+			return errors2.ErrConflict
+		})
+		assert.ErrorIs(t, err, errors2.ErrConflict)
+		assert.NotEmpty(t, shortURL)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("attempts_exhausted", func(t *testing.T) {
+		calls := 0
+		_, err := Unique(ctx, Default(), "https://example.com", func(string) error {
+			calls++
+			return errors2.ErrShortURLCollision
+		})
+		assert.ErrorIs(t, err, errors2.ErrShortURLCollision)
+		assert.Equal(t, MaxAttempts, calls)
+	})
+
+	t.Run("generator_error", func(t *testing.T) {
+		errCounter := errors.New("counter is unavailable")
+		gen, err := NewSequential(&counter{err: errCounter}, Base62, 0)
+		require.NoError(t, err)
+
+		_, err = Unique(ctx, gen, "https://example.com", func(string) error { return nil })
+		assert.ErrorIs(t, err, errCounter)
+	})
+
+	t.Run("empty_url", func(t *testing.T) {
+		_, err := Unique(ctx, Default(), "", func(string) error { return nil })
+		assert.ErrorIs(t, err, ErrEmptyURL)
+	})
+}
+
+func TestUniqueOrAlias(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("alias", func(t *testing.T) {
+		shortURL, err := UniqueOrAlias(ctx, Default(), "https://example.com", "spring-sale", func(shortURL string) error {
+			assert.Equal(t, "spring-sale", shortURL)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "spring-sale", shortURL)
+	})
+
+	t.Run("alias_taken", func(t *testing.T) {
+		calls := 0
+		_, err := UniqueOrAlias(ctx, Default(), "https://example.com", "spring-sale", func(string) error {
+			calls++
+			return errors2.ErrShortURLCollision
+		})
+		assert.ErrorIs(t, err, errors2.ErrAliasTaken)
+		assert.Equal(t, 1, calls)
+
+		var aliasErr *errors2.AliasError
+		require.True(t, errors.As(err, &aliasErr))
+		assert.Equal(t, "spring-sale", aliasErr.Alias)
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		shortURL, err := UniqueOrAlias(ctx, Default(), "https://example.com", "", func(string) error { return nil })
+		assert.NoError(t, err)
+		assert.Len(t, shortURL, defaultRandomLength)
+	})
+}
@@ -0,0 +1,64 @@
+package codegen
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// defaultHashLength - длина хэш-ссылок по умолчанию: длиннее случайных,
+// так как повторная попытка при коллизии дает менее предсказуемую ссылку.
+const defaultHashLength = 7
+
+// Hash - детерминированные ссылки: один и тот же URL всегда получает
+// одну и ту же ссылку. При коллизии в хэш добавляется номер попытки.
+type Hash struct {
+	alphabet string
+	length   int
+	salt     string
+}
+
+// NewHash - конструктор стратегии hash.
+func NewHash(alphabet string, length int, salt string) (*Hash, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > sha256.Size {
+		return nil, fmt.Errorf("hash code length must be in 1..%d", sha256.Size)
+	}
+
+	return &Hash{
+		alphabet: alphabet,
+		length:   length,
+		salt:     salt,
+	}, nil
+}
+
+// Generate возвращает ссылку из SHA-256 от соли, URL и номера попытки.
+func (g *Hash) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	if url == "" {
+		return "", ErrEmptyURL
+	}
+	generated()
+
+	input := g.salt + url
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+
+	// переводим хэш в систему счисления по алфавиту и берем младшие разряды
+	n := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(g.alphabet)))
+	digit := new(big.Int)
+
+	b := make([]byte, g.length)
+	for i := range b {
+		n.DivMod(n, base, digit)
+		b[i] = g.alphabet[digit.Int64()]
+	}
+
+	return string(b), nil
+}
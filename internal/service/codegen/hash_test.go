@@ -0,0 +1,39 @@
+package codegen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash_Generate(t *testing.T) {
+	ctx := context.Background()
+	gen, err := NewHash(Letters, defaultHashLength, "")
+	require.NoError(t, err)
+
+	first, err := gen.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, first, defaultHashLength)
+
+	// тот же URL - та же ссылка
+	again, err := gen.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	other, err := gen.Generate(ctx, "https://example.org", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other)
+
+	retry, err := gen.Generate(ctx, "https://example.com", 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, retry)
+
+	// соль меняет ссылки
+	salted, err := NewHash(Letters, defaultHashLength, "salt")
+	require.NoError(t, err)
+	saltedCode, err := salted.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, saltedCode)
+}
@@ -0,0 +1,59 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/speps/go-hashids/v2"
+)
+
+// defaultHashidsLength - минимальная длина Hashids-ссылок по умолчанию.
+const defaultHashidsLength = 5
+
+// Hashids - номер из счетчика хранилища, обфусцированный алгоритмом Hashids:
+// ссылки не идут подряд, но остаются короткими и уникальными по построению.
+type Hashids struct {
+	counter Counter
+	hd      *hashids.HashID
+}
+
+// NewHashids - конструктор стратегии hashids.
+// Пустой alphabet означает алфавит Hashids по умолчанию.
+func NewHashids(counter Counter, alphabet string, minLength int, salt string) (*Hashids, error) {
+	data := hashids.NewData()
+	if alphabet != "" {
+		data.Alphabet = alphabet
+	}
+	data.MinLength = minLength
+	data.Salt = salt
+
+	hd, err := hashids.NewWithData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hashids{
+		counter: counter,
+		hd:      hd,
+	}, nil
+}
+
+// Generate возвращает ссылку для следующего номера счетчика.
+// Каждая попытка берет новый номер, поэтому attempt не используется.
+func (g *Hashids) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	if url == "" {
+		return "", ErrEmptyURL
+	}
+
+	id, err := g.counter.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if id > math.MaxInt64 {
+		return "", fmt.Errorf("counter value %d is too large for hashids", id)
+	}
+	generated()
+
+	return g.hd.EncodeInt64([]int64{int64(id)})
+}
@@ -0,0 +1,35 @@
+package codegen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashids_Generate(t *testing.T) {
+	ctx := context.Background()
+	gen, err := NewHashids(&counter{}, "", defaultHashidsLength, "salt")
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := gen.Generate(ctx, "https://example.com", 0)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, len(code), defaultHashidsLength)
+		assert.False(t, seen[code], code)
+		seen[code] = true
+	}
+
+	// другая соль - другие ссылки для тех же номеров
+	other, err := NewHashids(&counter{}, "", defaultHashidsLength, "pepper")
+	require.NoError(t, err)
+	code, err := other.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	first, err := NewHashids(&counter{}, "", defaultHashidsLength, "salt")
+	require.NoError(t, err)
+	firstCode, err := first.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, firstCode, code)
+}
@@ -0,0 +1,98 @@
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// Параметры стратегии random.
+const (
+	defaultRandomLength = 5
+	// maxRandomLength - предельная длина, до которой растут случайные ссылки.
+	maxRandomLength = 12
+	// growWindow - окно генераций, в котором считается доля коллизий.
+	growWindow = 1000
+	// growCollisions - число коллизий в окне, после которого ссылки удлиняются:
+	// больше 1% коллизий означает, что пространство ссылок заполняется.
+	growCollisions = growWindow / 100
+)
+
+// Random - случайные ссылки из crypto/rand.
+// При частых коллизиях длина новых ссылок растет на один символ.
+type Random struct {
+	alphabet string
+	max      *big.Int
+
+	mu               sync.Mutex
+	length           int
+	windowGenerated  int
+	windowCollisions int
+}
+
+// NewRandom - конструктор стратегии random.
+func NewRandom(alphabet string, length int) (*Random, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > maxRandomLength {
+		return nil, fmt.Errorf("random code length must be in 1..%d", maxRandomLength)
+	}
+
+	codeLength.Set(int64(length))
+	return &Random{
+		alphabet: alphabet,
+		max:      big.NewInt(int64(len(alphabet))),
+		length:   length,
+	}, nil
+}
+
+// Generate возвращает случайную ссылку текущей длины.
+func (g *Random) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	if url == "" {
+		return "", ErrEmptyURL
+	}
+
+	b := make([]byte, g.nextLength())
+	for i := range b {
+		n, err := rand.Int(rand.Reader, g.max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = g.alphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// nextLength учитывает генерацию в окне и возвращает текущую длину ссылки.
+func (g *Random) nextLength() int {
+	generated()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.windowGenerated++
+	if g.windowGenerated >= growWindow {
+		g.windowGenerated = 0
+		g.windowCollisions = 0
+	}
+
+	return g.length
+}
+
+// observeCollision удлиняет ссылки при большой доле коллизий в окне.
+func (g *Random) observeCollision() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.windowCollisions++
+	if g.windowCollisions >= growCollisions && g.length < maxRandomLength {
+		g.length++
+		g.windowGenerated = 0
+		g.windowCollisions = 0
+		codeLength.Set(int64(g.length))
+	}
+}
@@ -0,0 +1,64 @@
+package codegen
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandom_Generate(t *testing.T) {
+	gen, err := NewRandom("01", maxRandomLength)
+	require.NoError(t, err)
+
+	code, err := gen.Generate(context.Background(), "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, code, 12)
+	assert.Empty(t, strings.Trim(code, "01"))
+}
+
+func TestRandom_Grow(t *testing.T) {
+	ctx := context.Background()
+	gen, err := NewRandom(Letters, defaultRandomLength)
+	require.NoError(t, err)
+
+	// коллизии из прошедшего окна не накапливаются
+	for i := 0; i < growCollisions-1; i++ {
+		Collision(gen)
+	}
+	for i := 0; i < growWindow; i++ {
+		_, _ = gen.Generate(ctx, "https://example.com", 0)
+	}
+	Collision(gen)
+	code, _ := gen.Generate(ctx, "https://example.com", 0)
+	assert.Len(t, code, defaultRandomLength)
+
+	// частые коллизии удлиняют ссылки на один символ
+	for i := 0; i < growCollisions; i++ {
+		Collision(gen)
+	}
+	code, _ = gen.Generate(ctx, "https://example.com", 0)
+	assert.Len(t, code, defaultRandomLength+1)
+	assert.Equal(t, int64(defaultRandomLength+1), codeLength.Value())
+
+	// длина ограничена сверху
+	for i := 0; i < growCollisions*maxRandomLength; i++ {
+		Collision(gen)
+	}
+	code, _ = gen.Generate(ctx, "https://example.com", 0)
+	assert.Len(t, code, maxRandomLength)
+}
+
+func BenchmarkRandom_Generate(b *testing.B) {
+	gen := Default()
+	ctx := context.Background()
+
+	for i := 0; i < b.N; i++ {
+		_, err := gen.Generate(ctx, "https://example.com", 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package codegen
+
+import (
+	"context"
+	"strings"
+)
+
+// Sequential - последовательные ссылки: номер из счетчика хранилища
+// в системе счисления по алфавиту (по умолчанию base62).
+type Sequential struct {
+	counter   Counter
+	alphabet  string
+	minLength int
+}
+
+// NewSequential - конструктор стратегии sequential.
+// Ссылки короче minLength дополняются слева нулевым символом алфавита.
+func NewSequential(counter Counter, alphabet string, minLength int) (*Sequential, error) {
+	if err := validateAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+
+	return &Sequential{
+		counter:   counter,
+		alphabet:  alphabet,
+		minLength: minLength,
+	}, nil
+}
+
+// Generate возвращает ссылку для следующего номера счетчика.
+// Каждая попытка берет новый номер, поэтому attempt не используется.
+func (g *Sequential) Generate(ctx context.Context, url string, attempt int) (string, error) {
+	if url == "" {
+		return "", ErrEmptyURL
+	}
+
+	id, err := g.counter.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+	generated()
+
+	code := encodeNumber(id, g.alphabet)
+	if len(code) < g.minLength {
+		code = strings.Repeat(g.alphabet[:1], g.minLength-len(code)) + code
+	}
+
+	return code, nil
+}
+
+// encodeNumber записывает число в системе счисления по алфавиту.
+func encodeNumber(n uint64, alphabet string) string {
+	base := uint64(len(alphabet))
+	if n == 0 {
+		return alphabet[:1]
+	}
+
+	var b []byte
+	for n > 0 {
+		b = append(b, alphabet[n%base])
+		n /= base
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b)
+}
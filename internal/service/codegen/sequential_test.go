@@ -0,0 +1,44 @@
+package codegen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequential_Generate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("base62", func(t *testing.T) {
+		c := &counter{}
+		c.id.Store(59)
+		gen, err := NewSequential(c, Base62, 0)
+		require.NoError(t, err)
+
+		var codes []string
+		for i := 0; i < 3; i++ {
+			code, err := gen.Generate(ctx, "https://example.com", 0)
+			require.NoError(t, err)
+			codes = append(codes, code)
+		}
+		assert.Equal(t, []string{"Y", "Z", "10"}, codes)
+	})
+
+	t.Run("min_length", func(t *testing.T) {
+		gen, err := NewSequential(&counter{}, Base62, 4)
+		require.NoError(t, err)
+
+		code, err := gen.Generate(ctx, "https://example.com", 0)
+		require.NoError(t, err)
+		assert.Equal(t, "0001", code)
+	})
+}
+
+func TestEncodeNumber(t *testing.T) {
+	assert.Equal(t, "0", encodeNumber(0, Base62))
+	assert.Equal(t, "Z", encodeNumber(61, Base62))
+	assert.Equal(t, "100", encodeNumber(62*62, Base62))
+	assert.Equal(t, "101", encodeNumber(5, "01"))
+}
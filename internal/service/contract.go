@@ -1,17 +1,83 @@
 package service
 
-import "github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+import (
+	"context"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
 
 // Storage - интерфейс хранилища.
 //
-//go:generate mockgen -source=./contract.go -destination=../mocks/mock_storage.go -package=mocks
+// Все операции, кроме Close, принимают контекст запроса: при его отмене
+// или истечении дедлайна хранилище должно прервать операцию.
+//
+// SaveURL атомарно сохраняет ссылку или находит уже сохраненную: для
+// известного originalURL возвращается его короткая ссылка и
+// errorscustom.ErrConflict, иначе - сохраненная shortURL.
+//
+// SaveURL и SaveSlice сохраняют срок жизни ссылки, ограничение переходов
+// и хеш пароля, GetURL возвращает
+// запись целиком, в том числе истекшую: срок проверяет сервис.
+// PurgeExpired удаляет не больше limit ссылок, истекших до before,
+// и возвращает число удаленных. PurgeDeleted так же окончательно удаляет
+// не больше limit ссылок, помеченных удаленными, вместе с их переходами.
+//
+// UseClick атомарно списывает переход по ссылке с ограничением и
+// возвращает оставшееся число переходов; когда переходов не осталось,
+// возвращается errorscustom.ErrExhaustedURL. Для ссылки без ограничения
+// ничего не списывается и возвращается -1. Конкурентные вызовы не
+// должны списать больше переходов, чем было разрешено.
+//
+// SaveClicks сохраняет пакет переходов, переходы по несуществующим
+// ссылкам пропускаются. ClickStats возвращает Total, Unique и непустые
+// интервалы длины bucket начиная с since; переходы удаляются вместе
+// со ссылкой.
+//
+// DeleteURLsBatch помечает удаленными ссылки нескольких пользователей
+// разом: как и в DeletedURLs, удаляется только ссылка своего владельца,
+// чужие и несуществующие пропускаются. Возвращает ссылки, которые
+// принадлежат своим владельцам, включая удаленные раньше.
+//
+// SaveDeletionJob надежно сохраняет задание на удаление и возвращает его
+// ID, ID растут в порядке сохранения. GetDeletionJob возвращает задание
+// или errorscustom.ErrNotFound. DueDeletionJobs возвращает не больше limit
+// невыполненных заданий с ID больше afterID и NextAttempt не позже before
+// в порядке ID. ClaimDeletionJobs атомарно берет в работу не больше limit
+// таких же заданий с NextAttempt не позже before: переносит их NextAttempt
+// на until и возвращает в порядке ID, чтобы до until их не взял другой
+// экземпляр сервиса. UpdateDeletionJobs сохраняет Status, Attempts, NextAttempt,
+// LastError, Deleted, Skipped и FinishedAt заданий, PurgeDeletionJobs
+// удаляет задания, выполненные или отложенные раньше before.
+//
+// GetStats возвращает число неудаленных ссылок и число пользователей,
+// у которых такие ссылки есть.
+//
+// SaveSlice сохраняет пакет целиком или не сохраняет вовсе. Элемент с
+// CustomAlias сохраняется под этой ссылкой; если она занята, пакет
+// отменяется ошибкой errorscustom.AliasError с ErrAliasTaken.
+//
+//go:generate mockgen -source=./contract.go -destination=../mocks/storage_mock.go -package=mocks
 type Storage interface {
-	SaveURL(shortURL, originalURL, userID string) error
-	SaveSlice(urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error)
-	GetURL(string) (string, error)
+	SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error)
+	SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error)
+	GetURL(ctx context.Context, shortURL string) (*models.Storage, error)
 	Close() error
-	Ping() error
-	CheckURL(string) (string, error)
-	GetAllURL(userID, baseURL string) ([]*models.UserURLs, error)
-	DeletedURLs(urls []string, userID string) error
+	Ping(ctx context.Context) error
+	CheckURL(ctx context.Context, originalURL string) (string, error)
+	GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error)
+	DeletedURLs(ctx context.Context, urls []string, userID string) error
+	DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error)
+	SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error)
+	GetDeletionJob(ctx context.Context, id int64) (*models.DeletionJob, error)
+	DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error)
+	ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error)
+	UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error
+	PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error)
+	PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error)
+	PurgeDeleted(ctx context.Context, limit int) (int, error)
+	UseClick(ctx context.Context, shortURL string) (int, error)
+	SaveClicks(ctx context.Context, clicks []models.Click) error
+	ClickStats(ctx context.Context, shortURL string, since time.Time, bucket time.Duration) (*models.LinkStats, error)
+	GetStats(ctx context.Context) (*models.InternalStats, error)
 }
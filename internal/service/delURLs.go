@@ -1,6 +1,24 @@
 package service
 
+import (
+	"context"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
 // DeletedURLs - удаление URL из хранилища
-func (s *Service) DeletedURLs(url []string, userID string) error {
-	return s.storage.DeletedURLs(url, userID)
+func (s *Service) DeletedURLs(ctx context.Context, url []string, userID string) error {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.DeletedURLs(ctx, url, userID)
+}
+
+// DeleteURLsBatch - удаление пакета URL нескольких пользователей из хранилища.
+// Возвращает URL, которые принадлежат своим владельцам.
+func (s *Service) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.DeleteURLsBatch(ctx, urls)
 }
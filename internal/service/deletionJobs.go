@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// SaveDeletionJob - сохранение задания на удаление URL.
+func (s *Service) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.SaveDeletionJob(ctx, job)
+}
+
+// DeletionJob - получение задания на удаление пользователя.
+// Чужое задание не отличается от несуществующего: errorscustom.ErrNotFound.
+func (s *Service) DeletionJob(ctx context.Context, userID string, id int64) (*models.DeletionJob, error) {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	job, err := s.storage.GetDeletionJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" || job.UserID != userID {
+		return nil, errors2.ErrNotFound
+	}
+
+	return job, nil
+}
+
+// DueDeletionJobs - получение заданий на удаление, которые пора выполнить.
+func (s *Service) DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error) {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	return s.storage.DueDeletionJobs(ctx, before, afterID, limit)
+}
+
+// ClaimDeletionJobs - взятие в работу заданий на удаление, которые пора
+// выполнить, до момента until.
+func (s *Service) ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error) {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.ClaimDeletionJobs(ctx, before, until, limit)
+}
+
+// UpdateDeletionJobs - сохранение состояния заданий на удаление.
+func (s *Service) UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.UpdateDeletionJobs(ctx, jobs)
+}
+
+// PurgeDeletionJobs - удаление заданий, завершенных раньше before.
+func (s *Service) PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.PurgeDeletionJobs(ctx, before)
+}
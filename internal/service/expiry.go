@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+)
+
+// expiresAt возвращает момент истечения новой ссылки пользователя: запрошенный,
+// иначе по сроку по умолчанию пользователя или сервиса. Срок жизни не
+// превышает maxTTL, ссылки без срока при заданном maxTTL получают maxTTL.
+func (s *Service) expiresAt(userID string, requested *time.Time) (*time.Time, error) {
+	now := s.now()
+	if requested != nil {
+		if !requested.After(now) {
+			return nil, fmt.Errorf("%w: expires_at is in the past", errors2.ErrInvalidExpiry)
+		}
+		if s.maxTTL > 0 && requested.Sub(now) > s.maxTTL {
+			return nil, fmt.Errorf("%w: TTL exceeds maximum %s", errors2.ErrInvalidExpiry, s.maxTTL)
+		}
+		return requested, nil
+	}
+
+	ttl := s.defaultTTL
+	if userTTL, ok := s.userTTL[userID]; ok {
+		ttl = userTTL
+	}
+	if s.maxTTL > 0 && (ttl <= 0 || ttl > s.maxTTL) {
+		ttl = s.maxTTL
+	}
+	if ttl <= 0 {
+		return nil, nil
+	}
+
+	expiresAt := now.Add(ttl)
+	return &expiresAt, nil
+}
+
+// validateMaxClicks проверяет ограничение числа переходов: 0 - без ограничения.
+func validateMaxClicks(maxClicks int) error {
+	if maxClicks < 0 {
+		return fmt.Errorf("%w: max_clicks must not be negative", errors2.ErrInvalidMaxClicks)
+	}
+	return nil
+}
+
+// PurgeExpired удаляет из хранилища не больше limit истекших ссылок
+// и возвращает число удаленных.
+func (s *Service) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.PurgeExpired(ctx, s.now(), limit)
+}
+
+// PurgeDeleted окончательно удаляет из хранилища не больше limit ссылок,
+// помеченных удаленными, и возвращает число удаленных.
+func (s *Service) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.PurgeDeleted(ctx, limit)
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_expiresAt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		userID    string
+		requested *time.Time
+		expected  *time.Time
+		wantErr   error
+	}{
+		{name: "no_ttl"},
+		{
+			name:     "default_ttl",
+			opts:     []Option{WithDefaultTTL(time.Hour)},
+			expected: at(time.Hour),
+		},
+		{
+			name:     "user_ttl",
+			opts:     []Option{WithDefaultTTL(time.Hour), WithUserTTL(map[string]time.Duration{"user": time.Minute})},
+			userID:   "user",
+			expected: at(time.Minute),
+		},
+		{
+			name:     "other_user_default_ttl",
+			opts:     []Option{WithDefaultTTL(time.Hour), WithUserTTL(map[string]time.Duration{"user": time.Minute})},
+			userID:   "other",
+			expected: at(time.Hour),
+		},
+		{
+			name:     "max_ttl_without_default",
+			opts:     []Option{WithMaxTTL(24 * time.Hour)},
+			expected: at(24 * time.Hour),
+		},
+		{
+			name:     "default_ttl_clamped",
+			opts:     []Option{WithDefaultTTL(48 * time.Hour), WithMaxTTL(24 * time.Hour)},
+			expected: at(24 * time.Hour),
+		},
+		{
+			name:      "requested",
+			opts:      []Option{WithDefaultTTL(time.Hour)},
+			requested: at(time.Minute),
+			expected:  at(time.Minute),
+		},
+		{
+			name:      "requested_in_past",
+			requested: at(-time.Minute),
+			wantErr:   errors2.ErrInvalidExpiry,
+		},
+		{
+			name:      "requested_over_max",
+			opts:      []Option{WithMaxTTL(time.Hour)},
+			requested: at(2 * time.Hour),
+			wantErr:   errors2.ErrInvalidExpiry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewService(nil, logger.NewLogger(), tt.opts...)
+			s.now = func() time.Time { return now }
+
+			expiresAt, err := s.expiresAt(tt.userID, tt.requested)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expiresAt)
+		})
+	}
+}
+
+func TestService_GetURL_Expired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	s := NewService(storage, logger.NewLogger())
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	expiresAt := now.Add(time.Minute)
+
+	storage.EXPECT().GetURL(gomock.Any(), "short").
+		Return(&models.Storage{OriginalURL: "https://example.com", ExpiresAt: &expiresAt}, nil).Times(2)
+
+	url, err := s.GetURL(context.Background(), "short")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+
+	// ссылка истекает ровно в момент expires_at
+	now = expiresAt
+	_, err = s.GetURL(context.Background(), "short")
+	assert.ErrorIs(t, err, errors2.ErrExpiredURL)
+}
+
+func TestService_SaveURL_Expiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	s := NewService(storage, logger.NewLogger(), WithDefaultTTL(time.Hour))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	expiresAt := now.Add(time.Hour)
+
+	t.Run("default_ttl", func(t *testing.T) {
+		storage.EXPECT().SaveURL(gomock.Any(), gomock.Any(), "https://example.com", "user",
+			models.LinkOptions{ExpiresAt: &expiresAt}).Return("short", nil)
+
+		_, err := s.SaveURL(context.Background(), "https://example.com", "user", models.LinkOptions{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		past := now.Add(-time.Hour)
+		_, err := s.SaveURL(context.Background(), "https://example.com", "user", models.LinkOptions{ExpiresAt: &past})
+		assert.ErrorIs(t, err, errors2.ErrInvalidExpiry)
+
+		_, err = s.SaveSliceOfDB(context.Background(), []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://example.com", ExpiresAt: &past},
+		}, "http://localhost", "user")
+		assert.ErrorIs(t, err, errors2.ErrInvalidExpiry)
+	})
+}
+
+func TestService_PurgeExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	s := NewService(storage, logger.NewLogger())
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	storage.EXPECT().PurgeExpired(gomock.Any(), now, 100).Return(7, nil)
+
+	purged, err := s.PurgeExpired(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, 7, purged)
+}
+
+func TestService_PurgeDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	s := NewService(storage, logger.NewLogger())
+
+	storage.EXPECT().PurgeDeleted(gomock.Any(), 100).Return(3, nil)
+
+	purged, err := s.PurgeDeleted(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, 3, purged)
+}
@@ -1,8 +1,15 @@
 package service
 
-import "github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+import (
+	"context"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
 
 // GetAllURL возвращает все сохраненные пользователем URL-адреса
-func (s *Service) GetAllURL(userID, baseURL string) ([]*models.UserURLs, error) {
-	return s.storage.GetAllURL(userID, baseURL)
+func (s *Service) GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error) {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	return s.storage.GetAllURL(ctx, userID, baseURL)
 }
@@ -1,10 +1,52 @@
 package service
 
+import (
+	"context"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+)
+
 // GetURL возвращаем информацию по короткой ссылке и ошибку.
-func (s *Service) GetURL(shortURL string) (string, error) {
-	url, err := s.storage.GetURL(shortURL)
+// Для истекшей ссылки возвращается ErrExpiredURL, для ссылки
+// с исчерпанным числом переходов - ErrExhaustedURL, для ссылки
+// с паролем - ErrPasswordRequired.
+func (s *Service) GetURL(ctx context.Context, shortURL string) (string, error) {
+	return s.OpenURL(ctx, shortURL, "")
+}
+
+// OpenURL возвращает оригинальный URL по короткой ссылке, проверяя пароль
+// защищенной ссылки. Переход списывается только после проверки пароля.
+func (s *Service) OpenURL(ctx context.Context, shortURL, password string) (string, error) {
+	readCtx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	record, err := s.storage.GetURL(readCtx, shortURL)
 	if err != nil {
 		return "", err
 	}
-	return url, nil
+	if record.Expired(s.now()) {
+		return "", errors2.ErrExpiredURL
+	}
+	if record.Protected() {
+		if err := s.verifyPassword(shortURL, record.PasswordHash, password); err != nil {
+			return "", err
+		}
+	}
+	if record.Limited() {
+		// переход списывается в хранилище: остаток в записи мог устареть
+		if err := s.useClick(ctx, shortURL); err != nil {
+			return "", err
+		}
+	}
+
+	return record.OriginalURL, nil
+}
+
+// useClick списывает переход по ссылке с ограничением.
+func (s *Service) useClick(ctx context.Context, shortURL string) error {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	_, err := s.storage.UseClick(ctx, shortURL)
+	return err
 }
@@ -1,12 +1,15 @@
 package service
 
 import (
+	"context"
 	"os"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/filestorage"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
 	"github.com/stretchr/testify/assert"
@@ -26,31 +29,82 @@ func TestGetURL(t *testing.T) {
 	defer file.Close()
 	service := NewService(storageURL, logs)
 	t.Run("get_URL", func(t *testing.T) {
-		url, err := service.GetURL("")
+		url, err := service.GetURL(context.Background(), "")
 		assert.NotNil(t, err)
 		assert.Equal(t, "", url)
 
 	})
 
 	t.Run("get_successful_URL", func(t *testing.T) {
-		saveURL, err := service.SaveURL("http://example.com", "")
+		saveURL, err := service.SaveURL(context.Background(), "http://example.com", "", models.LinkOptions{})
 		assert.Nil(t, err)
-		url, err := service.GetURL(saveURL)
+		url, err := service.GetURL(context.Background(), saveURL)
 		assert.Nil(t, err)
 		assert.Equal(t, "http://example.com", url)
 	})
 }
 
+func TestService_GetURL_Limited(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(mapstorage.NewMapURL(), logger.NewLogger(logger.WithLevel("info")))
+
+	_, err := service.SaveURL(ctx, "http://example.com", "user", models.LinkOptions{MaxClicks: -1})
+	assert.ErrorIs(t, err, errors2.ErrInvalidMaxClicks)
+
+	shortURL, err := service.SaveURL(ctx, "http://example.com", "user", models.LinkOptions{MaxClicks: 2})
+	assert.NoError(t, err)
+
+	// каждый переход списывается, после последнего ссылка исчерпана
+	for i := 0; i < 2; i++ {
+		url, err := service.GetURL(ctx, shortURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "http://example.com", url)
+	}
+	url, err := service.GetURL(ctx, shortURL)
+	assert.ErrorIs(t, err, errors2.ErrExhaustedURL)
+	assert.Empty(t, url)
+}
+
+func TestService_GetURL_UseClick(t *testing.T) {
+	cntl := gomock.NewController(t)
+	defer cntl.Finish()
+	mockStorage := mocks.NewMockStorage(cntl)
+	service := NewService(mockStorage, logger.NewLogger(logger.WithLevel("info")))
+
+	clicks := 1
+	mockStorage.EXPECT().GetURL(gomock.Any(), "limited").
+		Return(&models.Storage{OriginalURL: "https://example.com", ClicksLeft: &clicks}, nil).Times(2)
+	gomock.InOrder(
+		mockStorage.EXPECT().UseClick(gomock.Any(), "limited").Return(0, nil),
+		// запись могла устареть: решает хранилище
+		mockStorage.EXPECT().UseClick(gomock.Any(), "limited").Return(0, errors2.ErrExhaustedURL),
+	)
+
+	url, err := service.GetURL(context.Background(), "limited")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+
+	_, err = service.GetURL(context.Background(), "limited")
+	assert.ErrorIs(t, err, errors2.ErrExhaustedURL)
+
+	// для ссылки без ограничения хранилище не списывает переходы
+	mockStorage.EXPECT().GetURL(gomock.Any(), "unlimited").
+		Return(&models.Storage{OriginalURL: "https://example.com"}, nil)
+	url, err = service.GetURL(context.Background(), "unlimited")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+}
+
 func BenchmarkService_GetURL(b *testing.B) {
 
 	cntl := gomock.NewController(b)
 	defer cntl.Finish()
 	mockStorage := mocks.NewMockStorage(cntl)
-	mockStorage.EXPECT().GetURL(gomock.Any()).Return("https://example.com", nil).AnyTimes()
+	mockStorage.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(&models.Storage{OriginalURL: "https://example.com"}, nil).AnyTimes()
 
 	service := NewService(mockStorage, logger.NewLogger(logger.WithLevel("info")))
 
 	for i := 0; i < b.N; i++ {
-		service.GetURL("")
+		service.GetURL(context.Background(), "")
 	}
 }
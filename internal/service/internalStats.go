@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// GetStats возвращает число сокращенных URL и пользователей сервиса.
+func (s *Service) GetStats(ctx context.Context) (*models.InternalStats, error) {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	return s.storage.GetStats(ctx)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ограничение неудачных попыток ввода пароля ссылки по умолчанию.
+const (
+	defaultPasswordAttempts = 5
+	defaultPasswordWindow   = 15 * time.Minute
+)
+
+// maxPasswordLength - bcrypt учитывает только первые 72 байта пароля.
+const maxPasswordLength = 72
+
+// sweepThreshold - после скольких ссылок со счетчиками limiter
+// начинает удалять счетчики с закончившимся окном.
+const sweepThreshold = 1024
+
+// hashPassword возвращает bcrypt-хеш пароля ссылки.
+func hashPassword(password string) (string, error) {
+	if len(password) > maxPasswordLength {
+		return "", fmt.Errorf("%w: password must not be longer than %d bytes", errors2.ErrInvalidPassword, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// protect заменяет пароль из opts его хешем.
+func (s *Service) protect(opts *models.LinkOptions) error {
+	if opts.Password == "" {
+		return nil
+	}
+
+	hash, err := hashPassword(opts.Password)
+	if err != nil {
+		return err
+	}
+	opts.PasswordHash = hash
+	opts.Password = ""
+	return nil
+}
+
+// verifyPassword проверяет пароль ссылки с учетом ограничения попыток.
+// Без пароля возвращается ErrPasswordRequired, попытка при этом не считается.
+func (s *Service) verifyPassword(shortURL, hash, password string) error {
+	if password == "" {
+		return errors2.ErrPasswordRequired
+	}
+
+	// попытка учитывается до проверки, чтобы одновременные запросы
+	// не превысили ограничение, и снимается, если пароль верный
+	if retryAfter, ok := s.attempts.acquire(shortURL, s.now()); !ok {
+		return &errors2.AttemptsError{RetryAfter: retryAfter}
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return errors2.ErrWrongPassword
+	}
+	if err != nil {
+		s.logger.Error("Error verify password = ", logger.ErrAttr(err))
+		return err
+	}
+
+	s.attempts.reset(shortURL)
+	return nil
+}
+
+// attemptWindow - неудачные попытки ввода пароля одной ссылки в текущем окне.
+type attemptWindow struct {
+	failures int
+	reset    time.Time
+}
+
+// attemptLimiter ограничивает неудачные попытки ввода пароля для каждой
+// ссылки: после max неудач в окне window попытки отклоняются до конца окна.
+// Счетчики хранятся в памяти процесса.
+type attemptLimiter struct {
+	max    int
+	window time.Duration
+
+	mu    sync.Mutex
+	links map[string]*attemptWindow
+}
+
+// newAttemptLimiter создает ограничитель попыток.
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		max:    max,
+		window: window,
+		links:  make(map[string]*attemptWindow),
+	}
+}
+
+// acquire учитывает попытку для ссылки. Если попытки в окне исчерпаны,
+// возвращает время до конца окна и false.
+func (l *attemptLimiter) acquire(shortURL string, now time.Time) (time.Duration, bool) {
+	if l.max <= 0 {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.links[shortURL]
+	if !ok || !now.Before(w.reset) {
+		if !ok && len(l.links) >= sweepThreshold {
+			l.sweep(now)
+		}
+		l.links[shortURL] = &attemptWindow{failures: 1, reset: now.Add(l.window)}
+		return 0, true
+	}
+	if w.failures >= l.max {
+		return w.reset.Sub(now), false
+	}
+
+	w.failures++
+	return 0, true
+}
+
+// sweep удаляет счетчики с закончившимся окном. Вызывается под блокировкой.
+func (l *attemptLimiter) sweep(now time.Time) {
+	for link, w := range l.links {
+		if !now.Before(w.reset) {
+			delete(l.links, link)
+		}
+	}
+}
+
+// reset сбрасывает счетчик ссылки после верного пароля.
+func (l *attemptLimiter) reset(shortURL string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.links, shortURL)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestService_SaveURL_Password(t *testing.T) {
+	ctx := context.Background()
+	storage := mapstorage.NewMapURL()
+	service := NewService(storage, logger.NewLogger(logger.WithLevel("info")))
+
+	shortURL, err := service.SaveCustomURL(ctx, "https://example.com", "secret", "user", models.LinkOptions{Password: "qwerty"})
+	require.NoError(t, err)
+
+	// хранится только хеш пароля
+	record, err := storage.GetURL(ctx, shortURL)
+	require.NoError(t, err)
+	assert.NotEqual(t, "qwerty", record.PasswordHash)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(record.PasswordHash), []byte("qwerty")))
+
+	_, err = service.SaveURL(ctx, "https://example.com/long", "user", models.LinkOptions{Password: strings.Repeat("a", 73)})
+	assert.ErrorIs(t, err, errors2.ErrInvalidPassword)
+}
+
+func TestService_OpenURL_Password(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	service := NewService(mapstorage.NewMapURL(), logger.NewLogger(logger.WithLevel("info")),
+		WithPasswordAttempts(2, time.Minute))
+	service.now = func() time.Time { return now }
+
+	shortURL, err := service.SaveURL(ctx, "https://example.com", "user", models.LinkOptions{Password: "qwerty", MaxClicks: 1})
+	require.NoError(t, err)
+
+	_, err = service.GetURL(ctx, shortURL)
+	assert.ErrorIs(t, err, errors2.ErrPasswordRequired)
+
+	// неверный пароль не списывает переход
+	_, err = service.OpenURL(ctx, shortURL, "wrong")
+	assert.ErrorIs(t, err, errors2.ErrWrongPassword)
+	_, err = service.OpenURL(ctx, shortURL, "wrong")
+	assert.ErrorIs(t, err, errors2.ErrWrongPassword)
+
+	// попытки исчерпаны: отклоняется даже верный пароль
+	_, err = service.OpenURL(ctx, shortURL, "qwerty")
+	var attemptsErr *errors2.AttemptsError
+	require.True(t, errors.As(err, &attemptsErr))
+	assert.ErrorIs(t, err, errors2.ErrTooManyAttempts)
+	assert.Equal(t, time.Minute, attemptsErr.RetryAfter)
+
+	// после окна попытки снова доступны
+	now = now.Add(time.Minute)
+	url, err := service.OpenURL(ctx, shortURL, "qwerty")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+
+	_, err = service.OpenURL(ctx, shortURL, "qwerty")
+	assert.ErrorIs(t, err, errors2.ErrExhaustedURL)
+}
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("concurrent", func(t *testing.T) {
+		const (
+			max     = 3
+			callers = 20
+		)
+		l := newAttemptLimiter(max, time.Minute)
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed int
+		)
+		wg.Add(callers)
+		for i := 0; i < callers; i++ {
+			go func() {
+				defer wg.Done()
+				if _, ok := l.acquire("link", now); ok {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, max, allowed)
+	})
+
+	t.Run("per_link_and_reset", func(t *testing.T) {
+		l := newAttemptLimiter(1, time.Minute)
+
+		_, ok := l.acquire("first", now)
+		assert.True(t, ok)
+		retryAfter, ok := l.acquire("first", now.Add(10*time.Second))
+		assert.False(t, ok)
+		assert.Equal(t, 50*time.Second, retryAfter)
+
+		// у другой ссылки свой счетчик
+		_, ok = l.acquire("second", now)
+		assert.True(t, ok)
+
+		l.reset("first")
+		_, ok = l.acquire("first", now)
+		assert.True(t, ok)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		l := newAttemptLimiter(0, time.Minute)
+		for i := 0; i < 10; i++ {
+			_, ok := l.acquire("link", now)
+			assert.True(t, ok)
+		}
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		l := newAttemptLimiter(1, time.Minute)
+		for i := 0; i < sweepThreshold; i++ {
+			l.acquire(strconv.Itoa(i), now)
+		}
+		require.Len(t, l.links, sweepThreshold)
+
+		// новая ссылка после окна удаляет устаревшие счетчики
+		l.acquire("fresh", now.Add(time.Minute))
+		assert.Len(t, l.links, 1)
+	})
+}
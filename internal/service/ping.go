@@ -1,6 +1,11 @@
 package service
 
-// Ping проверяет соединение с базой данных.
-func (s *Service) Ping() error {
-	return s.storage.Ping()
+import "context"
+
+// Ping проверяет соединение с базой данных.
+func (s *Service) Ping(ctx context.Context) error {
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	return s.storage.Ping(ctx)
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"github.com/golang/mock/gomock"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
@@ -15,8 +16,8 @@ func TestService_Ping(t *testing.T) {
 	storage := mocks.NewMockStorage(ctrl)
 	service := NewService(storage, logger.NewLogger(logger.WithLevel("info")))
 	t.Run("ping", func(t *testing.T) {
-		storage.EXPECT().Ping().Return(nil)
-		err := service.Ping()
+		storage.EXPECT().Ping(gomock.Any()).Return(nil)
+		err := service.Ping(context.Background())
 		if !errors.Is(err, nil) {
 			t.Errorf("ожидался статус %v, но получен %v", nil, err)
 		}
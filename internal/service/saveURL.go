@@ -1,32 +1,50 @@
 package service
 
 import (
+	"context"
+	"errors"
+
 	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
-	"github.com/kamencov/go-musthave-shortener-tpl/internal/utils"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
 )
 
-// SaveURL сохраняет URL в базе
-func (s *Service) SaveURL(url, userID string) (string, error) {
-
-	// проверяем есть ли в базе уже данный URL
-	if shortURL, err := s.storage.CheckURL(url); err != nil {
-		return shortURL, errors2.ErrConflict
+// SaveURL сохраняет URL в базе.
+// Срок жизни из opts проверяется и дополняется сроком по умолчанию,
+// ограничение числа переходов проверяется, пароль заменяется хешем.
+func (s *Service) SaveURL(ctx context.Context, url, userID string, opts models.LinkOptions) (string, error) {
+	if err := validateMaxClicks(opts.MaxClicks); err != nil {
+		return "", err
+	}
+	if err := s.protect(&opts); err != nil {
+		return "", err
 	}
 
-	// создаем короткую ссылку так как не нашли в базе
-	encodeURL, err := utils.EncodeURL(url)
-
-	if err != nil {
-		s.logger.Error("Error = ", logger.ErrAttr(err))
+	var err error
+	if opts.ExpiresAt, err = s.expiresAt(userID, opts.ExpiresAt); err != nil {
 		return "", err
 	}
 
-	err = s.storage.SaveURL(encodeURL, url, userID)
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	// создаем короткую ссылку, хранилище атомарно сохранит ее
+	// или вернет уже существующую для этого URL; занятая ссылка
+	// генерируется заново
+	var shortURL string
+	_, err = codegen.Unique(ctx, s.codes, url, func(encodeURL string) error {
+		var err error
+		shortURL, err = s.storage.SaveURL(ctx, encodeURL, url, userID, opts)
+		return err
+	})
+	if errors.Is(err, errors2.ErrConflict) && shortURL != "" {
+		return shortURL, errors2.ErrConflict
+	}
 	if err != nil {
 		s.logger.Error("Error = ", logger.ErrAttr(err))
 		return "", err
 	}
 
-	return encodeURL, nil
+	return shortURL, nil
 }
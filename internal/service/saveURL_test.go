@@ -1,15 +1,21 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/filestorage"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestService_SaveURL(t *testing.T) {
@@ -27,25 +33,89 @@ func TestService_SaveURL(t *testing.T) {
 	service := NewService(storageURL, logs)
 
 	t.Run("save_URL", func(t *testing.T) {
-		_, err := service.SaveURL("", "")
+		_, err := service.SaveURL(context.Background(), "", "", models.LinkOptions{})
 		assert.NotNil(t, err)
 		assert.Equal(t, "URL is empty", err.Error())
 
-		_, err = service.SaveURL("http://example.com", "")
+		_, err = service.SaveURL(context.Background(), "http://example.com", "", models.LinkOptions{})
 		assert.Nil(t, err)
 	})
 }
 
+func TestService_SaveURL_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	service := NewService(storage, logger.NewLogger(logger.WithLevel("info")))
+
+	t.Run("existing_url", func(t *testing.T) {
+		// конфликт определяется одной операцией хранилища, без CheckURL
+		storage.EXPECT().SaveURL(gomock.Any(), gomock.Any(), "https://example.com", "user", gomock.Any()).
+			Return("oldShort", errors2.ErrConflict)
+
+		shortURL, err := service.SaveURL(context.Background(), "https://example.com", "user", models.LinkOptions{})
+		assert.ErrorIs(t, err, errors2.ErrConflict)
+		assert.Equal(t, "oldShort", shortURL)
+	})
+
+	t.Run("short_url_collision", func(t *testing.T) {
+		// занятая короткая ссылка генерируется заново
+		var first string
+		gomock.InOrder(
+			storage.EXPECT().SaveURL(gomock.Any(), gomock.Any(), "https://example.com", "user", gomock.Any()).
+				DoAndReturn(func(_ context.Context, shortURL, _, _ string, _ models.LinkOptions) (string, error) {
+					first = shortURL
+					return "", errors2.ErrShortURLCollision
+				}),
+			storage.EXPECT().SaveURL(gomock.Any(), gomock.Any(), "https://example.com", "user", gomock.Any()).
+				DoAndReturn(func(_ context.Context, shortURL, _, _ string, _ models.LinkOptions) (string, error) {
+					assert.NotEqual(t, first, shortURL)
+					return shortURL, nil
+				}),
+		)
+
+		shortURL, err := service.SaveURL(context.Background(), "https://example.com", "user", models.LinkOptions{})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, shortURL)
+		assert.NotEqual(t, first, shortURL)
+	})
+
+	t.Run("storage_error", func(t *testing.T) {
+		storage.EXPECT().SaveURL(gomock.Any(), gomock.Any(), "https://example.com", "user", gomock.Any()).
+			Return("", errors.New("connection refused"))
+
+		shortURL, err := service.SaveURL(context.Background(), "https://example.com", "user", models.LinkOptions{})
+		assert.EqualError(t, err, "connection refused")
+		assert.Empty(t, shortURL)
+	})
+}
+
+func TestService_SaveURL_CodeGenerator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+
+	gen, err := codegen.NewHash(codegen.Letters, 7, "salt")
+	require.NoError(t, err)
+	want, err := gen.Generate(context.Background(), "https://example.com", 0)
+	require.NoError(t, err)
+
+	// ссылку создает стратегия, переданная сервису
+	service := NewService(storage, logger.NewLogger(logger.WithLevel("info")), WithCodeGenerator(gen))
+	storage.EXPECT().SaveURL(gomock.Any(), want, "https://example.com", "user", gomock.Any()).Return(want, nil)
+
+	shortURL, err := service.SaveURL(context.Background(), "https://example.com", "user", models.LinkOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, shortURL)
+}
+
 func BenchmarkService_SaveURL(b *testing.B) {
 	cntl := gomock.NewController(b)
 	defer cntl.Finish()
 	mockStorage := mocks.NewMockStorage(cntl)
-	mockStorage.EXPECT().CheckURL(gomock.Any()).Return("https://example.com", nil).AnyTimes()
-	mockStorage.EXPECT().SaveURL(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockStorage.EXPECT().SaveURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return("short", nil).AnyTimes()
 
 	service := NewService(mockStorage, logger.NewLogger(logger.WithLevel("info")))
 
 	for i := 0; i < b.N; i++ {
-		service.SaveURL("https://example.com", "")
+		service.SaveURL(context.Background(), "https://example.com", "", models.LinkOptions{})
 	}
 }
@@ -1,19 +1,129 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+)
+
+// Таймауты операций с хранилищем по умолчанию.
+const (
+	defaultReadTimeout  = 5 * time.Second
+	defaultWriteTimeout = 10 * time.Second
 )
 
 // Service - сервис.
 type Service struct {
-	storage Storage
-	logger  *logger.Logger
+	storage      Storage
+	logger       *logger.Logger
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	codes        codegen.CodeGenerator
+
+	// defaultTTL, userTTL и maxTTL - сроки жизни новых ссылок.
+	defaultTTL time.Duration
+	userTTL    map[string]time.Duration
+	maxTTL     time.Duration
+	now        func() time.Time
+
+	// attempts ограничивает неудачные попытки ввода пароля ссылок.
+	attempts *attemptLimiter
+}
+
+// Option - опция сервиса.
+type Option func(s *Service)
+
+// WithReadTimeout устанавливает таймаут операций чтения из хранилища.
+// Нулевое значение отключает таймаут.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		s.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout устанавливает таймаут операций записи в хранилище.
+// Нулевое значение отключает таймаут.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		s.writeTimeout = timeout
+	}
+}
+
+// WithCodeGenerator устанавливает стратегию генерации коротких ссылок.
+func WithCodeGenerator(gen codegen.CodeGenerator) Option {
+	return func(s *Service) {
+		s.codes = gen
+	}
+}
+
+// WithDefaultTTL устанавливает срок жизни ссылок, для которых он не задан в запросе.
+// Нулевое значение - бессрочные ссылки.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.defaultTTL = ttl
+	}
+}
+
+// WithUserTTL устанавливает сроки жизни ссылок по умолчанию для отдельных
+// пользователей, они заменяют WithDefaultTTL.
+func WithUserTTL(ttls map[string]time.Duration) Option {
+	return func(s *Service) {
+		s.userTTL = ttls
+	}
+}
+
+// WithMaxTTL устанавливает предельный срок жизни ссылок.
+// Нулевое значение снимает ограничение.
+func WithMaxTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.maxTTL = ttl
+	}
+}
+
+// WithPasswordAttempts устанавливает число неудачных попыток ввода пароля
+// ссылки за окно window, после которого попытки отклоняются до конца окна.
+// Нулевое значение max снимает ограничение.
+func WithPasswordAttempts(max int, window time.Duration) Option {
+	return func(s *Service) {
+		s.attempts = newAttemptLimiter(max, window)
+	}
 }
 
 // NewService - конструктор сервиса.
-func NewService(storage Storage, logger *logger.Logger) *Service {
-	return &Service{
-		storage: storage,
-		logger:  logger,
+func NewService(storage Storage, logger *logger.Logger, opts ...Option) *Service {
+	s := &Service{
+		storage:      storage,
+		logger:       logger,
+		readTimeout:  defaultReadTimeout,
+		writeTimeout: defaultWriteTimeout,
+		codes:        codegen.Default(),
+		now:          time.Now,
+		attempts:     newAttemptLimiter(defaultPasswordAttempts, defaultPasswordWindow),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// readContext возвращает контекст с таймаутом операции чтения.
+func (s *Service) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.readTimeout)
+}
+
+// writeContext возвращает контекст с таймаутом операции записи.
+func (s *Service) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.writeTimeout)
+}
+
+// withTimeout добавляет таймаут к контексту, если он задан.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
 }
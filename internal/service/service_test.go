@@ -1,9 +1,14 @@
 package service
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,3 +26,49 @@ func BenchmarkNewService(b *testing.B) {
 		NewService(nil, logger.NewLogger(logger.WithLevel("info")))
 	}
 }
+
+func TestService_Timeouts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := mocks.NewMockStorage(ctrl)
+	s := NewService(storage, logger.NewLogger(logger.WithLevel("info")),
+		WithReadTimeout(time.Second),
+		WithWriteTimeout(0),
+	)
+
+	t.Run("read_deadline", func(t *testing.T) {
+		storage.EXPECT().GetURL(gomock.Any(), "short").DoAndReturn(
+			func(ctx context.Context, _ string) (*models.Storage, error) {
+				deadline, ok := ctx.Deadline()
+				assert.True(t, ok)
+				assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+				return &models.Storage{OriginalURL: "https://example.com"}, nil
+			})
+
+		_, err := s.GetURL(context.Background(), "short")
+		assert.NoError(t, err)
+	})
+
+	t.Run("write_without_deadline", func(t *testing.T) {
+		storage.EXPECT().DeletedURLs(gomock.Any(), gomock.Any(), "user").DoAndReturn(
+			func(ctx context.Context, _ []string, _ string) error {
+				_, ok := ctx.Deadline()
+				assert.False(t, ok)
+				return nil
+			})
+
+		assert.NoError(t, s.DeletedURLs(context.Background(), []string{"short"}, "user"))
+	})
+
+	t.Run("canceled_request", func(t *testing.T) {
+		storage.EXPECT().Ping(gomock.Any()).DoAndReturn(
+			func(ctx context.Context) error {
+				return ctx.Err()
+			})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, s.Ping(ctx), context.Canceled)
+	})
+}
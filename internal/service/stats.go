@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// statsBuckets - допустимые интервалы статистики переходов.
+var statsBuckets = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// defaultStatsBuckets - сколько последних интервалов возвращается,
+// если начало статистики не задано.
+const defaultStatsBuckets = 30
+
+// SaveClicks сохраняет пакет переходов по коротким ссылкам.
+func (s *Service) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	ctx, cancel := s.writeContext(ctx)
+	defer cancel()
+
+	return s.storage.SaveClicks(ctx, clicks)
+}
+
+// LinkStats возвращает статистику переходов по ссылке пользователя.
+// bucket - "minute", "hour" или "day"; нулевой since - последние 30 интервалов.
+// Чужая ссылка не отличается от несуществующей: возвращается ErrNotFound.
+func (s *Service) LinkStats(ctx context.Context, userID, shortURL, bucket string, since time.Time) (*models.LinkStats, error) {
+	length, ok := statsBuckets[bucket]
+	if !ok {
+		return nil, fmt.Errorf("%w: bucket must be minute, hour or day", errors2.ErrInvalidStats)
+	}
+	if since.IsZero() {
+		since = s.now().Add(-defaultStatsBuckets * length)
+	}
+	since = models.BucketStart(since, length)
+
+	ctx, cancel := s.readContext(ctx)
+	defer cancel()
+
+	record, err := s.storage.GetURL(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" || record.UUID != userID {
+		return nil, errors2.ErrNotFound
+	}
+
+	stats, err := s.storage.ClickStats(ctx, shortURL, since, length)
+	if err != nil {
+		return nil, err
+	}
+	stats.ShortURL = shortURL
+	stats.Bucket = bucket
+	if stats.Buckets == nil {
+		stats.Buckets = []models.ClicksBucket{}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_LinkStats(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	storage := mapstorage.NewMapURL()
+	service := NewService(storage, logger.NewLogger(logger.WithLevel("info")))
+	service.now = func() time.Time { return now }
+
+	shortURL, err := service.SaveCustomURL(ctx, "https://example.com", "stats", "owner", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, service.SaveClicks(ctx, []models.Click{
+		{ShortURL: shortURL, At: now.AddDate(0, 0, -40), IPHash: "a"},
+		{ShortURL: shortURL, At: now.Add(-time.Hour), IPHash: "a"},
+		{ShortURL: shortURL, At: now, IPHash: "b"},
+	}))
+
+	t.Run("default_since", func(t *testing.T) {
+		stats, err := service.LinkStats(ctx, "owner", shortURL, "day", time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, &models.LinkStats{
+			ShortURL: shortURL,
+			Total:    3,
+			Unique:   2,
+			Bucket:   "day",
+			Buckets:  []models.ClicksBucket{{Start: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Clicks: 2}},
+		}, stats)
+	})
+
+	t.Run("since", func(t *testing.T) {
+		stats, err := service.LinkStats(ctx, "owner", shortURL, "hour", now.Add(-30*time.Minute))
+		require.NoError(t, err)
+		// начало выравнивается по интервалу
+		assert.Len(t, stats.Buckets, 2)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		stats, err := service.LinkStats(ctx, "owner", shortURL, "minute", now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.NotNil(t, stats.Buckets)
+		assert.Empty(t, stats.Buckets)
+	})
+
+	t.Run("invalid_bucket", func(t *testing.T) {
+		_, err := service.LinkStats(ctx, "owner", shortURL, "week", time.Time{})
+		assert.ErrorIs(t, err, errors2.ErrInvalidStats)
+	})
+
+	t.Run("foreign", func(t *testing.T) {
+		_, err := service.LinkStats(ctx, "stranger", shortURL, "day", time.Time{})
+		assert.ErrorIs(t, err, errors2.ErrNotFound)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		require.NoError(t, storage.DeletedURLs(ctx, []string{shortURL}, "owner"))
+		_, err := service.LinkStats(ctx, "owner", shortURL, "day", time.Time{})
+		assert.ErrorIs(t, err, errors2.ErrDeletedURL)
+	})
+}
@@ -0,0 +1,150 @@
+// Package boltstorage - хранилище во встроенной базе ключ-значение bbolt.
+//
+// Данные лежат в B+дереве в одном файле, каждая запись - транзакция
+// с fsync, поэтому хранилище переживает падение процесса без журнала
+// и компактизации. Индексы хранятся в пяти бакетах:
+//
+//	urls      - короткая ссылка -> запись models.Storage в JSON
+//	originals - оригинальный URL -> короткая ссылка
+//	users     - вложенный бакет на пользователя: порядковый номер -> короткая ссылка
+//	clicks    - вложенный бакет на ссылку: порядковый номер -> models.Click в JSON
+//	jobs      - ID задания на удаление -> models.DeletionJob в JSON
+//
+// Последовательность бакета urls хранит счетчик коротких ссылок.
+package boltstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Scheme - префикс DSN, по которому выбирается хранилище bbolt,
+// например bolt:///var/lib/shortener.bolt.
+const Scheme = "bolt://"
+
+// openTimeout - сколько ждать блокировку файла, если он открыт другим процессом.
+const openTimeout = time.Second
+
+// Имена бакетов.
+var (
+	bucketURLs      = []byte("urls")
+	bucketOriginals = []byte("originals")
+	bucketUsers     = []byte("users")
+	bucketClicks    = []byte("clicks")
+	bucketJobs      = []byte("jobs")
+)
+
+// ErrInvalidDSN - ошибка, если в DSN не указан путь к файлу базы.
+var ErrInvalidDSN = errors.New("bolt DSN must be bolt://<path>")
+
+// BoltStorage - хранилище в bbolt.
+type BoltStorage struct {
+	db *bolt.DB
+
+	// codes - стратегия генерации ссылок для SaveSlice, seq - счетчик для нее.
+	// Счетчик живет в памяти, так как генерация идет внутри транзакции записи,
+	// а в базу попадает вместе со следующей записью.
+	codes codegen.CodeGenerator
+	seq   atomic.Uint64
+}
+
+// IsDSN проверяет, что DSN указывает на bbolt.
+func IsDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, Scheme)
+}
+
+// NewBoltStorage открывает базу по DSN вида bolt://<path> и создает бакеты.
+func NewBoltStorage(dsn string) (*BoltStorage, error) {
+	path, ok := strings.CutPrefix(dsn, Scheme)
+	if !ok || path == "" {
+		return nil, ErrInvalidDSN
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BoltStorage{
+		db:    db,
+		codes: codegen.Default(),
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketURLs, bucketOriginals, bucketUsers, bucketClicks, bucketJobs} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		s.seq.Store(tx.Bucket(bucketURLs).Sequence())
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetCodeGenerator задает стратегию генерации коротких ссылок для SaveSlice.
+func (s *BoltStorage) SetCodeGenerator(gen codegen.CodeGenerator) {
+	s.codes = gen
+}
+
+// NextID возвращает следующее значение счетчика коротких ссылок.
+func (s *BoltStorage) NextID(ctx context.Context) (uint64, error) {
+	return s.seq.Add(1), nil
+}
+
+// view выполняет fn в транзакции чтения, если контекст еще не отменен.
+func (s *BoltStorage) view(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.View(fn)
+}
+
+// update выполняет fn в транзакции записи, если контекст еще не отменен.
+func (s *BoltStorage) update(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(fn)
+}
+
+// Backup пишет согласованную копию базы в w, не останавливая запись.
+// Копия снимается в транзакции чтения и сама является файлом bbolt.
+func (s *BoltStorage) Backup(w io.Writer) (int64, error) {
+	var written int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		written, err = tx.WriteTo(w)
+		return err
+	})
+
+	return written, err
+}
+
+// Path возвращает путь к файлу базы.
+func (s *BoltStorage) Path() string {
+	return s.db.Path()
+}
+
+// Ping проверяет, что база открыта.
+func (s *BoltStorage) Ping(ctx context.Context) error {
+	return s.view(ctx, func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Close закрывает базу.
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
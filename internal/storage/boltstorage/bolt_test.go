@@ -0,0 +1,121 @@
+package boltstorage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T) (*BoltStorage, string) {
+	t.Helper()
+
+	dsn := Scheme + filepath.Join(t.TempDir(), "shortener.bolt")
+	storage, err := NewBoltStorage(dsn)
+	require.NoError(t, err)
+
+	return storage, dsn
+}
+
+func TestNewBoltStorage_InvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"bolt://", "sqlite:///tmp/db"} {
+		_, err := NewBoltStorage(dsn)
+		assert.ErrorIs(t, err, ErrInvalidDSN, dsn)
+	}
+}
+
+func TestBoltStorage_Reopen(t *testing.T) {
+	ctx := context.Background()
+	storage, dsn := newTestStorage(t)
+
+	_, err := storage.SaveURL(ctx, "short1", "https://example.com/1", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = storage.SaveURL(ctx, "short2", "https://example.com/2", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, storage.DeletedURLs(ctx, []string{"short2"}, "user"))
+	require.NoError(t, storage.Close())
+
+	storage, err = NewBoltStorage(dsn)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	record, err := storage.GetURL(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", record.OriginalURL)
+
+	_, err = storage.GetURL(ctx, "short2")
+	assert.ErrorIs(t, err, errors2.ErrDeletedURL)
+}
+
+func TestBoltStorage_NextID_Reopen(t *testing.T) {
+	ctx := context.Background()
+	storage, dsn := newTestStorage(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := storage.NextID(ctx)
+		require.NoError(t, err)
+	}
+	_, err := storage.SaveURL(ctx, "short", "https://example.com", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, storage.Close())
+
+	// счетчик сохранен вместе с записью и продолжается после перезапуска
+	storage, err = NewBoltStorage(dsn)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	id, err := storage.NextID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), id)
+}
+
+func TestBoltStorage_Backup(t *testing.T) {
+	ctx := context.Background()
+	storage, _ := newTestStorage(t)
+	defer storage.Close()
+
+	_, err := storage.SaveURL(ctx, "short", "https://example.com", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	written, err := storage.Backup(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), written)
+
+	// запись после снятия копии в копию не попадает
+	_, err = storage.SaveURL(ctx, "later", "https://example.com/later", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	// копия - рабочая база bbolt
+	path := filepath.Join(t.TempDir(), "backup.bolt")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+
+	backup, err := NewBoltStorage(Scheme + path)
+	require.NoError(t, err)
+	defer backup.Close()
+
+	record, err := backup.GetURL(ctx, "short")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", record.OriginalURL)
+
+	_, err = backup.GetURL(ctx, "later")
+	assert.ErrorIs(t, err, errors2.ErrNotFound)
+}
+
+func TestBoltStorage_CanceledContext(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	defer storage.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := storage.SaveURL(ctx, "short", "https://example.com", "user", models.LinkOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, storage.Ping(ctx), context.Canceled)
+}
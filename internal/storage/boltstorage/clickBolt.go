@@ -0,0 +1,103 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// UseClick списывает переход по ссылке с ограничением и возвращает остаток.
+// Для ссылки без ограничения возвращает -1. Пишущие транзакции bbolt
+// выполняются по одной, поэтому списание атомарно.
+func (s *BoltStorage) UseClick(ctx context.Context, shortURL string) (int, error) {
+	clicks := -1
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		record, err := get(tx, shortURL)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return errors2.ErrNotFound
+		}
+		if record.DeletedFlag {
+			return errors2.ErrDeletedURL
+		}
+		if record.ClicksLeft == nil {
+			return nil
+		}
+		if *record.ClicksLeft <= 0 {
+			return errors2.ErrExhaustedURL
+		}
+
+		*record.ClicksLeft--
+		clicks = *record.ClicksLeft
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketURLs).Put([]byte(shortURL), data)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return clicks, nil
+}
+
+// SaveClicks сохраняет переходы по ссылкам одной транзакцией.
+// Переходы по несуществующим ссылкам пропускаются.
+func (s *BoltStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	return s.update(ctx, func(tx *bolt.Tx) error {
+		urls := tx.Bucket(bucketURLs)
+		for _, click := range clicks {
+			if urls.Get([]byte(click.ShortURL)) == nil {
+				continue
+			}
+			link, err := tx.Bucket(bucketClicks).CreateBucketIfNotExists([]byte(click.ShortURL))
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(click)
+			if err != nil {
+				return err
+			}
+			seq, err := link.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err = link.Put(binary.BigEndian.AppendUint64(nil, seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClickStats возвращает статистику переходов по ссылке.
+func (s *BoltStorage) ClickStats(ctx context.Context, shortURL string, since time.Time, bucket time.Duration) (*models.LinkStats, error) {
+	var clicks []models.Click
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		link := tx.Bucket(bucketClicks).Bucket([]byte(shortURL))
+		if link == nil {
+			return nil
+		}
+		return link.ForEach(func(_, data []byte) error {
+			var click models.Click
+			if err := json.Unmarshal(data, &click); err != nil {
+				return err
+			}
+			clicks = append(clicks, click)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.CountClicks(clicks, since, bucket), nil
+}
@@ -0,0 +1,76 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DeletedURLs помечает удаленными ссылки пользователя.
+// Чужие и несуществующие ссылки пропускаются.
+func (s *BoltStorage) DeletedURLs(ctx context.Context, urls []string, userID string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	return s.update(ctx, func(tx *bolt.Tx) error {
+		for _, shortURL := range urls {
+			if _, err := markDeleted(tx, shortURL, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteURLsBatch помечает удаленными ссылки нескольких пользователей
+// в одной транзакции и возвращает ссылки своих владельцев.
+// Чужие и несуществующие ссылки пропускаются.
+func (s *BoltStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	deleted := make([]models.DeletedURL, 0, len(urls))
+	if len(urls) == 0 {
+		return deleted, nil
+	}
+
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		for _, url := range urls {
+			owned, err := markDeleted(tx, url.ShortURL, url.UserID)
+			if err != nil {
+				return err
+			}
+			if owned {
+				deleted = append(deleted, url)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// markDeleted помечает удаленной ссылку владельца userID и сообщает,
+// принадлежит ли ссылка владельцу.
+func markDeleted(tx *bolt.Tx, shortURL, userID string) (bool, error) {
+	record, err := get(tx, shortURL)
+	if err != nil {
+		return false, err
+	}
+	if record == nil || record.UUID != userID {
+		return false, nil
+	}
+	if record.DeletedFlag {
+		return true, nil
+	}
+
+	record.DeletedFlag = true
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Bucket(bucketURLs).Put([]byte(shortURL), data)
+}
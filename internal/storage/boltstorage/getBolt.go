@@ -0,0 +1,76 @@
+package boltstorage
+
+import (
+	"context"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// GetURL возвращает запись по короткой ссылке.
+func (s *BoltStorage) GetURL(ctx context.Context, shortURL string) (*models.Storage, error) {
+	var result *models.Storage
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		record, err := get(tx, shortURL)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return errors2.ErrNotFound
+		}
+		if record.DeletedFlag {
+			return errors2.ErrDeletedURL
+		}
+		result = record
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetAllURL возвращает все неудаленные ссылки пользователя.
+func (s *BoltStorage) GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error) {
+	var userURLs []*models.UserURLs
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		user := tx.Bucket(bucketUsers).Bucket([]byte(userID))
+		if user == nil {
+			return nil
+		}
+
+		return user.ForEach(func(_, shortURL []byte) error {
+			record, err := get(tx, string(shortURL))
+			if err != nil || record == nil || record.DeletedFlag {
+				return err
+			}
+
+			userURLs = append(userURLs, &models.UserURLs{
+				ShortURL:    baseURL + "/" + record.ShortURL,
+				OriginalURL: record.OriginalURL,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return userURLs, nil
+}
+
+// CheckURL - проверяет есть ли в хранилище уже данный URL.
+func (s *BoltStorage) CheckURL(ctx context.Context, originalURL string) (string, error) {
+	var shortURL string
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		shortURL = string(tx.Bucket(bucketOriginals).Get([]byte(originalURL)))
+		return nil
+	})
+	if err != nil || shortURL == "" {
+		return "", err
+	}
+
+	return shortURL, errors2.ErrConflict
+}
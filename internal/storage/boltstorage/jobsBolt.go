@@ -0,0 +1,191 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// jobKey возвращает ключ задания: ID в big-endian, чтобы курсор шел в порядке ID.
+func jobKey(id int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+// SaveDeletionJob сохраняет задание на удаление и возвращает его ID.
+// ID берется из последовательности бакета jobs.
+func (s *BoltStorage) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		jobs := tx.Bucket(bucketJobs)
+		seq, err := jobs.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		job.ID = int64(seq)
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		return jobs.Put(jobKey(job.ID), data)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return job.ID, nil
+}
+
+// GetDeletionJob возвращает задание на удаление по ID.
+func (s *BoltStorage) GetDeletionJob(ctx context.Context, id int64) (*models.DeletionJob, error) {
+	var job *models.DeletionJob
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketJobs).Get(jobKey(id))
+		if data == nil {
+			return nil
+		}
+		job = &models.DeletionJob{}
+		return json.Unmarshal(data, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, errorscustom.ErrNotFound
+	}
+
+	return job, nil
+}
+
+// DueDeletionJobs возвращает задания на удаление, которые пора выполнить.
+func (s *BoltStorage) DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error) {
+	due := make([]models.DeletionJob, 0)
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketJobs).Cursor()
+		for key, data := cursor.Seek(jobKey(afterID + 1)); key != nil && len(due) < limit; key, data = cursor.Next() {
+			var job models.DeletionJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if !job.Finished() && !job.NextAttempt.After(before) {
+				due = append(due, job)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return due, nil
+}
+
+// ClaimDeletionJobs берет в работу задания, которые пора выполнить, до until
+// в одной транзакции.
+func (s *BoltStorage) ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error) {
+	claimed := make([]models.DeletionJob, 0)
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		claimed = claimed[:0]
+		bucket := tx.Bucket(bucketJobs)
+		cursor := bucket.Cursor()
+		for key, data := cursor.First(); key != nil && len(claimed) < limit; key, data = cursor.Next() {
+			var job models.DeletionJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if !job.Finished() && !job.NextAttempt.After(before) {
+				claimed = append(claimed, job)
+			}
+		}
+
+		// бакет меняется после обхода: запись под курсором сбивает его
+		for i := range claimed {
+			claimed[i].NextAttempt = until
+			data, err := json.Marshal(claimed[i])
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put(jobKey(claimed[i].ID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// UpdateDeletionJobs сохраняет состояние заданий в одной транзакции.
+func (s *BoltStorage) UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	return s.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketJobs)
+		for _, job := range jobs {
+			data := bucket.Get(jobKey(job.ID))
+			if data == nil {
+				continue
+			}
+
+			var stored models.DeletionJob
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return err
+			}
+			data, err := json.Marshal(models.UpdateJob(stored, job))
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put(jobKey(job.ID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PurgeDeletionJobs удаляет задания, выполненные или отложенные раньше before.
+// Задания не индексируются по FinishedAt, поэтому бакет просматривается целиком.
+func (s *BoltStorage) PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error) {
+	var purged int
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		jobs := tx.Bucket(bucketJobs)
+
+		var finished [][]byte
+		err := jobs.ForEach(func(key, data []byte) error {
+			var job models.DeletionJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if job.Finished() && job.FinishedAt != nil && job.FinishedAt.Before(before) {
+				finished = append(finished, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// бакет нельзя менять во время ForEach, поэтому удаляем после обхода
+		for _, key := range finished {
+			if err = jobs.Delete(key); err != nil {
+				return err
+			}
+		}
+		purged = len(finished)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,101 @@
+package boltstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// PurgeExpired удаляет не более limit ссылок, истекших до before, из всех бакетов.
+// Возвращает количество удаленных ссылок.
+func (s *BoltStorage) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	return s.purge(ctx, limit, func(record *models.Storage) bool {
+		return record.Expired(before)
+	})
+}
+
+// PurgeDeleted удаляет не более limit ссылок, помеченных удаленными, из всех бакетов.
+// Возвращает количество удаленных ссылок.
+func (s *BoltStorage) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	return s.purge(ctx, limit, func(record *models.Storage) bool {
+		return record.DeletedFlag
+	})
+}
+
+// purge удаляет из всех бакетов не более limit ссылок, подходящих под match.
+func (s *BoltStorage) purge(ctx context.Context, limit int, match func(record *models.Storage) bool) (int, error) {
+	var purged int
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		var matched []*models.Storage
+		err := tx.Bucket(bucketURLs).ForEach(func(_, data []byte) error {
+			if len(matched) >= limit {
+				return nil
+			}
+			var record models.Storage
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			if match(&record) {
+				matched = append(matched, &record)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// бакеты нельзя менять во время ForEach, поэтому удаляем после обхода
+		for _, record := range matched {
+			if err = remove(tx, record); err != nil {
+				return err
+			}
+		}
+		purged = len(matched)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// remove удаляет запись из всех бакетов.
+func remove(tx *bolt.Tx, record *models.Storage) error {
+	if err := tx.Bucket(bucketURLs).Delete([]byte(record.ShortURL)); err != nil {
+		return err
+	}
+	err := tx.Bucket(bucketClicks).DeleteBucket([]byte(record.ShortURL))
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return err
+	}
+
+	originals := tx.Bucket(bucketOriginals)
+	if string(originals.Get([]byte(record.OriginalURL))) == record.ShortURL {
+		if err := originals.Delete([]byte(record.OriginalURL)); err != nil {
+			return err
+		}
+	}
+
+	if record.UUID == "" {
+		return nil
+	}
+	user := tx.Bucket(bucketUsers).Bucket([]byte(record.UUID))
+	if user == nil {
+		return nil
+	}
+
+	cursor := user.Cursor()
+	for key, shortURL := cursor.First(); key != nil; key, shortURL = cursor.Next() {
+		if bytes.Equal(shortURL, []byte(record.ShortURL)) {
+			return cursor.Delete()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+	bolt "go.etcd.io/bbolt"
+)
+
+// SaveURL сохраняет URL в хранилище.
+// Для уже сохраненного URL возвращает его короткую ссылку и ErrConflict.
+func (s *BoltStorage) SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error) {
+	saved := shortURL
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		if existing := tx.Bucket(bucketOriginals).Get([]byte(originalURL)); existing != nil {
+			saved = string(existing)
+			return errors2.ErrConflict
+		}
+		if tx.Bucket(bucketURLs).Get([]byte(shortURL)) != nil {
+			saved = ""
+			return errors2.ErrShortURLCollision
+		}
+
+		return s.put(tx, &models.Storage{
+			UUID:         userID,
+			ShortURL:     shortURL,
+			OriginalURL:  originalURL,
+			ExpiresAt:    opts.ExpiresAt,
+			ClicksLeft:   opts.ClicksLeft(),
+			PasswordHash: opts.PasswordHash,
+		})
+	})
+	if err != nil && !errors.Is(err, errors2.ErrConflict) {
+		return "", err
+	}
+
+	return saved, err
+}
+
+// SaveSlice сохраняет множество URL в одной транзакции.
+// Для уже сохраненных URL возвращается существующая короткая ссылка.
+func (s *BoltStorage) SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	resultMultipleURL := make([]models.ResultMultipleURL, 0, len(urls))
+
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		originals := tx.Bucket(bucketOriginals)
+		for _, req := range urls {
+			// повторы внутри пакета находятся здесь же,
+			// так как транзакция видит свои записи
+			shortURL := string(originals.Get([]byte(req.OriginalURL)))
+			if shortURL == "" {
+				encodeURL, err := codegen.UniqueOrAlias(ctx, s.codes, req.OriginalURL, req.CustomAlias, func(shortURL string) error {
+					if tx.Bucket(bucketURLs).Get([]byte(shortURL)) != nil {
+						return errors2.ErrShortURLCollision
+					}
+					return s.put(tx, &models.Storage{
+						UUID:        userID,
+						ShortURL:    shortURL,
+						OriginalURL: req.OriginalURL,
+						ExpiresAt:   req.ExpiresAt,
+						ClicksLeft:  req.LinkOptions().ClicksLeft(),
+					})
+				})
+				if err != nil {
+					return err
+				}
+				shortURL = encodeURL
+			}
+
+			resultMultipleURL = append(resultMultipleURL, models.ResultMultipleURL{
+				CorrelationID: req.CorrelationID,
+				ShortURL:      baseURL + "/" + shortURL,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resultMultipleURL, nil
+}
+
+// put записывает новую запись во все бакеты и сохраняет счетчик коротких ссылок.
+func (s *BoltStorage) put(tx *bolt.Tx, record *models.Storage) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	urls := tx.Bucket(bucketURLs)
+	if seq := s.seq.Load(); seq > urls.Sequence() {
+		if err = urls.SetSequence(seq); err != nil {
+			return err
+		}
+	}
+	if err = urls.Put([]byte(record.ShortURL), data); err != nil {
+		return err
+	}
+	if err = tx.Bucket(bucketOriginals).Put([]byte(record.OriginalURL), []byte(record.ShortURL)); err != nil {
+		return err
+	}
+
+	if record.UUID == "" {
+		return nil
+	}
+
+	// ключ - порядковый номер, чтобы список пользователя шел в порядке создания
+	user, err := tx.Bucket(bucketUsers).CreateBucketIfNotExists([]byte(record.UUID))
+	if err != nil {
+		return err
+	}
+	seq, err := user.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	return user.Put(binary.BigEndian.AppendUint64(nil, seq), []byte(record.ShortURL))
+}
+
+// get читает запись по короткой ссылке. Возвращает nil, если записи нет.
+func get(tx *bolt.Tx, shortURL string) (*models.Storage, error) {
+	data := tx.Bucket(bucketURLs).Get([]byte(shortURL))
+	if data == nil {
+		return nil, nil
+	}
+
+	var record models.Storage
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
@@ -0,0 +1,29 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// GetStats возвращает число неудаленных ссылок и их владельцев.
+func (s *BoltStorage) GetStats(ctx context.Context) (*models.InternalStats, error) {
+	var counter models.StatsCounter
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketURLs).ForEach(func(_, data []byte) error {
+			var record models.Storage
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			counter.Add(&record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counter.Stats(), nil
+}
@@ -0,0 +1,355 @@
+// Package cache - кэширующая обертка над любым service.Storage.
+//
+// Кэшируются только результаты GetURL: найденные записи, пометки удаления
+// и, с отдельным коротким TTL, отсутствие ссылки. Одновременные промахи
+// по одной короткой ссылке схлопываются в один запрос к хранилищу.
+// Остальные методы проходят в хранилище без изменений и сбрасывают
+// записи кэша для затронутых коротких ссылок. Истечение ссылки проверяет
+// сервис по записи, поэтому кэш хранит записи вместе с моментом истечения.
+// Ссылки с ограничением числа переходов не кэшируются: каждый переход
+// списывается в хранилище, и кэш не должен пережить исчерпание ссылки.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"golang.org/x/sync/singleflight"
+)
+
+// Значения по умолчанию.
+const (
+	defaultSize        = 10000
+	defaultTTL         = 5 * time.Minute
+	defaultNegativeTTL = 30 * time.Second
+	defaultLoadTimeout = 5 * time.Second
+)
+
+// Cache - хранилище с кэшем чтения коротких ссылок.
+type Cache struct {
+	service.Storage
+
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	loadTimeout time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// epoch растет при каждой инвалидации: загрузка, начатая до нее,
+	// не кладет в кэш устаревший результат.
+	epoch uint64
+
+	group singleflight.Group
+	stats counters
+}
+
+// entry - запись кэша.
+type entry struct {
+	shortURL string
+	record   *models.Storage
+	err      error
+	expires  time.Time
+}
+
+// counters - счетчики статистики.
+type counters struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	loads     atomic.Int64
+	evictions atomic.Int64
+}
+
+// Stats - статистика кэша.
+type Stats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Loads     int64 `json:"loads"`
+	Evictions int64 `json:"evictions"`
+	Size      int   `json:"size"`
+}
+
+// Option - опция кэша.
+type Option func(c *Cache)
+
+// WithSize устанавливает максимальное число записей.
+func WithSize(size int) Option {
+	return func(c *Cache) {
+		c.size = size
+	}
+}
+
+// WithTTL устанавливает время жизни найденных и удаленных ссылок.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeTTL устанавливает время жизни записи об отсутствующей ссылке.
+// Нулевое значение отключает кэширование промахов.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithLoadTimeout устанавливает наибольшее время загрузки ссылки из хранилища.
+// Загрузка общая для всех ожидающих ее запросов, поэтому не зависит от
+// их контекстов и ограничена только этим временем.
+func WithLoadTimeout(timeout time.Duration) Option {
+	return func(c *Cache) {
+		if timeout > 0 {
+			c.loadTimeout = timeout
+		}
+	}
+}
+
+// New - конструктор кэша поверх storage.
+func New(storage service.Storage, opts ...Option) *Cache {
+	c := &Cache{
+		Storage:     storage,
+		size:        defaultSize,
+		ttl:         defaultTTL,
+		negativeTTL: defaultNegativeTTL,
+		loadTimeout: defaultLoadTimeout,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetURL возвращает копию записи из кэша или из хранилища.
+// Отмена ctx прерывает ожидание загрузки только этого вызова.
+func (c *Cache) GetURL(ctx context.Context, shortURL string) (*models.Storage, error) {
+	if record, err, ok := c.lookup(shortURL); ok {
+		c.stats.hits.Add(1)
+		return record, err
+	}
+	c.stats.misses.Add(1)
+
+	c.mu.Lock()
+	epoch := c.epoch
+	c.mu.Unlock()
+
+	// ключ включает epoch, чтобы после инвалидации не присоединяться
+	// к загрузке, начатой до нее
+	key := strconv.FormatUint(epoch, 10) + ":" + shortURL
+	loaded := c.group.DoChan(key, func() (any, error) {
+		c.stats.loads.Add(1)
+		// отмена запроса, начавшего загрузку, не должна стать ошибкой остальных ожидающих
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.loadTimeout)
+		defer cancel()
+
+		record, err := c.Storage.GetURL(loadCtx, shortURL)
+		c.store(epoch, shortURL, record, err)
+		return record, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-loaded:
+		// результат загрузки общий для всех ожидавших, поэтому каждому - своя копия
+		record, _ := res.Val.(*models.Storage)
+		return clone(record), res.Err
+	}
+}
+
+// SaveURL сохраняет URL и сбрасывает запись кэша о короткой ссылке.
+func (c *Cache) SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error) {
+	defer c.invalidate(shortURL)
+	return c.Storage.SaveURL(ctx, shortURL, originalURL, userID, opts)
+}
+
+// SaveSlice сохраняет пакет URL и сбрасывает записи кэша о его коротких ссылках.
+func (c *Cache) SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	result, err := c.Storage.SaveSlice(ctx, urls, baseURL, userID)
+
+	shortURLs := make([]string, 0, len(result))
+	for _, res := range result {
+		shortURLs = append(shortURLs, strings.TrimPrefix(res.ShortURL, baseURL+"/"))
+	}
+	c.invalidate(shortURLs...)
+
+	return result, err
+}
+
+// DeletedURLs удаляет ссылки и сбрасывает их записи кэша.
+func (c *Cache) DeletedURLs(ctx context.Context, urls []string, userID string) error {
+	defer c.invalidate(urls...)
+	return c.Storage.DeletedURLs(ctx, urls, userID)
+}
+
+// DeleteURLsBatch удаляет пакет ссылок и сбрасывает их записи кэша.
+func (c *Cache) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	shortURLs := make([]string, 0, len(urls))
+	for _, url := range urls {
+		shortURLs = append(shortURLs, url.ShortURL)
+	}
+
+	defer c.invalidate(shortURLs...)
+	return c.Storage.DeleteURLsBatch(ctx, urls)
+}
+
+// PurgeExpired удаляет истекшие ссылки и сбрасывает их записи кэша.
+func (c *Cache) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	purged, err := c.Storage.PurgeExpired(ctx, before, limit)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	for _, elem := range c.entries {
+		if record := elem.Value.(*entry).record; record != nil && record.Expired(before) {
+			c.remove(elem)
+		}
+	}
+
+	return purged, err
+}
+
+// PurgeDeleted удаляет ссылки, помеченные удаленными, и сбрасывает их записи кэша.
+func (c *Cache) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	purged, err := c.Storage.PurgeDeleted(ctx, limit)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	for _, elem := range c.entries {
+		if errors.Is(elem.Value.(*entry).err, errors2.ErrDeletedURL) {
+			c.remove(elem)
+		}
+	}
+
+	return purged, err
+}
+
+// Stats возвращает статистику кэша.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:      c.stats.hits.Load(),
+		Misses:    c.stats.misses.Load(),
+		Loads:     c.stats.loads.Load(),
+		Evictions: c.stats.evictions.Load(),
+		Size:      size,
+	}
+}
+
+// lookup ищет неистекшую запись и поднимает ее в начало LRU.
+func (c *Cache) lookup(shortURL string) (*models.Storage, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[shortURL]
+	if !ok {
+		return nil, nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(elem)
+		return nil, nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return clone(e.record), e.err, true
+}
+
+// store кладет результат загрузки в кэш, если с ее начала не было инвалидаций.
+// Ошибки хранилища, кроме отсутствия и удаления ссылки, не кэшируются.
+func (c *Cache) store(epoch uint64, shortURL string, record *models.Storage, err error) {
+	ttl := c.ttl
+	switch {
+	case err == nil && record.Limited():
+		return
+	case err == nil:
+	case errors.Is(err, errors2.ErrDeletedURL):
+		err = errors2.ErrDeletedURL
+	case errors.Is(err, errors2.ErrNotFound):
+		err = errors2.ErrNotFound
+		ttl = c.negativeTTL
+	default:
+		return
+	}
+
+	if ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if epoch != c.epoch {
+		return
+	}
+
+	e := &entry{
+		shortURL: shortURL,
+		record:   clone(record),
+		err:      err,
+		expires:  c.now().Add(ttl),
+	}
+
+	if elem, ok := c.entries[shortURL]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[shortURL] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.stats.evictions.Add(1)
+	}
+}
+
+// invalidate сбрасывает записи кэша о коротких ссылках.
+func (c *Cache) invalidate(shortURLs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	for _, shortURL := range shortURLs {
+		if elem, ok := c.entries[shortURL]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// remove удаляет элемент из кэша. Вызывается под блокировкой.
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).shortURL)
+}
+
+// clone возвращает копию записи, чтобы вызывающий не менял запись в кэше.
+func clone(record *models.Storage) *models.Storage {
+	if record == nil {
+		return nil
+	}
+	result := *record
+	return &result
+}
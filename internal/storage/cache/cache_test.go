@@ -0,0 +1,370 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		return New(mapstorage.NewMapURL())
+	})
+}
+
+var errConnection = errors.New("connection refused")
+
+func TestCache_GetURL(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		record  *models.Storage
+		err     error
+		wantErr error
+		calls   int
+	}{
+		{name: "found", record: &models.Storage{ShortURL: "short", OriginalURL: "https://example.com"}, calls: 1},
+		{name: "deleted", err: errors2.ErrDeletedURL, wantErr: errors2.ErrDeletedURL, calls: 1},
+		{name: "not_found", err: fmt.Errorf("%w: no rows", errors2.ErrNotFound), wantErr: errors2.ErrNotFound, calls: 1},
+		{name: "storage_error", err: errConnection, wantErr: errConnection, calls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			storage := mocks.NewMockStorage(ctrl)
+			storage.EXPECT().GetURL(gomock.Any(), "short").
+				Return(tt.record, tt.err).Times(tt.calls)
+
+			c := New(storage)
+			for i := 0; i < 3; i++ {
+				record, err := c.GetURL(ctx, "short")
+				assert.Equal(t, tt.record, record)
+				if tt.wantErr == nil {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+			}
+
+			stats := c.Stats()
+			assert.Equal(t, int64(tt.calls), stats.Misses)
+			assert.Equal(t, int64(3-tt.calls), stats.Hits)
+		})
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(storage, WithTTL(time.Minute), WithNegativeTTL(time.Second))
+	c.now = func() time.Time { return now }
+
+	storage.EXPECT().GetURL(gomock.Any(), "short").Return(&models.Storage{OriginalURL: "https://example.com"}, nil).Times(2)
+	storage.EXPECT().GetURL(gomock.Any(), "missing").Return(nil, errors2.ErrNotFound).Times(2)
+
+	for _, shortURL := range []string{"short", "missing", "short", "missing"} {
+		c.GetURL(ctx, shortURL)
+	}
+
+	// промах истекает раньше найденной ссылки
+	now = now.Add(2 * time.Second)
+	c.GetURL(ctx, "short")
+	c.GetURL(ctx, "missing")
+
+	now = now.Add(time.Minute)
+	c.GetURL(ctx, "short")
+}
+
+func TestCache_NegativeTTLDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	storage.EXPECT().GetURL(gomock.Any(), "missing").Return(nil, errors2.ErrNotFound).Times(2)
+
+	c := New(storage, WithNegativeTTL(0))
+	c.GetURL(context.Background(), "missing")
+	c.GetURL(context.Background(), "missing")
+}
+
+func TestCache_Eviction(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+
+	c := New(storage, WithSize(2))
+
+	storage.EXPECT().GetURL(gomock.Any(), "a").Return(&models.Storage{OriginalURL: "https://a.com"}, nil).Times(1)
+	storage.EXPECT().GetURL(gomock.Any(), "b").Return(&models.Storage{OriginalURL: "https://b.com"}, nil).Times(2)
+	storage.EXPECT().GetURL(gomock.Any(), "c").Return(&models.Storage{OriginalURL: "https://c.com"}, nil).Times(1)
+
+	c.GetURL(ctx, "a")
+	c.GetURL(ctx, "b")
+	c.GetURL(ctx, "a") // a становится самой свежей
+	c.GetURL(ctx, "c") // вытесняет b
+	c.GetURL(ctx, "a")
+	c.GetURL(ctx, "b")
+
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, int64(2), stats.Evictions)
+}
+
+func TestCache_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	storage := mapstorage.NewMapURL()
+	c := New(storage)
+
+	// отсутствие ссылки закэшировано, но сохранение его сбрасывает
+	_, err := c.GetURL(ctx, "short")
+	require.ErrorIs(t, err, errors2.ErrNotFound)
+	_, err = c.SaveURL(ctx, "short", "https://example.com", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	record, err := c.GetURL(ctx, "short")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", record.OriginalURL)
+
+	// удаление сбрасывает закэшированную ссылку
+	require.NoError(t, c.DeletedURLs(ctx, []string{"short"}, "user"))
+	_, err = c.GetURL(ctx, "short")
+	assert.ErrorIs(t, err, errors2.ErrDeletedURL)
+
+	// пакетное сохранение сбрасывает промахи по своим ссылкам
+	result, err := storage.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "1", OriginalURL: "https://go.dev"},
+	}, "http://localhost", "user")
+	require.NoError(t, err)
+	shortURL := result[0].ShortURL[len("http://localhost/"):]
+	require.NoError(t, storage.DeletedURLs(ctx, []string{shortURL}, "user"))
+
+	_, err = c.GetURL(ctx, shortURL)
+	require.ErrorIs(t, err, errors2.ErrDeletedURL)
+
+	_, err = c.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "1", OriginalURL: "https://go.dev"},
+	}, "http://localhost", "user")
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Stats().Size) // осталась только запись "short"
+}
+
+func TestCache_Singleflight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+
+	release := make(chan struct{})
+	storage.EXPECT().GetURL(gomock.Any(), "short").DoAndReturn(
+		func(ctx context.Context, _ string) (*models.Storage, error) {
+			<-release
+			return &models.Storage{OriginalURL: "https://example.com"}, nil
+		}).Times(1)
+
+	c := New(storage)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			record, err := c.GetURL(context.Background(), "short")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com", record.OriginalURL)
+		}()
+	}
+
+	// ждем, пока все вызовы промахнутся и встанут в ожидание загрузки
+	require.Eventually(t, func() bool {
+		return c.Stats().Misses == callers
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), c.Stats().Loads)
+}
+
+// TestCache_CancelledLoader - отмена запроса, начавшего загрузку, не
+// прерывает загрузку для остальных ожидающих.
+func TestCache_CancelledLoader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	storage.EXPECT().GetURL(gomock.Any(), "short").DoAndReturn(
+		func(ctx context.Context, _ string) (*models.Storage, error) {
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return &models.Storage{OriginalURL: "https://example.com"}, nil
+		}).Times(1)
+
+	c := New(storage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetURL(ctx, "short")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan *models.Storage, 1)
+	go func() {
+		record, err := c.GetURL(context.Background(), "short")
+		assert.NoError(t, err)
+		second <- record
+	}()
+	require.Eventually(t, func() bool {
+		return c.Stats().Misses == 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	close(release)
+
+	record := <-second
+	require.NotNil(t, record)
+	assert.Equal(t, "https://example.com", record.OriginalURL)
+}
+
+func TestCache_StaleLoad(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockStorage(ctrl)
+	c := New(storage)
+
+	// удаление завершается, пока идет загрузка: ее результат устарел
+	storage.EXPECT().GetURL(gomock.Any(), "short").DoAndReturn(
+		func(ctx context.Context, _ string) (*models.Storage, error) {
+			require.NoError(t, c.DeletedURLs(ctx, []string{"short"}, "user"))
+			return &models.Storage{OriginalURL: "https://example.com"}, nil
+		})
+	storage.EXPECT().DeletedURLs(gomock.Any(), []string{"short"}, "user").Return(nil)
+	storage.EXPECT().GetURL(gomock.Any(), "short").Return(nil, errors2.ErrDeletedURL)
+
+	record, err := c.GetURL(ctx, "short")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", record.OriginalURL)
+
+	_, err = c.GetURL(ctx, "short")
+	assert.ErrorIs(t, err, errors2.ErrDeletedURL)
+}
+
+func TestCache_PurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	storage := mapstorage.NewMapURL()
+	c := New(storage)
+
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	alive := now.Add(time.Hour)
+	_, err := c.SaveURL(ctx, "expired", "https://example.com/expired", "user", models.LinkOptions{ExpiresAt: &expired})
+	require.NoError(t, err)
+	_, err = c.SaveURL(ctx, "alive", "https://example.com/alive", "user", models.LinkOptions{ExpiresAt: &alive})
+	require.NoError(t, err)
+
+	// записи кэшируются вместе с моментом истечения
+	for _, shortURL := range []string{"expired", "alive"} {
+		record, err := c.GetURL(ctx, shortURL)
+		require.NoError(t, err)
+		require.NotNil(t, record.ExpiresAt)
+	}
+	require.Equal(t, 2, c.Stats().Size)
+
+	purged, err := c.PurgeExpired(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	// удаленная ссылка сброшена из кэша, живая осталась
+	assert.Equal(t, 1, c.Stats().Size)
+	_, err = c.GetURL(ctx, "expired")
+	assert.ErrorIs(t, err, errors2.ErrNotFound)
+}
+
+func TestCache_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	storage := mapstorage.NewMapURL()
+	c := New(storage)
+
+	for _, shortURL := range []string{"deleted", "alive"} {
+		_, err := c.SaveURL(ctx, shortURL, "https://example.com/"+shortURL, "user", models.LinkOptions{})
+		require.NoError(t, err)
+	}
+	require.NoError(t, c.DeletedURLs(ctx, []string{"deleted"}, "user"))
+
+	// удаление ссылки кэшируется вместе с живой записью
+	_, err := c.GetURL(ctx, "deleted")
+	require.ErrorIs(t, err, errors2.ErrDeletedURL)
+	_, err = c.GetURL(ctx, "alive")
+	require.NoError(t, err)
+	require.Equal(t, 2, c.Stats().Size)
+
+	purged, err := c.PurgeDeleted(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	// удаленная ссылка сброшена из кэша и больше не найдена, живая осталась
+	assert.Equal(t, 1, c.Stats().Size)
+	_, err = c.GetURL(ctx, "deleted")
+	assert.ErrorIs(t, err, errors2.ErrNotFound)
+}
+
+func TestCache_GetURL_Copy(t *testing.T) {
+	ctx := context.Background()
+	c := New(mapstorage.NewMapURL())
+
+	_, err := c.SaveURL(ctx, "short", "https://example.com", "user", models.LinkOptions{})
+	require.NoError(t, err)
+
+	// изменение полученной записи не затрагивает кэш
+	record, err := c.GetURL(ctx, "short")
+	require.NoError(t, err)
+	record.OriginalURL = "https://changed.com"
+
+	record, err = c.GetURL(ctx, "short")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", record.OriginalURL)
+}
+
+func TestCache_Limited(t *testing.T) {
+	ctx := context.Background()
+	c := New(mapstorage.NewMapURL())
+
+	_, err := c.SaveURL(ctx, "limited", "https://example.com/limited", "user", models.LinkOptions{MaxClicks: 1})
+	require.NoError(t, err)
+
+	// ссылка с ограничением не кэшируется, остаток читается из хранилища
+	record, err := c.GetURL(ctx, "limited")
+	require.NoError(t, err)
+	require.NotNil(t, record.ClicksLeft)
+	assert.Zero(t, c.Stats().Size)
+
+	_, err = c.UseClick(ctx, "limited")
+	require.NoError(t, err)
+	_, err = c.UseClick(ctx, "limited")
+	assert.ErrorIs(t, err, errors2.ErrExhaustedURL)
+
+	record, err = c.GetURL(ctx, "limited")
+	require.NoError(t, err)
+	assert.Zero(t, *record.ClicksLeft)
+	assert.Zero(t, c.Stats().Size)
+}
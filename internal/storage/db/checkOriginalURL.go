@@ -9,22 +9,26 @@ import (
 )
 
 // CheckURL - проверяет есть ли в базе уже данный URL.
-func (p *PstStorage) CheckURL(originalURL string) (string, error) {
+func (p *PstStorage) CheckURL(ctx context.Context, originalURL string) (string, error) {
 	var shortURL string
 
-	if row := p.storage.QueryRowContext(context.Background(),
+	err := p.storage.QueryRowContext(ctx,
 		"SELECT short_url FROM urls WHERE original_url = $1",
-		originalURL).Scan(&shortURL); errors.Is(row, sql.ErrNoRows) {
+		originalURL).Scan(&shortURL)
+	if errors.Is(err, sql.ErrNoRows) {
 		return "", nil
 	}
+	if err != nil {
+		return "", err
+	}
 
 	return shortURL, errors2.ErrConflict
 }
 
 // CheckUser - проверяет есть ли в базе уже данный пользователь.
-func (p *PstStorage) CheckUser(user string) error {
+func (p *PstStorage) CheckUser(ctx context.Context, user string) error {
 
-	if row := p.storage.QueryRowContext(context.Background(),
+	if row := p.storage.QueryRowContext(ctx,
 		"SELECT user_id FROM urls WHERE user_id = $1",
 		user); row.Err() != nil {
 		return row.Err()
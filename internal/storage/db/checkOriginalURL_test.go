@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
@@ -36,7 +37,7 @@ func TestPstStorage_CheckURL(t *testing.T) {
 				storage: db,
 			}
 			mock.ExpectQuery("SELECT short_url").WithArgs(tt.originalURL).WillReturnError(tt.execErr)
-			_, err = storage.CheckURL(tt.originalURL)
+			_, err = storage.CheckURL(context.Background(), tt.originalURL)
 
 			// Сравнение ошибок
 			if err != nil && tt.expectedErr != nil {
@@ -72,7 +73,7 @@ func TestPstStorage_CheckUser(t *testing.T) {
 			}
 			mock.ExpectQuery("SELECT user_id").WithArgs(tt.user).WillReturnRows(sqlmock.NewRows([]string{}))
 			mock.ExpectQuery("SELECT user_id").WithArgs(tt.user).WillReturnError(tt.execErr)
-			err = storage.CheckUser(tt.user)
+			err = storage.CheckUser(context.Background(), tt.user)
 
 			// Сравнение ошибок
 			if err != nil && tt.expectedErr != nil {
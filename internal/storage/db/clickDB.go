@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// UseClick списывает переход по ссылке с ограничением и возвращает остаток.
+// Для ссылки без ограничения возвращает -1. Остаток уменьшается одним
+// UPDATE с условием clicks_left > 0: конкурентные переходы ждут блокировку
+// строки и перепроверяют условие, поэтому остаток не уходит в минус.
+func (p *PstStorage) UseClick(ctx context.Context, shortURL string) (int, error) {
+	var clicks int
+	err := p.storage.QueryRowContext(ctx,
+		"UPDATE urls SET clicks_left = clicks_left - 1 WHERE short_url = $1 AND is_deleted = FALSE AND clicks_left > 0 RETURNING clicks_left",
+		shortURL).Scan(&clicks)
+	if err == nil {
+		return clicks, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// ничего не списано: выясняем почему
+	var (
+		deleted bool
+		left    sql.NullInt64
+	)
+	err = p.storage.QueryRowContext(ctx,
+		"SELECT is_deleted, clicks_left FROM urls WHERE short_url = $1",
+		shortURL).Scan(&deleted, &left)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, fmt.Errorf("%w: %w", errors2.ErrNotFound, err)
+	case err != nil:
+		return 0, err
+	case deleted:
+		return 0, errors2.ErrDeletedURL
+	case !left.Valid:
+		return -1, nil
+	}
+
+	return 0, errors2.ErrExhaustedURL
+}
+
+// clicksBatchSize - число строк в одном INSERT переходов. По 5 параметров
+// на строку, чтобы не превысить ограничение PostgreSQL в 65535 параметров.
+const clicksBatchSize = 1000
+
+// SaveClicks сохраняет переходы по ссылкам в одной транзакции пачками
+// по clicksBatchSize. Переходы по несуществующим ссылкам отбрасываются
+// условием EXISTS, чтобы не нарушать внешний ключ.
+func (p *PstStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	tx, err := p.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(clicks); start += clicksBatchSize {
+		end := min(start+clicksBatchSize, len(clicks))
+		if err = insertClicks(ctx, tx, clicks[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertClicks вставляет пачку переходов одним запросом.
+func insertClicks(ctx context.Context, tx *sql.Tx, clicks []models.Click) error {
+	var query strings.Builder
+	query.WriteString("WITH v (short_url, clicked_at, referrer, user_agent, ip_hash) AS (VALUES ")
+
+	args := make([]any, 0, 5*len(clicks))
+	for i, click := range clicks {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d::timestamptz, $%d, $%d, $%d)", 5*i+1, 5*i+2, 5*i+3, 5*i+4, 5*i+5)
+		args = append(args, click.ShortURL, click.At, click.Referrer, click.UserAgent, click.IPHash)
+	}
+	query.WriteString(") INSERT INTO clicks (short_url, clicked_at, referrer, user_agent, ip_hash)" +
+		" SELECT short_url, clicked_at, referrer, user_agent, ip_hash FROM v" +
+		" WHERE EXISTS (SELECT 1 FROM urls WHERE urls.short_url = v.short_url)")
+
+	_, err := tx.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// ClickStats возвращает статистику переходов по ссылке.
+// Интервалы считаются в миллисекундах от начала эпохи, как models.BucketStart.
+func (p *PstStorage) ClickStats(ctx context.Context, shortURL string, since time.Time, bucket time.Duration) (*models.LinkStats, error) {
+	var stats models.LinkStats
+	err := p.storage.QueryRowContext(ctx,
+		"SELECT count(*), count(DISTINCT NULLIF(ip_hash, '')) FROM clicks WHERE short_url = $1",
+		shortURL).Scan(&stats.Total, &stats.Unique)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.storage.QueryContext(ctx,
+		"SELECT (floor(extract(epoch FROM clicked_at) * 1000 / $2) * $2)::BIGINT AS start, count(*)"+
+			" FROM clicks WHERE short_url = $1 AND clicked_at >= $3 GROUP BY start ORDER BY start",
+		shortURL, bucket.Milliseconds(), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start int64
+		var item models.ClicksBucket
+		if err = rows.Scan(&start, &item.Clicks); err != nil {
+			return nil, err
+		}
+		item.Start = time.UnixMilli(start).UTC()
+		stats.Buckets = append(stats.Buckets, item)
+	}
+
+	return &stats, rows.Err()
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPstStorage_UseClick(t *testing.T) {
+	const (
+		updateQuery = `UPDATE urls SET clicks_left = clicks_left - 1 WHERE short_url = \$1 AND is_deleted = FALSE AND clicks_left > 0 RETURNING clicks_left`
+		selectQuery = `SELECT is_deleted, clicks_left FROM urls WHERE short_url = \$1`
+	)
+
+	tests := []struct {
+		name         string
+		mockBehavior func(mock sqlmock.Sqlmock)
+		expected     int
+		expectedErr  error
+	}{
+		{
+			name: "successful",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(updateQuery).WithArgs("qwerty").
+					WillReturnRows(sqlmock.NewRows([]string{"clicks_left"}).AddRow(4))
+			},
+			expected: 4,
+		},
+		{
+			name: "exhausted",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(updateQuery).WithArgs("qwerty").WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(selectQuery).WithArgs("qwerty").
+					WillReturnRows(sqlmock.NewRows([]string{"is_deleted", "clicks_left"}).AddRow(false, 0))
+			},
+			expectedErr: errors2.ErrExhaustedURL,
+		},
+		{
+			name: "unlimited",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(updateQuery).WithArgs("qwerty").WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(selectQuery).WithArgs("qwerty").
+					WillReturnRows(sqlmock.NewRows([]string{"is_deleted", "clicks_left"}).AddRow(false, nil))
+			},
+			expected: -1,
+		},
+		{
+			name: "deleted",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(updateQuery).WithArgs("qwerty").WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(selectQuery).WithArgs("qwerty").
+					WillReturnRows(sqlmock.NewRows([]string{"is_deleted", "clicks_left"}).AddRow(true, 3))
+			},
+			expectedErr: errors2.ErrDeletedURL,
+		},
+		{
+			name: "not_found",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(updateQuery).WithArgs("qwerty").WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(selectQuery).WithArgs("qwerty").WillReturnError(sql.ErrNoRows)
+			},
+			expectedErr: errors2.ErrNotFound,
+		},
+		{
+			name: "update_error",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(updateQuery).WithArgs("qwerty").WillReturnError(sql.ErrConnDone)
+			},
+			expectedErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tt.mockBehavior(mock)
+			storage := &PstStorage{storage: db}
+
+			clicks, err := storage.UseClick(context.Background(), "qwerty")
+			assert.Equal(t, tt.expected, clicks)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPstStorage_SaveClicks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec(`WITH v \(short_url, clicked_at, referrer, user_agent, ip_hash\) AS \(VALUES \(\$1, \$2::timestamptz, \$3, \$4, \$5\), \(\$6, \$7::timestamptz, \$8, \$9, \$10\)\) INSERT INTO clicks .* WHERE EXISTS \(SELECT 1 FROM urls WHERE urls.short_url = v.short_url\)`).
+		WithArgs("qwerty", at, "https://ref.example.com", "test", "hash", "other", at, "", "", "").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	storage := &PstStorage{storage: db}
+	err = storage.SaveClicks(context.Background(), []models.Click{
+		{ShortURL: "qwerty", At: at, Referrer: "https://ref.example.com", UserAgent: "test", IPHash: "hash"},
+		{ShortURL: "other", At: at},
+	})
+	assert.NoError(t, err)
+
+	// пустой пакет не открывает транзакцию
+	assert.NoError(t, storage.SaveClicks(context.Background(), nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPstStorage_ClickStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT count\(\*\), count\(DISTINCT NULLIF\(ip_hash, ''\)\) FROM clicks WHERE short_url = \$1`).
+		WithArgs("qwerty").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "count"}).AddRow(5, 2))
+	mock.ExpectQuery(`SELECT \(floor\(extract\(epoch FROM clicked_at\) \* 1000 / \$2\) \* \$2\)::BIGINT AS start, count\(\*\) FROM clicks WHERE short_url = \$1 AND clicked_at >= \$3 GROUP BY start ORDER BY start`).
+		WithArgs("qwerty", time.Hour.Milliseconds(), since).
+		WillReturnRows(sqlmock.NewRows([]string{"start", "count"}).
+			AddRow(since.UnixMilli(), 3).
+			AddRow(since.Add(time.Hour).UnixMilli(), 1))
+
+	storage := &PstStorage{storage: db}
+	stats, err := storage.ClickStats(context.Background(), "qwerty", since, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, &models.LinkStats{
+		Total:  5,
+		Unique: 2,
+		Buckets: []models.ClicksBucket{
+			{Start: since, Clicks: 3},
+			{Start: since.Add(time.Hour), Clicks: 1},
+		},
+	}, stats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/storagetest"
+)
+
+// TestConformance запускается на реальной базе, адрес которой задан в TEST_DATABASE_DSN.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		storage, err := NewPstStorage(dsn)
+		if err != nil {
+			t.Fatalf("ошибка подключения к базе: %v", err)
+		}
+		if _, err = storage.storage.Exec("TRUNCATE urls, clicks, deletion_jobs RESTART IDENTITY CASCADE"); err != nil {
+			t.Fatalf("ошибка очистки таблиц: %v", err)
+		}
+		t.Cleanup(func() { storage.Close() })
+		return storage
+	})
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	_ "github.com/jackc/pgx/v5/stdlib" // pgx driver
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/migrations"
 )
 
 // PsqlStorage - интерфейс хранилища для PostgreSQL.
@@ -12,18 +15,20 @@ import (
 //go:generate mockgen -source=db.go -destination=mock_db.go -package=db
 type PsqlStorage interface {
 	initDB(dataSourceName string) error
-	Ping() error
+	Ping(ctx context.Context) error
 	Close() error
 }
 
 // PstStorage - хранилище для PostgreSQL.
 type PstStorage struct {
 	storage *sql.DB
+	// codes - стратегия генерации ссылок для SaveSlice.
+	codes codegen.CodeGenerator
 }
 
 // NewPstStorage - создает новое хранилище для PostgreSQL.
 func NewPstStorage(dataSourceName string) (*PstStorage, error) {
-	p := &PstStorage{}
+	p := &PstStorage{codes: codegen.Default()}
 	err := p.initDB(dataSourceName)
 	return p, err
 }
@@ -38,55 +43,41 @@ func (p *PstStorage) initDB(dataSourceName string) error {
 	fmt.Println(dataSourceName)
 	fmt.Println(db)
 
-	err = p.CreateTableIfNotExists()
+	err = p.Migrate(context.Background())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// CreateTableIfNotExists функция для создания таблицы, если она не существует
-func (p *PstStorage) CreateTableIfNotExists() error {
-	db, err := p.storage.Begin()
+// Migrate применяет непримененные миграции схемы.
+func (p *PstStorage) Migrate(ctx context.Context) error {
+	migrator, err := migrations.NewMigrator(p.storage, migrations.Postgres)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err != nil {
-			db.Rollback()
-		} else {
-			db.Commit()
-		}
-	}()
 
-	queryCreate := `
-    CREATE TABLE IF NOT EXISTS urls (
-        id SERIAL PRIMARY KEY,
-        original_url TEXT NOT NULL,
-        short_url TEXT NOT NULL,
-        user_id UUID,
-        UNIQUE (original_url)
-    );`
-
-	_, err = db.Exec(queryCreate)
-	if err != nil {
-		return err
-	}
-
-	queryUpdate := `
-    ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_deleted BOOL NOT NULL DEFAULT FALSE;`
+	_, err = migrator.Up(ctx)
+	return err
+}
 
-	_, err = db.Exec(queryUpdate)
-	if err != nil {
-		return err
-	}
+// SetCodeGenerator задает стратегию генерации коротких ссылок для SaveSlice.
+func (p *PstStorage) SetCodeGenerator(gen codegen.CodeGenerator) {
+	p.codes = gen
+}
 
-	return nil
+// NextID возвращает следующее значение последовательности short_code_seq.
+// nextval не откатывается вместе с транзакцией, поэтому счетчик можно
+// вызывать и во время открытой транзакции записи.
+func (p *PstStorage) NextID(ctx context.Context) (uint64, error) {
+	var id uint64
+	err := p.storage.QueryRowContext(ctx, "SELECT nextval('short_code_seq')").Scan(&id)
+	return id, err
 }
 
 // Ping проверяет соединение с базой данных.
-func (p *PstStorage) Ping() error {
-	return p.storage.Ping()
+func (p *PstStorage) Ping(ctx context.Context) error {
+	return p.storage.PingContext(ctx)
 }
 
 // Close закрывает соединение с базой данных.
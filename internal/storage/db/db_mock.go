@@ -5,6 +5,7 @@
 package db
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -48,17 +49,17 @@ func (mr *MockPsqlStorageMockRecorder) Close() *gomock.Call {
 }
 
 // Ping mocks base method.
-func (m *MockPsqlStorage) Ping() error {
+func (m *MockPsqlStorage) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
+	ret := m.ctrl.Call(m, "Ping", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Ping indicates an expected call of Ping.
-func (mr *MockPsqlStorageMockRecorder) Ping() *gomock.Call {
+func (mr *MockPsqlStorageMockRecorder) Ping(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockPsqlStorage)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockPsqlStorage)(nil).Ping), ctx)
 }
 
 // initDB mocks base method.
@@ -1,11 +1,14 @@
 package db
 
 import (
-	"github.com/DATA-DOG/go-sqlmock"
+	"context"
+	"regexp"
 	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
 )
 
-func TestPstStorage_CreateTable(t *testing.T) {
+func TestPstStorage_Migrate(t *testing.T) {
 	// Создаем mock базы данных
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -13,21 +16,84 @@ func TestPstStorage_CreateTable(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Определяем поведение mock для операций с базой данных
+	// Определяем поведение mock: блокировка, таблица версий, пять миграций
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}))
 	mock.ExpectBegin()
 	mock.ExpectExec("CREATE TABLE IF NOT EXISTS urls").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(1, "create_urls").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
 	mock.ExpectExec("ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_deleted").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(2, "add_is_deleted").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE urls SET short_url = short_url \\|\\| (.+) CREATE UNIQUE INDEX IF NOT EXISTS urls_short_url_key").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(3, "unique_short_url").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE SEQUENCE IF NOT EXISTS short_code_seq").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(4, "short_code_seq").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(5, "add_expires_at").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks_left").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(6, "add_clicks_left").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE urls ADD COLUMN IF NOT EXISTS password_hash").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(7, "add_password_hash").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS clicks").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(8, "create_clicks").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS deletion_jobs").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(9, "create_deletion_jobs").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
 
 	// Создаем тестовое хранилище
 	storage := &PstStorage{storage: db}
 
-	// Вызываем метод CreateTableIfNotExists
-	err = storage.CreateTableIfNotExists()
+	// Вызываем метод Migrate
+	err = storage.Migrate(context.Background())
 	if err != nil {
-		t.Errorf("ошибка создания таблицы: %v", err)
+		t.Errorf("ошибка применения миграций: %v", err)
 	}
 
 	// Проверяем, что все ожидаемые действия были выполнены
@@ -43,3 +109,27 @@ func TestPstStorage_InitDB_BadDSN(t *testing.T) {
 		t.Errorf("ошибка инициализации базы данных: %v", err)
 	}
 }
+
+func TestPstStorage_NextID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("ошибка создания mock базы данных: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT nextval('short_code_seq')")).
+		WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(42))
+
+	storage := &PstStorage{storage: db}
+	id, err := storage.NextID(context.Background())
+	if err != nil {
+		t.Fatalf("ошибка получения значения счетчика: %v", err)
+	}
+	if id != 42 {
+		t.Errorf("ожидалось 42, получено %d", id)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("не выполнены все ожидания mock: %v", err)
+	}
+}
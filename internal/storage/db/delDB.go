@@ -1,11 +1,19 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"strings"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
+// deleteBatchSize - число ссылок в одном UPDATE пакетного удаления.
+const deleteBatchSize = 1000
+
 // DeletedURLs удаляет URL из базы данных.
-func (p *PstStorage) DeletedURLs(urls []string, userID string) error {
+func (p *PstStorage) DeletedURLs(ctx context.Context, urls []string, userID string) error {
 	if len(urls) == 0 {
 		return nil
 	}
@@ -24,10 +32,74 @@ func (p *PstStorage) DeletedURLs(urls []string, userID string) error {
 	urlsArray += "}"
 
 	// Выполняем запрос.
-	_, err := p.storage.Exec(query, userID, urlsArray)
+	_, err := p.storage.ExecContext(ctx, query, userID, urlsArray)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// DeleteURLsBatch помечает удаленными ссылки нескольких пользователей
+// в одной транзакции пачками по deleteBatchSize и возвращает ссылки
+// своих владельцев. Чужие и несуществующие ссылки пропускаются.
+func (p *PstStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	deleted := make([]models.DeletedURL, 0, len(urls))
+	if len(urls) == 0 {
+		return deleted, nil
+	}
+
+	tx, err := p.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(urls); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(urls))
+		if deleted, err = deleteURLs(ctx, tx, urls[start:end], deleted); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// deleteURLs помечает удаленными пачку ссылок одним запросом и дописывает
+// в deleted ссылки своих владельцев, включая удаленные раньше.
+func deleteURLs(ctx context.Context, tx *sql.Tx, urls, deleted []models.DeletedURL) ([]models.DeletedURL, error) {
+	var query strings.Builder
+	query.WriteString("UPDATE urls SET is_deleted = TRUE FROM (VALUES ")
+
+	args := make([]any, 0, 2*len(urls))
+	for i, url := range urls {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d::uuid)", 2*i+1, 2*i+2)
+		args = append(args, url.ShortURL, url.UserID)
+	}
+	query.WriteString(") AS d (short_url, user_id)" +
+		" WHERE urls.short_url = d.short_url AND urls.user_id = d.user_id" +
+		" RETURNING urls.short_url, urls.user_id")
+
+	rows, err := tx.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url models.DeletedURL
+		if err = rows.Scan(&url.ShortURL, &url.UserID); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, url)
+	}
+
+	return deleted, rows.Err()
+}
@@ -1,12 +1,16 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPstStorage_DeletedURLs(t *testing.T) {
@@ -60,7 +64,7 @@ func TestPstStorage_DeletedURLs(t *testing.T) {
 
 			}
 			// Вызываем тестируемую функцию
-			err = storage.DeletedURLs(tt.urls, tt.userID)
+			err = storage.DeletedURLs(context.Background(), tt.urls, tt.userID)
 
 			// Сравнение ошибок
 			if err != nil && tt.expectedErr != nil {
@@ -74,3 +78,50 @@ func TestPstStorage_DeletedURLs(t *testing.T) {
 		})
 	}
 }
+
+func TestPstStorage_DeleteURLsBatch(t *testing.T) {
+	const query = `UPDATE urls SET is_deleted = TRUE FROM \(VALUES \(\$1, \$2::uuid\), \(\$3, \$4::uuid\)\) AS d \(short_url, user_id\) WHERE urls.short_url = d.short_url AND urls.user_id = d.user_id RETURNING urls.short_url, urls.user_id`
+
+	urls := []models.DeletedURL{
+		{ShortURL: "qwerty", UserID: "user1"},
+		{ShortURL: "asdfgh", UserID: "user2"},
+	}
+
+	t.Run("successful", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(query).WithArgs("qwerty", "user1", "asdfgh", "user2").
+			WillReturnRows(sqlmock.NewRows([]string{"short_url", "user_id"}).AddRow("qwerty", "user1"))
+		mock.ExpectCommit()
+
+		storage := &PstStorage{storage: db}
+		deleted, err := storage.DeleteURLsBatch(context.Background(), urls)
+		require.NoError(t, err)
+		assert.Equal(t, urls[:1], deleted)
+
+		// пустой пакет не открывает транзакцию
+		deleted, err = storage.DeleteURLsBatch(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		errExec := errors.New("exec failed")
+		mock.ExpectBegin()
+		mock.ExpectQuery(query).WillReturnError(errExec)
+		mock.ExpectRollback()
+
+		storage := &PstStorage{storage: db}
+		_, err = storage.DeleteURLsBatch(context.Background(), urls)
+		assert.ErrorIs(t, err, errExec)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
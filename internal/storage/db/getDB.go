@@ -3,53 +3,61 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
-// GetURL возвращает оригинальную ссылку по короткой ссылке.
-func (p *PstStorage) GetURL(shortURL string) (string, error) {
-	var originalURL string
-	var deletedURL bool
-	//var storage []*models.Storage
+// GetURL возвращает запись по короткой ссылке.
+func (p *PstStorage) GetURL(ctx context.Context, shortURL string) (*models.Storage, error) {
+	var (
+		record models.Storage
+		user   sql.NullString
+	)
 	db := p.storage
 	// создаем запрос
-	query := "SELECT original_url, is_deleted FROM urls WHERE short_url = $1"
+	query := "SELECT short_url, original_url, user_id, is_deleted, expires_at, clicks_left, password_hash FROM urls WHERE short_url = $1"
 	// делаем запрос
-	row := db.QueryRowContext(context.Background(), query, shortURL)
+	row := db.QueryRowContext(ctx, query, shortURL)
 
 	if row == nil {
-		return "", sql.ErrNoRows
+		return nil, sql.ErrNoRows
 	}
 
-	if err := row.Scan(&originalURL, &deletedURL); err != nil {
-		return "", err
+	if err := row.Scan(&record.ShortURL, &record.OriginalURL, &user, &record.DeletedFlag, &record.ExpiresAt, &record.ClicksLeft, &record.PasswordHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %w", errors2.ErrNotFound, err)
+		}
+		return nil, err
 	}
 
-	if deletedURL {
-		return "", errors2.ErrDeletedURL
+	if record.DeletedFlag {
+		return nil, errors2.ErrDeletedURL
 	}
+	record.UUID = user.String
 
-	return originalURL, nil
+	return &record, nil
 }
 
-// GetAllURL возвращает все сохраненные ссылки пользователя.
-func (p *PstStorage) GetAllURL(userID, baseURL string) ([]*models.UserURLs, error) {
+// GetAllURL возвращает все неудаленные ссылки пользователя.
+func (p *PstStorage) GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error) {
 	var userURLs []*models.UserURLs
-	tx, err := p.storage.Begin()
-
+	tx, err := p.storage.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	// после Commit откат ничего не делает
+	defer tx.Rollback()
+
 	// создаем запрос
-	query := "SELECT short_url, original_url FROM urls WHERE user_id = $1"
+	query := "SELECT short_url, original_url FROM urls WHERE user_id = $1 AND is_deleted = FALSE"
 
 	// делаем запрос
-	rows, err := tx.QueryContext(context.Background(), query, userID)
+	rows, err := tx.QueryContext(ctx, query, userID)
 	if err != nil {
-		return nil, sql.ErrNoRows
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -65,11 +73,12 @@ func (p *PstStorage) GetAllURL(userID, baseURL string) ([]*models.UserURLs, erro
 	}
 
 	if err = rows.Err(); err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
 	// завершаем транзакцию
-	tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
 	return userURLs, nil
 }
@@ -1,11 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,22 +21,66 @@ func TestPstStorage_GetURL(t *testing.T) {
 	// Инициализируем PstStorage с mock-базой данных
 	pstStorage := &PstStorage{storage: db}
 
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clicksLeft := 2
+	columns := []string{"short_url", "original_url", "user_id", "is_deleted", "expires_at", "clicks_left", "password_hash"}
+
 	tests := []struct {
-		name         string
-		shortURL     string
-		expectedURL  string
-		expectedErr  error
-		mockBehavior func()
+		name           string
+		shortURL       string
+		expectedRecord *models.Storage
+		expectedErr    error
+		mockBehavior   func()
 	}{
 		{
-			name:        "successful",
-			shortURL:    "qwerty",
-			expectedURL: "http://original-url.com",
+			name:     "successful",
+			shortURL: "qwerty",
+			expectedRecord: &models.Storage{
+				UUID:        "user",
+				ShortURL:    "qwerty",
+				OriginalURL: "http://original-url.com",
+			},
+			expectedErr: nil,
+			mockBehavior: func() {
+				rows := sqlmock.NewRows(columns).
+					AddRow("qwerty", "http://original-url.com", "user", false, nil, nil, "")
+				mock.ExpectQuery("SELECT short_url, original_url, user_id, is_deleted, expires_at, clicks_left, password_hash FROM urls WHERE short_url = \\$1").
+					WithArgs("qwerty").
+					WillReturnRows(rows)
+			},
+		},
+		{
+			name:     "expiring",
+			shortURL: "qwerty",
+			expectedRecord: &models.Storage{
+				ShortURL:    "qwerty",
+				OriginalURL: "http://original-url.com",
+				ExpiresAt:   &expiresAt,
+			},
 			expectedErr: nil,
 			mockBehavior: func() {
-				rows := sqlmock.NewRows([]string{"original_url", "is_deleted"}).
-					AddRow("http://original-url.com", false)
-				mock.ExpectQuery("SELECT original_url, is_deleted FROM urls WHERE short_url = \\$1").
+				rows := sqlmock.NewRows(columns).
+					AddRow("qwerty", "http://original-url.com", nil, false, expiresAt, nil, "")
+				mock.ExpectQuery("SELECT short_url, original_url, user_id, is_deleted, expires_at, clicks_left, password_hash FROM urls WHERE short_url = \\$1").
+					WithArgs("qwerty").
+					WillReturnRows(rows)
+			},
+		},
+		{
+			name:     "limited_protected",
+			shortURL: "qwerty",
+			expectedRecord: &models.Storage{
+				UUID:         "user",
+				ShortURL:     "qwerty",
+				OriginalURL:  "http://original-url.com",
+				ClicksLeft:   &clicksLeft,
+				PasswordHash: "$2a$10$hash",
+			},
+			expectedErr: nil,
+			mockBehavior: func() {
+				rows := sqlmock.NewRows(columns).
+					AddRow("qwerty", "http://original-url.com", "user", false, nil, 2, "$2a$10$hash")
+				mock.ExpectQuery("SELECT short_url, original_url, user_id, is_deleted, expires_at, clicks_left, password_hash FROM urls WHERE short_url = \\$1").
 					WithArgs("qwerty").
 					WillReturnRows(rows)
 			},
@@ -41,12 +88,11 @@ func TestPstStorage_GetURL(t *testing.T) {
 		{
 			name:        "url deleted",
 			shortURL:    "qwerty",
-			expectedURL: "",
 			expectedErr: errors2.ErrDeletedURL,
 			mockBehavior: func() {
-				rows := sqlmock.NewRows([]string{"original_url", "is_deleted"}).
-					AddRow("http://original-url.com", true)
-				mock.ExpectQuery("SELECT original_url, is_deleted FROM urls WHERE short_url = \\$1").
+				rows := sqlmock.NewRows(columns).
+					AddRow("qwerty", "http://original-url.com", "user", true, nil, nil, "")
+				mock.ExpectQuery("SELECT short_url, original_url, user_id, is_deleted, expires_at, clicks_left, password_hash FROM urls WHERE short_url = \\$1").
 					WithArgs("qwerty").
 					WillReturnRows(rows)
 			},
@@ -54,10 +100,9 @@ func TestPstStorage_GetURL(t *testing.T) {
 		{
 			name:        "url not found",
 			shortURL:    "notfound",
-			expectedURL: "",
 			expectedErr: sql.ErrNoRows,
 			mockBehavior: func() {
-				mock.ExpectQuery("SELECT original_url, is_deleted FROM urls WHERE short_url = \\$1").
+				mock.ExpectQuery("SELECT short_url, original_url, user_id, is_deleted, expires_at, clicks_left, password_hash FROM urls WHERE short_url = \\$1").
 					WithArgs("notfound").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -68,13 +113,13 @@ func TestPstStorage_GetURL(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockBehavior()
 
-			url, err := pstStorage.GetURL(tt.shortURL)
+			record, err := pstStorage.GetURL(context.Background(), tt.shortURL)
 			if tt.expectedErr != nil {
 				require.ErrorIs(t, err, tt.expectedErr)
 			} else {
 				require.NoError(t, err)
 			}
-			require.Equal(t, tt.expectedURL, url)
+			require.Equal(t, tt.expectedRecord, record)
 		})
 	}
 
@@ -114,18 +159,33 @@ func TestPstStorage_GetAllURL(t *testing.T) {
 				mock.ExpectCommit()
 			},
 		},
+		{
+			name:        "query_error",
+			userID:      "test",
+			baseURL:     "http://localhost:8080",
+			expectedErr: sql.ErrConnDone,
+			mockBehavior: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT short_url, original_url FROM urls WHERE user_id = \\$1").
+					WithArgs("test").
+					WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+		},
 		{
 			name:        "rows_error",
 			userID:      "test",
 			baseURL:     "http://localhost:8080",
-			expectedErr: sql.ErrNoRows,
+			expectedErr: sql.ErrConnDone,
 			mockBehavior: func() {
 				mock.ExpectBegin()
+				rows := mock.NewRows([]string{"short_url", "original_url"}).
+					AddRow("qwerty", "https://ya.ru").
+					RowError(0, sql.ErrConnDone)
 				mock.ExpectQuery("SELECT short_url, original_url FROM urls WHERE user_id = \\$1").
-					WithArgs("").
-					WillReturnError(sql.ErrNoRows)
+					WithArgs("test").
+					WillReturnRows(rows)
 				mock.ExpectRollback()
-				mock.ExpectCommit()
 			},
 		},
 	}
@@ -133,12 +193,14 @@ func TestPstStorage_GetAllURL(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockBehavior()
-			_, err := pstStorage.GetAllURL(tt.userID, tt.baseURL)
+			_, err := pstStorage.GetAllURL(context.Background(), tt.userID, tt.baseURL)
 			if tt.expectedErr != nil {
 				require.ErrorIs(t, err, tt.expectedErr)
 			} else {
 				require.NoError(t, err)
 			}
+			// транзакция завершается на любом пути
+			require.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// jobsBatchSize - число заданий в одном UPDATE. По 8 параметров на задание.
+const jobsBatchSize = 1000
+
+// jobColumns - столбцы задания в порядке scanJob.
+const jobColumns = "id, user_id, short_urls, status, attempts, next_attempt_at, last_error, deleted_urls, skipped_urls, finished_at"
+
+// SaveDeletionJob сохраняет задание на удаление и возвращает его ID.
+// Ссылки задания хранятся JSON-массивами.
+func (p *PstStorage) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	urls, err := marshalURLs(job.URLs)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = p.storage.QueryRowContext(ctx,
+		"INSERT INTO deletion_jobs (user_id, short_urls, status, attempts, next_attempt_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		job.UserID, urls, job.Status, job.Attempts, job.NextAttempt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetDeletionJob возвращает задание на удаление по ID.
+func (p *PstStorage) GetDeletionJob(ctx context.Context, id int64) (*models.DeletionJob, error) {
+	row := p.storage.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM deletion_jobs WHERE id = $1", id)
+
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errorscustom.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// DueDeletionJobs возвращает задания на удаление, которые пора выполнить.
+func (p *PstStorage) DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error) {
+	return p.queryJobs(ctx,
+		"SELECT "+jobColumns+" FROM deletion_jobs"+
+			" WHERE status NOT IN ('done', 'failed') AND next_attempt_at <= $1 AND id > $2 ORDER BY id LIMIT $3",
+		before, afterID, limit)
+}
+
+// ClaimDeletionJobs берет в работу задания, которые пора выполнить, до until.
+// Строки, которые в это же время берет другой экземпляр, пропускаются
+// через FOR UPDATE SKIP LOCKED, поэтому задание достается одному из них.
+func (p *PstStorage) ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error) {
+	return p.queryJobs(ctx,
+		"WITH claimed AS (UPDATE deletion_jobs SET next_attempt_at = $2 WHERE id IN ("+
+			"SELECT id FROM deletion_jobs WHERE status NOT IN ('done', 'failed') AND next_attempt_at <= $1"+
+			" ORDER BY id LIMIT $3 FOR UPDATE SKIP LOCKED) RETURNING "+jobColumns+")"+
+			" SELECT "+jobColumns+" FROM claimed ORDER BY id",
+		before, until, limit)
+}
+
+// queryJobs выполняет запрос, который возвращает задания с jobColumns.
+func (p *PstStorage) queryJobs(ctx context.Context, query string, args ...any) ([]models.DeletionJob, error) {
+	rows, err := p.storage.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]models.DeletionJob, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// UpdateDeletionJobs сохраняет состояние заданий в одной транзакции
+// пачками по jobsBatchSize.
+func (p *PstStorage) UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	tx, err := p.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(jobs); start += jobsBatchSize {
+		end := min(start+jobsBatchSize, len(jobs))
+		if err = updateJobs(ctx, tx, jobs[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// updateJobs сохраняет состояние пачки заданий одним запросом.
+func updateJobs(ctx context.Context, tx *sql.Tx, jobs []models.DeletionJob) error {
+	var query strings.Builder
+	query.WriteString("UPDATE deletion_jobs SET status = j.status, attempts = j.attempts," +
+		" next_attempt_at = j.next_attempt_at, last_error = j.last_error, deleted_urls = j.deleted_urls," +
+		" skipped_urls = j.skipped_urls, finished_at = j.finished_at FROM (VALUES ")
+
+	args := make([]any, 0, 8*len(jobs))
+	for i, job := range jobs {
+		deleted, err := marshalURLs(job.Deleted)
+		if err != nil {
+			return err
+		}
+		skipped, err := marshalURLs(job.Skipped)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := 8 * i
+		fmt.Fprintf(&query, "($%d::bigint, $%d, $%d::integer, $%d::timestamptz, $%d, $%d, $%d, $%d::timestamptz)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+		args = append(args, job.ID, job.Status, job.Attempts, job.NextAttempt, job.LastError, deleted, skipped, job.FinishedAt)
+	}
+	query.WriteString(") AS j (id, status, attempts, next_attempt_at, last_error, deleted_urls, skipped_urls, finished_at)" +
+		" WHERE deletion_jobs.id = j.id")
+
+	_, err := tx.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// PurgeDeletionJobs удаляет задания, выполненные или отложенные раньше before.
+func (p *PstStorage) PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error) {
+	result, err := p.storage.ExecContext(ctx, "DELETE FROM deletion_jobs WHERE finished_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// scanJob читает задание из строки с jobColumns.
+func scanJob(row interface{ Scan(dest ...any) error }) (models.DeletionJob, error) {
+	var (
+		job                    models.DeletionJob
+		urls, deleted, skipped string
+		finishedAt             sql.NullTime
+	)
+	err := row.Scan(&job.ID, &job.UserID, &urls, &job.Status, &job.Attempts, &job.NextAttempt,
+		&job.LastError, &deleted, &skipped, &finishedAt)
+	if err != nil {
+		return models.DeletionJob{}, err
+	}
+
+	for _, field := range []struct {
+		data string
+		dest *[]string
+	}{{urls, &job.URLs}, {deleted, &job.Deleted}, {skipped, &job.Skipped}} {
+		if err = json.Unmarshal([]byte(field.data), field.dest); err != nil {
+			return models.DeletionJob{}, fmt.Errorf("deletion job %d: %w", job.ID, err)
+		}
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+
+	return job, nil
+}
+
+// marshalURLs кодирует ссылки JSON-массивом, пустой список - как [].
+func marshalURLs(urls []string) (string, error) {
+	if urls == nil {
+		urls = []string{}
+	}
+
+	data, err := json.Marshal(urls)
+	return string(data), err
+}
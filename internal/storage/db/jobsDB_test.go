@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var jobRows = []string{"id", "user_id", "short_urls", "status", "attempts", "next_attempt_at", "last_error", "deleted_urls", "skipped_urls", "finished_at"}
+
+func TestPstStorage_SaveDeletionJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`INSERT INTO deletion_jobs \(user_id, short_urls, status, attempts, next_attempt_at\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id`).
+		WithArgs("user", `["qwerty","asdfgh"]`, models.JobQueued, 0, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	storage := &PstStorage{storage: db}
+	id, err := storage.SaveDeletionJob(context.Background(), models.DeletionJob{
+		UserID:      "user",
+		URLs:        []string{"qwerty", "asdfgh"},
+		Status:      models.JobQueued,
+		NextAttempt: now,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPstStorage_GetDeletionJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const query = `SELECT id, user_id, short_urls, status, attempts, next_attempt_at, last_error, deleted_urls, skipped_urls, finished_at FROM deletion_jobs WHERE id = \$1`
+	mock.ExpectQuery(query).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows(jobRows).
+			AddRow(4, "user", `["qwerty","asdfgh"]`, models.JobDone, 0, now, "", `["qwerty"]`, `["asdfgh"]`, now))
+	mock.ExpectQuery(query).WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(jobRows))
+
+	storage := &PstStorage{storage: db}
+	job, err := storage.GetDeletionJob(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Equal(t, &models.DeletionJob{
+		ID:          4,
+		UserID:      "user",
+		URLs:        []string{"qwerty", "asdfgh"},
+		Status:      models.JobDone,
+		NextAttempt: now,
+		Deleted:     []string{"qwerty"},
+		Skipped:     []string{"asdfgh"},
+		FinishedAt:  &now,
+	}, job)
+
+	_, err = storage.GetDeletionJob(context.Background(), 5)
+	assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPstStorage_DueDeletionJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, user_id, short_urls, status, attempts, next_attempt_at, last_error, deleted_urls, skipped_urls, finished_at FROM deletion_jobs WHERE status NOT IN \('done', 'failed'\) AND next_attempt_at <= \$1 AND id > \$2 ORDER BY id LIMIT \$3`).
+		WithArgs(now, int64(3), 10).
+		WillReturnRows(sqlmock.NewRows(jobRows).
+			AddRow(4, "user", `["qwerty"]`, models.JobQueued, 1, now, "storage failed", "[]", "[]", nil))
+
+	storage := &PstStorage{storage: db}
+	jobs, err := storage.DueDeletionJobs(context.Background(), now, 3, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.DeletionJob{{
+		ID:          4,
+		UserID:      "user",
+		URLs:        []string{"qwerty"},
+		Status:      models.JobQueued,
+		Attempts:    1,
+		NextAttempt: now,
+		LastError:   "storage failed",
+		Deleted:     []string{},
+		Skipped:     []string{},
+	}}, jobs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPstStorage_ClaimDeletionJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := now.Add(time.Minute)
+	mock.ExpectQuery(`WITH claimed AS \(UPDATE deletion_jobs SET next_attempt_at = \$2 WHERE id IN \(SELECT id FROM deletion_jobs WHERE status NOT IN \('done', 'failed'\) AND next_attempt_at <= \$1 ORDER BY id LIMIT \$3 FOR UPDATE SKIP LOCKED\) RETURNING .+\) SELECT .+ FROM claimed ORDER BY id`).
+		WithArgs(now, until, 10).
+		WillReturnRows(sqlmock.NewRows(jobRows).
+			AddRow(4, "user", `["qwerty"]`, models.JobQueued, 0, until, "", "[]", "[]", nil))
+
+	storage := &PstStorage{storage: db}
+	jobs, err := storage.ClaimDeletionJobs(context.Background(), now, until, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, int64(4), jobs[0].ID)
+	assert.Equal(t, until, jobs[0].NextAttempt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPstStorage_UpdateAndPurgeDeletionJobs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE deletion_jobs SET status = j.status, attempts = j.attempts, next_attempt_at = j.next_attempt_at, last_error = j.last_error, deleted_urls = j.deleted_urls, skipped_urls = j.skipped_urls, finished_at = j.finished_at FROM \(VALUES \(\$1::bigint, \$2, \$3::integer, \$4::timestamptz, \$5, \$6, \$7, \$8::timestamptz\), \(\$9::bigint, \$10, \$11::integer, \$12::timestamptz, \$13, \$14, \$15, \$16::timestamptz\)\) AS j \(id, status, attempts, next_attempt_at, last_error, deleted_urls, skipped_urls, finished_at\) WHERE deletion_jobs.id = j.id`).
+		WithArgs(int64(4), models.JobFailed, 5, now, "storage failed", "[]", "[]", now,
+			int64(5), models.JobDone, 0, now, "", `["qwerty"]`, "[]", now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectExec(`DELETE FROM deletion_jobs WHERE finished_at < \$1`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	storage := &PstStorage{storage: db}
+	require.NoError(t, storage.UpdateDeletionJobs(context.Background(), []models.DeletionJob{
+		{ID: 4, Status: models.JobFailed, Attempts: 5, NextAttempt: now, LastError: "storage failed", FinishedAt: &now},
+		{ID: 5, Status: models.JobDone, NextAttempt: now, Deleted: []string{"qwerty"}, FinishedAt: &now},
+	}))
+
+	purged, err := storage.PurgeDeletionJobs(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	// пустой список не открывает транзакцию
+	require.NoError(t, storage.UpdateDeletionJobs(context.Background(), nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// PurgeExpired удаляет не более limit ссылок, истекших до before.
+// Возвращает количество удаленных ссылок.
+func (p *PstStorage) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := "DELETE FROM urls WHERE id IN" +
+		" (SELECT id FROM urls WHERE expires_at <= $1 LIMIT $2 FOR UPDATE SKIP LOCKED)"
+
+	result, err := p.storage.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// PurgeDeleted удаляет не более limit ссылок, помеченных удаленными.
+// Возвращает количество удаленных ссылок.
+func (p *PstStorage) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	query := "DELETE FROM urls WHERE id IN" +
+		" (SELECT id FROM urls WHERE is_deleted = TRUE LIMIT $1 FOR UPDATE SKIP LOCKED)"
+
+	result, err := p.storage.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPstStorage_PurgeExpired(t *testing.T) {
+	before := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		affected    int64
+		execErr     error
+		expected    int
+		expectedErr error
+	}{
+		{
+			name:     "successful",
+			affected: 3,
+			expected: 3,
+		},
+		{
+			name:        "exec_error",
+			execErr:     errors.New("some error"),
+			expectedErr: errors.New("some error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			storage := &PstStorage{storage: db}
+
+			exec := mock.ExpectExec(`DELETE FROM urls WHERE id IN \(SELECT id FROM urls WHERE expires_at <= \$1 LIMIT \$2`).
+				WithArgs(before, 100)
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			}
+
+			purged, err := storage.PurgeExpired(context.Background(), before, 100)
+			assert.Equal(t, tt.expected, purged)
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPstStorage_PurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	storage := &PstStorage{storage: db}
+
+	mock.ExpectExec(`DELETE FROM urls WHERE id IN \(SELECT id FROM urls WHERE is_deleted = TRUE LIMIT \$1 FOR UPDATE SKIP LOCKED\)`).
+		WithArgs(100).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	purged, err := storage.PurgeDeleted(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
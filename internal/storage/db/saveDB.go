@@ -2,66 +2,252 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
-	"github.com/kamencov/go-musthave-shortener-tpl/internal/utils"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
 )
 
 // SaveURL сохраняет URL в базе данных.
-func (p *PstStorage) SaveURL(shortURL, originalURL, userID string) error {
+// Для уже сохраненного URL возвращает его короткую ссылку и ErrConflict.
+//
+// Вставка и поиск существующей ссылки - один запрос: при конфликте пустое
+// обновление возвращает через RETURNING сохраненную строку, а xmax = 0
+// отличает новую строку от существующей.
+func (p *PstStorage) SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error) {
 	var user *string
 	if userID != "" {
 		user = &userID
 	}
 
-	// начинаем транзакцию
-	tx, err := p.storage.Begin()
+	query := "INSERT INTO urls (original_url, short_url, user_id, expires_at, clicks_left, password_hash) VALUES ($1, $2, $3, $4, $5, $6)" +
+		" ON CONFLICT (original_url) DO UPDATE SET original_url = EXCLUDED.original_url" +
+		" RETURNING short_url, (xmax = 0) AS inserted"
+
+	var (
+		saved    string
+		inserted bool
+	)
+	err := p.storage.QueryRowContext(ctx, query, originalURL, shortURL, user, opts.ExpiresAt, opts.ClicksLeft(), opts.PasswordHash).Scan(&saved, &inserted)
 	if err != nil {
-		return err
+		// конфликт по URL обработан ON CONFLICT, значит занята короткая ссылка
+		if isUniqueViolation(err) {
+			return "", errors2.ErrShortURLCollision
+		}
+		return "", err
 	}
-	// создаем запрос
-	query := "INSERT INTO urls (original_url, short_url, user_id) VALUES ($1, $2, $3)"
-	_, err = tx.ExecContext(context.Background(), query, originalURL, shortURL, user)
-	if err != nil {
-		// если ошибка, то откатываем изменения
-		tx.Rollback()
-		return err
+
+	if !inserted {
+		return saved, errors2.ErrConflict
 	}
 
-	// завершаем транзакцию
-	return tx.Commit()
+	return saved, nil
 }
 
-// SaveSliceOfDB сохраняет множество URL в базе данных.
-func (p *PstStorage) SaveSlice(urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
-	var resultMultipleURL []models.ResultMultipleURL
+// batchSize - число строк в одном INSERT пакета. По 5 параметров на строку
+// запрос остается далеко от предела PostgreSQL в 65535 параметров.
+const batchSize = 1000
 
-	tx, err := p.storage.Begin()
-	if err != nil {
-		return resultMultipleURL, err
+// SaveSlice сохраняет множество URL в одной транзакции: пакет сохраняется
+// целиком или не сохраняется вовсе. Строки вставляются многострочными INSERT
+// по batchSize штук, для уже сохраненных URL возвращается существующая
+// короткая ссылка, для занятых коротких ссылок генерируются новые.
+// Занятая пользовательская ссылка отменяет весь пакет.
+func (p *PstStorage) SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	var user *string
+	if userID != "" {
+		user = &userID
 	}
 
-	// создаем короткую ссылку и записываем в resultMultipleURL
+	// уникальные URL пакета в порядке первого появления
+	originals := make([]string, 0, len(urls))
+	shortURLs := make(map[string]string, len(urls))
+	opts := make(map[string]models.LinkOptions, len(urls))
+	aliases := make(map[string]bool)
 	for _, req := range urls {
-		// проверяем есть ли в базе уже данный URL
-		encodeURL, err := p.CheckURL(req.OriginalURL)
-		if err == nil {
-			encodeURL, err = utils.EncodeURL(req.OriginalURL)
-			if err != nil {
-				return resultMultipleURL, err
+		if _, ok := shortURLs[req.OriginalURL]; ok {
+			continue
+		}
+		opts[req.OriginalURL] = req.LinkOptions()
+
+		if req.CustomAlias != "" {
+			shortURLs[req.OriginalURL] = req.CustomAlias
+			aliases[req.OriginalURL] = true
+			originals = append(originals, req.OriginalURL)
+			continue
+		}
+
+		encodeURL, err := p.codes.Generate(ctx, req.OriginalURL, 0)
+		if err != nil {
+			return nil, err
+		}
+		shortURLs[req.OriginalURL] = encodeURL
+		originals = append(originals, req.OriginalURL)
+	}
+
+	if len(originals) > 0 {
+		tx, err := p.storage.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+
+		// вставляем, пока не останется URL с занятыми короткими ссылками
+		pending := originals
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt == codegen.MaxAttempts {
+				return nil, fmt.Errorf("%w: no free short URL after %d attempts", errors2.ErrShortURLCollision, attempt)
+			}
+
+			var collided []string
+			for start := 0; start < len(pending); start += batchSize {
+				end := min(start+batchSize, len(pending))
+				chunkCollided, err := saveBatch(ctx, tx, pending[start:end], shortURLs, opts, user)
+				if err != nil {
+					return nil, err
+				}
+				collided = append(collided, chunkCollided...)
+			}
+
+			for _, original := range collided {
+				// пользовательская ссылка не генерируется заново
+				if aliases[original] {
+					return nil, &errors2.AliasError{Alias: shortURLs[original], Err: errors2.ErrAliasTaken}
+				}
+				codegen.Collision(p.codes)
+				if shortURLs[original], err = p.codes.Generate(ctx, original, attempt+1); err != nil {
+					return nil, err
+				}
 			}
+			pending = collided
 		}
 
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	resultMultipleURL := make([]models.ResultMultipleURL, 0, len(urls))
+	for _, req := range urls {
 		resultMultipleURL = append(resultMultipleURL, models.ResultMultipleURL{
 			CorrelationID: req.CorrelationID,
-			ShortURL:      baseURL + "/" + encodeURL,
+			ShortURL:      baseURL + "/" + shortURLs[req.OriginalURL],
 		})
-
-		p.SaveURL(encodeURL, req.OriginalURL, userID)
-		tx.Rollback()
 	}
 
-	// завершаем транзакцию
-	tx.Commit()
 	return resultMultipleURL, nil
 }
+
+// saveBatch сохраняет пачку URL и записывает в shortURLs короткие ссылки уже
+// сохраненных URL. Возвращает URL, чьи короткие ссылки оказались заняты.
+func saveBatch(ctx context.Context, tx *sql.Tx, originals []string, shortURLs map[string]string, opts map[string]models.LinkOptions, user *string) ([]string, error) {
+	inserted, err := insertBatch(ctx, tx, originals, shortURLs, opts, user)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0, len(originals)-len(inserted))
+	for _, original := range originals {
+		if !inserted[original] {
+			missing = append(missing, original)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	existing, err := selectExisting(ctx, tx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	var collided []string
+	for _, original := range missing {
+		if shortURL, ok := existing[original]; ok {
+			shortURLs[original] = shortURL
+			continue
+		}
+		collided = append(collided, original)
+	}
+
+	return collided, nil
+}
+
+// insertBatch вставляет пачку URL одним запросом и возвращает вставленные.
+// Строки, нарушающие любую уникальность - по URL или по короткой ссылке,
+// пропускаются без ошибки, чтобы не прерывать транзакцию.
+func insertBatch(ctx context.Context, tx *sql.Tx, originals []string, shortURLs map[string]string, opts map[string]models.LinkOptions, user *string) (map[string]bool, error) {
+	var query strings.Builder
+	query.WriteString("INSERT INTO urls (original_url, short_url, user_id, expires_at, clicks_left) VALUES ")
+
+	args := make([]any, 0, 5*len(originals))
+	for i, original := range originals {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d)", 5*i+1, 5*i+2, 5*i+3, 5*i+4, 5*i+5)
+		args = append(args, original, shortURLs[original], user, opts[original].ExpiresAt, opts[original].ClicksLeft())
+	}
+	query.WriteString(" ON CONFLICT DO NOTHING RETURNING original_url")
+
+	rows, err := tx.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]bool, len(originals))
+	for rows.Next() {
+		var original string
+		if err = rows.Scan(&original); err != nil {
+			return nil, err
+		}
+		inserted[original] = true
+	}
+
+	return inserted, rows.Err()
+}
+
+// selectExisting возвращает короткие ссылки уже сохраненных URL.
+func selectExisting(ctx context.Context, tx *sql.Tx, originals []string) (map[string]string, error) {
+	args := make([]any, 0, len(originals))
+	placeholders := make([]string, 0, len(originals))
+	for i, original := range originals {
+		args = append(args, original)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT original_url, short_url FROM urls WHERE original_url IN ("+strings.Join(placeholders, ", ")+")",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]string, len(originals))
+	for rows.Next() {
+		var original, shortURL string
+		if err = rows.Scan(&original, &shortURL); err != nil {
+			return nil, err
+		}
+		existing[original] = shortURL
+	}
+
+	return existing, rows.Err()
+}
+
+// uniqueViolationCode - код ошибки PostgreSQL при нарушении уникальности.
+const uniqueViolationCode = "23505"
+
+// isUniqueViolation проверяет, что ошибка - нарушение уникальности.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
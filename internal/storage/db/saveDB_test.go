@@ -1,40 +1,50 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestPstStorage_SaveURL(t *testing.T) {
-
 	tests := []struct {
 		name        string
-		shortURL    string
-		userID      string
-		originalURL string
-		exec        bool
-		execErr     error
+		rows        *sqlmock.Rows
+		queryErr    error
+		expected    string
 		expectedErr error
 	}{
 		{
-			name:        "successful",
-			shortURL:    "shortURL",
-			userID:      "testID",
-			originalURL: "www.test.ru",
+			name:     "inserted",
+			rows:     sqlmock.NewRows([]string{"short_url", "inserted"}).AddRow("shortURL", true),
+			expected: "shortURL",
+		},
+		{
+			name:        "existing",
+			rows:        sqlmock.NewRows([]string{"short_url", "inserted"}).AddRow("oldShort", false),
+			expected:    "oldShort",
+			expectedErr: errors2.ErrConflict,
 		},
 		{
-			name:        "incorrect_exec",
-			shortURL:    "shortURL",
-			userID:      "testID",
-			originalURL: "www.test.ru",
-			exec:        true,
-			execErr:     sql.ErrTxDone,
-			expectedErr: errors.New("some error"),
+			name:        "short_url_collision",
+			queryErr:    &pgconn.PgError{Code: uniqueViolationCode},
+			expectedErr: errors2.ErrShortURLCollision,
+		},
+		{
+			name:        "query_error",
+			queryErr:    sql.ErrConnDone,
+			expectedErr: sql.ErrConnDone,
 		},
 	}
 
@@ -43,88 +53,188 @@ func TestPstStorage_SaveURL(t *testing.T) {
 			db, mock, err := sqlmock.New()
 			assert.NoError(t, err)
 
-			mock.ExpectBegin()
-			if !tt.exec {
-				mock.ExpectExec("INSERT INTO urls").
-					WithArgs("shortURL", "www.test.ru", "testID").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+			query := mock.ExpectQuery(`INSERT INTO urls .* ON CONFLICT \(original_url\) DO UPDATE .* RETURNING short_url, \(xmax = 0\)`).
+				WithArgs("www.test.ru", "shortURL", "testID", nil, nil, "")
+			if tt.queryErr != nil {
+				query.WillReturnError(tt.queryErr)
 			} else {
-				mock.ExpectExec("INSERT INTO urls").
-					WithArgs("shortURL", "www.test.ru", "testID").
-					WillReturnError(tt.execErr)
-				mock.ExpectRollback()
+				query.WillReturnRows(tt.rows)
 			}
 
 			storage := &PstStorage{
 				storage: db,
 			}
 
-			err = storage.SaveURL(tt.originalURL, tt.shortURL, tt.userID)
-
-			if errors.Is(err, tt.expectedErr) {
-				t.Errorf("SaveUrl = %t, want = %t", err, tt.expectedErr)
+			shortURL, err := storage.SaveURL(context.Background(), "shortURL", "www.test.ru", "testID", models.LinkOptions{})
+			assert.Equal(t, tt.expected, shortURL)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
 
-func TestPstStorage_SaveSliceOfDB(t *testing.T) {
-
-	tests := []struct {
-		name        string
-		urls        []models.MultipleURL
-		baseURL     string
-		userID      string
-		expectedErr error
-	}{
-		{
-			name: "successful",
-			urls: []models.MultipleURL{
-				{
-					CorrelationID: "1",
-					OriginalURL:   "www.test.ru",
-				},
-			},
-			baseURL: "http://localhost:8080",
-			userID:  "testID",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+// captureArg - аргумент sqlmock, который запоминает переданное значение.
+type captureArg struct {
+	value *string
+}
 
-			db, mock, err := sqlmock.New()
-			assert.NoError(t, err)
+// Match запоминает значение и принимает любую строку.
+func (a captureArg) Match(v driver.Value) bool {
+	str, ok := v.(string)
+	*a.value = str
+	return ok
+}
 
-			storage := &PstStorage{
-				storage: db,
-			}
-			mock.ExpectBegin()
-			// Сначала вызывается CheckURL и возвращает ошибку, чтобы мы перешли к генерации короткой ссылки
-			mock.ExpectQuery("SELECT short_url FROM urls WHERE original_url = ?").
-				WithArgs(tt.urls[0].OriginalURL).
-				WillReturnError(sql.ErrNoRows) // URL не найден в базе
-
-			// Мокируем успешную генерацию короткой ссылки и сохранение в базе
-			mock.ExpectExec("INSERT INTO urls").
-				WithArgs("encodedURL1", tt.urls[0].OriginalURL, tt.userID).
-				WillReturnResult(sqlmock.NewResult(1, 1))
-
-			// Мокируем завершение транзакции
-			mock.ExpectRollback() // Откат транзакции для обоих вызовов SaveURL
-			mock.ExpectCommit()
-
-			_, err = storage.SaveSlice(tt.urls, tt.baseURL, tt.userID)
-
-			// Сравнение ошибок
-			if err != nil && tt.expectedErr != nil {
-				if err.Error() != tt.expectedErr.Error() {
-					t.Errorf("Ожидали ошибку %v, пришла ошибка %v", tt.expectedErr, err)
-				}
-			} else if err != tt.expectedErr {
-				t.Errorf("Ожидали ошибку %v, пришла ошибка %v", tt.expectedErr, err)
+func TestPstStorage_SaveSlice(t *testing.T) {
+	const baseURL = "http://localhost:8080"
+	ctx := context.Background()
+
+	t.Run("successful", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		storage := &PstStorage{storage: db, codes: codegen.Default()}
+
+		var newShort, knownShort string
+		expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO urls \(original_url, short_url, user_id, expires_at, clicks_left\) VALUES \(\$1, \$2, \$3, \$4, \$5\), \(\$6, \$7, \$8, \$9, \$10\) ON CONFLICT DO NOTHING RETURNING original_url`).
+			WithArgs("https://new.com", captureArg{&newShort}, "user", expiresAt, 3,
+				"https://known.com", captureArg{&knownShort}, "user", nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"original_url"}).AddRow("https://new.com"))
+		mock.ExpectQuery(`SELECT original_url, short_url FROM urls WHERE original_url IN \(\$1\)`).
+			WithArgs("https://known.com").
+			WillReturnRows(sqlmock.NewRows([]string{"original_url", "short_url"}).
+				AddRow("https://known.com", "oldCode"))
+		mock.ExpectCommit()
+
+		result, err := storage.SaveSlice(ctx, []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://new.com", ExpiresAt: &expiresAt, MaxClicks: 3},
+			{CorrelationID: "2", OriginalURL: "https://known.com"},
+			{CorrelationID: "3", OriginalURL: "https://new.com"},
+		}, baseURL, "user")
+		assert.NoError(t, err)
+
+		// для известного URL возвращается существующая ссылка,
+		// повтор внутри пакета получает ту же ссылку
+		assert.Equal(t, []models.ResultMultipleURL{
+			{CorrelationID: "1", ShortURL: baseURL + "/" + newShort},
+			{CorrelationID: "2", ShortURL: baseURL + "/oldCode"},
+			{CorrelationID: "3", ShortURL: baseURL + "/" + newShort},
+		}, result)
+		assert.NotEmpty(t, newShort)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("collision_retry", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		storage := &PstStorage{storage: db, codes: codegen.Default()}
+
+		// первая короткая ссылка занята другим URL: строка не вставлена
+		// и не найдена по URL, поэтому ссылка генерируется заново
+		var firstShort, secondShort string
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO urls").
+			WithArgs("https://example.com", captureArg{&firstShort}, "user", nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"original_url"}))
+		mock.ExpectQuery("SELECT original_url, short_url FROM urls").
+			WithArgs("https://example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"original_url", "short_url"}))
+		mock.ExpectQuery("INSERT INTO urls").
+			WithArgs("https://example.com", captureArg{&secondShort}, "user", nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"original_url"}).AddRow("https://example.com"))
+		mock.ExpectCommit()
+
+		result, err := storage.SaveSlice(ctx, []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://example.com"},
+		}, baseURL, "user")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.ResultMultipleURL{
+			{CorrelationID: "1", ShortURL: baseURL + "/" + secondShort},
+		}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("alias_taken", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		storage := &PstStorage{storage: db, codes: codegen.Default()}
+
+		// пользовательская ссылка занята: пакет откатывается без повторной генерации
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO urls").
+			WithArgs("https://example.com", "spring-sale", "user", nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"original_url"}))
+		mock.ExpectQuery("SELECT original_url, short_url FROM urls").
+			WithArgs("https://example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"original_url", "short_url"}))
+		mock.ExpectRollback()
+
+		result, err := storage.SaveSlice(ctx, []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://example.com", CustomAlias: "spring-sale"},
+		}, baseURL, "user")
+		assert.ErrorIs(t, err, errors2.ErrAliasTaken)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		storage := &PstStorage{storage: db, codes: codegen.Default()}
+
+		urls := make([]models.MultipleURL, batchSize+1)
+		inserted := sqlmock.NewRows([]string{"original_url"})
+		for i := range urls {
+			urls[i] = models.MultipleURL{CorrelationID: fmt.Sprint(i), OriginalURL: fmt.Sprintf("https://example.com/%d", i)}
+			if i < batchSize {
+				inserted.AddRow(urls[i].OriginalURL)
 			}
-
-		})
-	}
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`\(\$4996, \$4997, \$4998, \$4999, \$5000\) ON CONFLICT`).
+			WillReturnRows(inserted)
+		mock.ExpectQuery(`VALUES \(\$1, \$2, \$3, \$4, \$5\) ON CONFLICT`).
+			WithArgs(urls[batchSize].OriginalURL, sqlmock.AnyArg(), "user", nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"original_url"}).AddRow(urls[batchSize].OriginalURL))
+		mock.ExpectCommit()
+
+		result, err := storage.SaveSlice(ctx, urls, baseURL, "user")
+		assert.NoError(t, err)
+		assert.Len(t, result, len(urls))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error_rollback", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		storage := &PstStorage{storage: db, codes: codegen.Default()}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO urls").WillReturnError(errors.New("insert error"))
+		mock.ExpectRollback()
+
+		result, err := storage.SaveSlice(ctx, []models.MultipleURL{
+			{CorrelationID: "1", OriginalURL: "https://example.com"},
+		}, baseURL, "user")
+		assert.EqualError(t, err, "insert error")
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		storage := &PstStorage{storage: db, codes: codegen.Default()}
+
+		result, err := storage.SaveSlice(ctx, nil, baseURL, "user")
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPstStorage_GetStats(t *testing.T) {
+	const query = `SELECT count\(\*\), count\(DISTINCT user_id\) FROM urls WHERE is_deleted = FALSE`
+
+	t.Run("successful", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"count", "count"}).AddRow(10, 3))
+
+		storage := &PstStorage{storage: db}
+		stats, err := storage.GetStats(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, &models.InternalStats{URLs: 10, Users: 3}, stats)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(query).WillReturnError(sql.ErrConnDone)
+
+		storage := &PstStorage{storage: db}
+		_, err = storage.GetStats(context.Background())
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -1,6 +1,19 @@
 package filestorage
 
-// CheckURL проверяет существует ли URL в файле.
-func (s *SaveFile) CheckURL(originalURL string) (string, error) {
+import (
+	"context"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+)
+
+// CheckURL проверяет существует ли URL в файле.
+func (s *SaveFile) CheckURL(ctx context.Context, originalURL string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if shortURL, ok := s.originals[originalURL]; ok {
+		return shortURL, errors2.ErrConflict
+	}
+
 	return "", nil
 }
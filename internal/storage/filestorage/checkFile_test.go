@@ -1,8 +1,13 @@
 package filestorage
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
 func TestSaveFile_CheckURL(t *testing.T) {
@@ -12,9 +17,17 @@ func TestSaveFile_CheckURL(t *testing.T) {
 	}
 	defer os.Remove("testStorage.txt")
 
-	_, err = storage.CheckURL("qwert")
+	_, err = storage.CheckURL(context.Background(), "qwert")
 	if err != nil {
 		t.Error("problam check url")
 	}
 
+	if _, err = storage.SaveURL(context.Background(), "qwert", "https://ya.ru", "test", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+
+	shortURL, err := storage.CheckURL(context.Background(), "https://ya.ru")
+	if !errors.Is(err, errors2.ErrConflict) || shortURL != "qwert" {
+		t.Errorf("ожидали конфликт с qwert, получили %q, %v", shortURL, err)
+	}
 }
@@ -0,0 +1,160 @@
+package filestorage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// defaultClickRetention - окно хранения поминутной истории переходов по умолчанию.
+const defaultClickRetention = 30 * 24 * time.Hour
+
+// ClickSummary - сводка переходов по ссылке в снимке журнала: общее число
+// переходов, хеши IP посетителей и число переходов по минутам.
+type ClickSummary struct {
+	Total    int                   `json:"total"`
+	Visitors []string              `json:"visitors,omitempty"`
+	Minutes  []models.ClicksBucket `json:"minutes,omitempty"`
+}
+
+// linkClicks - переходы по одной ссылке в индексе. Отдельные переходы не
+// хранятся: они сводятся в общий счетчик, множество посетителей и счетчики
+// по минутам, а минуты старше окна хранения отбрасываются.
+type linkClicks struct {
+	total    int
+	visitors map[string]struct{}
+	minutes  map[time.Time]int
+	// latest - последняя минута с переходами, от нее отсчитывается окно хранения.
+	latest time.Time
+}
+
+// newLinkClicks - конструктор пустой сводки переходов.
+func newLinkClicks() *linkClicks {
+	return &linkClicks{
+		visitors: make(map[string]struct{}),
+		minutes:  make(map[time.Time]int),
+	}
+}
+
+// add учитывает переход. Переход старше окна хранения попадает только
+// в общий счетчик и число посетителей.
+func (c *linkClicks) add(click models.Click, retention time.Duration) {
+	c.total++
+	if click.IPHash != "" {
+		c.visitors[click.IPHash] = struct{}{}
+	}
+	c.addMinute(models.BucketStart(click.At, time.Minute), 1, retention)
+}
+
+// merge добавляет сводку из снимка журнала.
+func (c *linkClicks) merge(summary *ClickSummary, retention time.Duration) {
+	c.total += summary.Total
+	for _, visitor := range summary.Visitors {
+		c.visitors[visitor] = struct{}{}
+	}
+	for _, minute := range summary.Minutes {
+		c.addMinute(minute.Start.UTC(), minute.Clicks, retention)
+	}
+}
+
+// addMinute добавляет count переходов в минуту minute. Окно хранения
+// сдвигается, только когда появляется более поздняя минута, поэтому
+// устаревшие минуты отбрасываются не чаще раза в минуту.
+func (c *linkClicks) addMinute(minute time.Time, count int, retention time.Duration) {
+	if minute.After(c.latest) {
+		c.latest = minute
+		if retention > 0 {
+			cutoff := c.latest.Add(-retention)
+			for start := range c.minutes {
+				if start.Before(cutoff) {
+					delete(c.minutes, start)
+				}
+			}
+		}
+	}
+	if retention > 0 && minute.Before(c.latest.Add(-retention)) {
+		return
+	}
+	c.minutes[minute] += count
+}
+
+// summary возвращает сводку для снимка журнала, минуты - по возрастанию.
+func (c *linkClicks) summary() *ClickSummary {
+	summary := &ClickSummary{Total: c.total}
+	for visitor := range c.visitors {
+		summary.Visitors = append(summary.Visitors, visitor)
+	}
+	slices.Sort(summary.Visitors)
+	for start, clicks := range c.minutes {
+		summary.Minutes = append(summary.Minutes, models.ClicksBucket{Start: start, Clicks: clicks})
+	}
+	sortBuckets(summary.Minutes)
+
+	return summary
+}
+
+// stats считает статистику: Total и Unique - по всем переходам,
+// интервалы длины bucket - по минутам начиная с since.
+func (c *linkClicks) stats(since time.Time, bucket time.Duration) *models.LinkStats {
+	stats := &models.LinkStats{Total: c.total, Unique: len(c.visitors)}
+
+	buckets := make(map[time.Time]int)
+	for start, clicks := range c.minutes {
+		if !start.Before(since) {
+			buckets[models.BucketStart(start, bucket)] += clicks
+		}
+	}
+	for start, clicks := range buckets {
+		stats.Buckets = append(stats.Buckets, models.ClicksBucket{Start: start, Clicks: clicks})
+	}
+	sortBuckets(stats.Buckets)
+
+	return stats
+}
+
+// sortBuckets сортирует интервалы по началу.
+func sortBuckets(buckets []models.ClicksBucket) {
+	slices.SortFunc(buckets, func(a, b models.ClicksBucket) int {
+		return a.Start.Compare(b.Start)
+	})
+}
+
+// SaveClicks дописывает в файл события переходов по ссылкам.
+// Переходы по несуществующим ссылкам пропускаются.
+func (s *SaveFile) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		if _, ok := s.urls[click.ShortURL]; !ok {
+			continue
+		}
+		// событие может остаться в pending компактизации, поэтому
+		// ссылается на копию, а не на элемент пачки вызывающего
+		if err := s.write(&Event{
+			ShortURL: click.ShortURL,
+			Click:    &click,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClickStats возвращает статистику переходов по ссылке.
+// Интервалы считаются по минутам в окне хранения, поэтому since
+// округляется вверх до минуты.
+func (s *SaveFile) ClickStats(ctx context.Context, shortURL string, since time.Time, bucket time.Duration) (*models.LinkStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clicks, ok := s.clicks[shortURL]
+	if !ok {
+		return &models.LinkStats{}, nil
+	}
+
+	return clicks.stats(since, bucket), nil
+}
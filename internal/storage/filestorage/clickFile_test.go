@@ -0,0 +1,108 @@
+package filestorage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+func TestSaveFile_SaveClicks(t *testing.T) {
+	fileName := "testStorage_clicks.txt"
+	defer os.Remove(fileName)
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+	if _, err = storage.SaveURL(ctx, "qwert", "https://ya.ru", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	err = storage.SaveClicks(ctx, []models.Click{
+		{ShortURL: "qwert", At: at, IPHash: "a"},
+		{ShortURL: "qwert", At: at.Add(time.Minute), IPHash: "b"},
+		{ShortURL: "missing", At: at},
+	})
+	if err != nil {
+		t.Fatalf("ошибка при сохранении переходов: %v", err)
+	}
+	if lines := countLines(t, fileName); lines != 3 {
+		t.Errorf("ожидали 3 строки, получили %d", lines)
+	}
+	storage.Close()
+
+	// переходы восстанавливаются из журнала и переживают компактизацию
+	for i := 0; i < 2; i++ {
+		storage, err = NewSaveFile(fileName)
+		if err != nil {
+			t.Fatalf("ошибка открытия тестового файла: %v", err)
+		}
+		stats, err := storage.ClickStats(ctx, "qwert", at, time.Hour)
+		if err != nil {
+			t.Fatalf("ошибка получения статистики: %v", err)
+		}
+		if stats.Total != 2 || stats.Unique != 2 || len(stats.Buckets) != 1 {
+			t.Errorf("неожиданная статистика: %+v", stats)
+		}
+		if err = storage.Compact(); err != nil {
+			t.Fatalf("ошибка компактизации: %v", err)
+		}
+		// в снимке ссылка и одна сводка ее переходов
+		if lines := countLines(t, fileName); lines != 2 {
+			t.Errorf("ожидали 2 строки, получили %d", lines)
+		}
+		storage.Close()
+	}
+}
+
+func TestSaveFile_ClickRetention(t *testing.T) {
+	fileName := "testStorage_click_retention.txt"
+	defer os.Remove(fileName)
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	storage, err := NewSaveFile(fileName, WithClickRetention(time.Hour))
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+	if _, err = storage.SaveURL(ctx, "qwert", "https://ya.ru", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	err = storage.SaveClicks(ctx, []models.Click{
+		{ShortURL: "qwert", At: at, IPHash: "a"},
+		{ShortURL: "qwert", At: at.Add(30 * time.Second), IPHash: "a"},
+		{ShortURL: "qwert", At: at.Add(2 * time.Hour), IPHash: "b"},
+		// опоздавший переход за пределами окна
+		{ShortURL: "qwert", At: at.Add(time.Minute), IPHash: "c"},
+	})
+	if err != nil {
+		t.Fatalf("ошибка при сохранении переходов: %v", err)
+	}
+
+	// минуты вне окна отброшены, счетчики сохранились, в том числе после компактизации
+	for i := 0; i < 2; i++ {
+		stats, err := storage.ClickStats(ctx, "qwert", at, time.Minute)
+		if err != nil {
+			t.Fatalf("ошибка получения статистики: %v", err)
+		}
+		if stats.Total != 4 || stats.Unique != 3 {
+			t.Errorf("неожиданные счетчики: %+v", stats)
+		}
+		if len(stats.Buckets) != 1 || !stats.Buckets[0].Start.Equal(at.Add(2*time.Hour)) || stats.Buckets[0].Clicks != 1 {
+			t.Errorf("неожиданные интервалы: %+v", stats.Buckets)
+		}
+
+		if err = storage.Compact(); err != nil {
+			t.Fatalf("ошибка компактизации: %v", err)
+		}
+		storage.Close()
+		if storage, err = NewSaveFile(fileName, WithClickRetention(time.Hour)); err != nil {
+			t.Fatalf("ошибка открытия тестового файла: %v", err)
+		}
+	}
+	storage.Close()
+}
@@ -0,0 +1,220 @@
+package filestorage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ErrCompactionInProgress - ошибка, если компактизация уже идет.
+var ErrCompactionInProgress = errors.New("compaction is already in progress")
+
+// ErrStorageClosed - ошибка, если файл хранилища уже закрыт.
+var ErrStorageClosed = errors.New("file storage is closed")
+
+// Compact переписывает журнал в снимок живых записей.
+//
+// Каждая запись, сводка переходов по ссылке и каждое задание на удаление
+// сохраняются одним событием, tombstone-события, выполненные задания и дубликаты
+// отбрасываются. Снимок пишется во временный файл без блокировки писателей,
+// события, записанные за это время, дописываются в конец снимка, после чего
+// временный файл атомарно переименовывается поверх журнала.
+func (s *SaveFile) Compact() error {
+	path, snapshot, err := s.beginCompaction()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".compact-*")
+	if err != nil {
+		s.abortCompaction()
+		return err
+	}
+
+	if err = s.writeSnapshot(tmp, snapshot); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		s.abortCompaction()
+		return err
+	}
+
+	return s.finishCompaction(tmp, len(snapshot))
+}
+
+// beginCompaction включает режим компактизации и возвращает путь к журналу и снимок записей.
+func (s *SaveFile) beginCompaction() (string, []Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return "", nil, ErrStorageClosed
+	}
+	if s.compacting {
+		return "", nil, ErrCompactionInProgress
+	}
+	s.compacting = true
+	s.pending = nil
+
+	snapshot := make([]Event, 0, len(s.order))
+	for _, shortURL := range s.order {
+		record := s.urls[shortURL]
+		snapshot = append(snapshot, Event{
+			UUID:         len(snapshot) + 1,
+			ShortURL:     record.ShortURL,
+			OriginalURL:  record.OriginalURL,
+			UserID:       record.UUID,
+			DeletedFlag:  record.DeletedFlag,
+			ExpiresAt:    record.ExpiresAt,
+			ClicksLeft:   record.ClicksLeft,
+			PasswordHash: record.PasswordHash,
+		})
+	}
+	for _, shortURL := range s.order {
+		if clicks, ok := s.clicks[shortURL]; ok {
+			snapshot = append(snapshot, Event{
+				UUID:     len(snapshot) + 1,
+				ShortURL: shortURL,
+				Clicks:   clicks.summary(),
+			})
+		}
+	}
+	// задания, в том числе отложенные, - в порядке ID
+	ids := make([]int64, 0, len(s.jobs))
+	for id := range s.jobs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		job := s.jobs[id]
+		snapshot = append(snapshot, Event{
+			UUID: len(snapshot) + 1,
+			Job:  &job,
+		})
+	}
+	if len(snapshot) > 0 {
+		snapshot[0].Seq = s.seq.Load()
+	}
+
+	return s.file.Name(), snapshot, nil
+}
+
+// abortCompaction выключает режим компактизации после ошибки.
+func (s *SaveFile) abortCompaction() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.compacting = false
+	s.pending = nil
+}
+
+// writeSnapshot записывает снимок во временный файл.
+func (s *SaveFile) writeSnapshot(tmp *os.File, snapshot []Event) error {
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	for i := range snapshot {
+		if err := encoder.Encode(&snapshot[i]); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// finishCompaction дописывает события, пришедшие во время компактизации,
+// и подменяет журнал снимком.
+func (s *SaveFile) finishCompaction(tmp *os.File, count int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defer func() {
+		s.compacting = false
+		s.pending = nil
+	}()
+
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if s.closed {
+		return fail(ErrStorageClosed)
+	}
+
+	encoder := json.NewEncoder(tmp)
+	for i := range s.pending {
+		count++
+		s.pending[i].UUID = count
+		if err := encoder.Encode(&s.pending[i]); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	path := s.file.Name()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+
+	s.file.Close()
+	s.file = file
+	s.encoder = json.NewEncoder(file)
+	s.count = count
+	if info, err := file.Stat(); err == nil {
+		s.compactedSize = info.Size()
+	}
+
+	return nil
+}
+
+// Size возвращает текущий размер файла журнала.
+func (s *SaveFile) Size() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// CompactIfLarger компактизирует журнал, если его размер не меньше threshold
+// и хотя бы вдвое больше размера после прошлой компактизации, чтобы не сжимать
+// файл, в котором почти нет мусора. Возвращает true, если журнал сжат.
+func (s *SaveFile) CompactIfLarger(threshold int64) (bool, error) {
+	size, err := s.Size()
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	compacted := s.compactedSize
+	s.mu.RUnlock()
+
+	if size < threshold || size < 2*compacted {
+		return false, nil
+	}
+
+	if err = s.Compact(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,167 @@
+package filestorage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// countLines считает количество строк в файле.
+func countLines(t *testing.T, fileName string) int {
+	t.Helper()
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия файла: %v", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestSaveFile_Compact(t *testing.T) {
+	fileName := "testStorage_compact.txt"
+	defer os.Remove(fileName)
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err = storage.SaveURL(context.Background(), fmt.Sprintf("short%d", i), fmt.Sprintf("https://example.com/%d", i), "owner", models.LinkOptions{}); err != nil {
+			t.Fatalf("ошибка при сохранении URL: %v", err)
+		}
+	}
+	if err = storage.DeletedURLs(context.Background(), []string{"short1", "short2"}, "owner"); err != nil {
+		t.Fatalf("ошибка при удалении URL: %v", err)
+	}
+
+	if lines := countLines(t, fileName); lines != 12 {
+		t.Fatalf("ожидали 12 строк до компактизации, получили %d", lines)
+	}
+
+	if err = storage.Compact(); err != nil {
+		t.Fatalf("ошибка компактизации: %v", err)
+	}
+
+	if lines := countLines(t, fileName); lines != 10 {
+		t.Errorf("ожидали 10 строк после компактизации, получили %d", lines)
+	}
+
+	// после компактизации запись продолжается в новый файл
+	if _, err = storage.SaveURL(context.Background(), "short10", "https://example.com/10", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	storage.Close()
+
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	if _, err = storage.GetURL(context.Background(), "short1"); !errors.Is(err, errors2.ErrDeletedURL) {
+		t.Errorf("ожидали ошибку %v, получили %v", errors2.ErrDeletedURL, err)
+	}
+	if record, err := storage.GetURL(context.Background(), "short10"); err != nil || record.OriginalURL != "https://example.com/10" {
+		t.Errorf("ожидали https://example.com/10, получили %v, %v", record, err)
+	}
+	if urls, err := storage.GetAllURL(context.Background(), "owner", ""); err != nil || len(urls) != 9 {
+		t.Errorf("ожидали 9 URL пользователя, получили %d, %v", len(urls), err)
+	}
+}
+
+// TestSaveFile_CompactIfLarger - журнал сжимается по порогу, но не раньше,
+// чем вдвое вырастет после прошлой компактизации.
+func TestSaveFile_CompactIfLarger(t *testing.T) {
+	fileName := "testStorage_compact_size.txt"
+	defer os.Remove(fileName)
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	save := func(from, to int) {
+		for i := from; i < to; i++ {
+			if _, err := storage.SaveURL(context.Background(), fmt.Sprintf("short%d", i), fmt.Sprintf("https://example.com/%d", i), "owner", models.LinkOptions{}); err != nil {
+				t.Fatalf("ошибка при сохранении URL: %v", err)
+			}
+		}
+	}
+	compact := func(threshold int64, expected bool) {
+		t.Helper()
+		compacted, err := storage.CompactIfLarger(threshold)
+		if err != nil || compacted != expected {
+			t.Fatalf("ожидали компактизацию %v, получили %v, %v", expected, compacted, err)
+		}
+	}
+
+	save(0, 10)
+	compact(1<<20, false)
+	compact(1, true)
+
+	// файл почти без мусора не сжимается повторно
+	save(10, 12)
+	compact(1, false)
+
+	// после удвоения размера сжимается снова
+	save(12, 30)
+	compact(1, true)
+}
+
+func TestSaveFile_CompactConcurrentWrites(t *testing.T) {
+	fileName := "testStorage_compact_concurrent.txt"
+	defer os.Remove(fileName)
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				id := fmt.Sprintf("%d_%d", w, i)
+				if _, err := storage.SaveURL(context.Background(), "s"+id, "https://example.com/"+id, "owner", models.LinkOptions{}); err != nil {
+					t.Errorf("ошибка при сохранении URL: %v", err)
+				}
+			}
+		}(w)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := storage.Compact(); err != nil && !errors.Is(err, ErrCompactionInProgress) {
+			t.Errorf("ошибка компактизации: %v", err)
+		}
+	}
+	wg.Wait()
+	storage.Close()
+
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	if urls, err := storage.GetAllURL(context.Background(), "owner", ""); err != nil || len(urls) != 200 {
+		t.Errorf("ожидали 200 URL пользователя, получили %d, %v", len(urls), err)
+	}
+}
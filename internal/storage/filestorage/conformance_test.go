@@ -0,0 +1,20 @@
+package filestorage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		storage, err := NewSaveFile(filepath.Join(t.TempDir(), "storage.json"))
+		if err != nil {
+			t.Fatalf("ошибка создания файла хранилища: %v", err)
+		}
+		t.Cleanup(func() { storage.Close() })
+		return storage
+	})
+}
@@ -1,8 +1,61 @@
 package filestorage
 
-import "errors"
+import (
+	"context"
 
-// DeletedURLs удаляет URL из файла.
-func (s *SaveFile) DeletedURLs(url []string, userID string) error {
-	return errors.New("there is no such implementation")
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// DeletedURLs помечает URL пользователя удаленными, дописывая в файл tombstone-события.
+// Чужие и уже удаленные URL пропускаются.
+func (s *SaveFile) DeletedURLs(ctx context.Context, urls []string, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, shortURL := range urls {
+		if _, err := s.markDeleted(shortURL, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteURLsBatch помечает удаленными URL нескольких пользователей
+// под одной блокировкой и возвращает URL своих владельцев.
+// Чужие URL пропускаются, для уже удаленных событие не пишется.
+func (s *SaveFile) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := make([]models.DeletedURL, 0, len(urls))
+	for _, url := range urls {
+		owned, err := s.markDeleted(url.ShortURL, url.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if owned {
+			deleted = append(deleted, url)
+		}
+	}
+
+	return deleted, nil
+}
+
+// markDeleted дописывает tombstone-событие для URL владельца userID и
+// сообщает, принадлежит ли URL владельцу. Вызывается под s.mu.
+func (s *SaveFile) markDeleted(shortURL, userID string) (bool, error) {
+	record, ok := s.urls[shortURL]
+	if !ok || record.UUID != userID {
+		return false, nil
+	}
+	if record.DeletedFlag {
+		return true, nil
+	}
+
+	return true, s.write(&Event{
+		ShortURL:    shortURL,
+		UserID:      userID,
+		DeletedFlag: true,
+	})
 }
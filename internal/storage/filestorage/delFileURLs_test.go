@@ -0,0 +1,59 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// TestSaveFile_DeletedURLs - тестирует удаление urls и восстановление удаления после перезапуска.
+func TestSaveFile_DeletedURLs(t *testing.T) {
+	fileName := "testStorage_deleted.txt"
+	defer os.Remove(fileName)
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+
+	if _, err = storage.SaveURL(context.Background(), "qwert", "https://ya.ru", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	if _, err = storage.SaveURL(context.Background(), "asdfg", "https://go.dev", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+
+	// чужой пользователь не может удалить ссылку
+	if err = storage.DeletedURLs(context.Background(), []string{"qwert"}, "stranger"); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, err = storage.GetURL(context.Background(), "qwert"); err != nil {
+		t.Errorf("ссылка не должна быть удалена: %v", err)
+	}
+
+	if err = storage.DeletedURLs(context.Background(), []string{"qwert", "unknown"}, "owner"); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, err = storage.GetURL(context.Background(), "qwert"); !errors.Is(err, errors2.ErrDeletedURL) {
+		t.Errorf("ожидали ошибку %v, получили %v", errors2.ErrDeletedURL, err)
+	}
+	storage.Close()
+
+	// после перезапуска состояние восстанавливается из файла
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	if _, err = storage.GetURL(context.Background(), "qwert"); !errors.Is(err, errors2.ErrDeletedURL) {
+		t.Errorf("ожидали ошибку %v, получили %v", errors2.ErrDeletedURL, err)
+	}
+	if record, err := storage.GetURL(context.Background(), "asdfg"); err != nil || record.OriginalURL != "https://go.dev" {
+		t.Errorf("ожидали https://go.dev, получили %v, %v", record, err)
+	}
+}
@@ -1,47 +1,82 @@
 package filestorage
 
 import (
-	"bufio"
-	"encoding/json"
-	"errors"
-	"os"
+	"context"
+	"fmt"
 
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
 // ErrShortURLNoFound - ошибка, если короткий URL не найден.
 var (
-	ErrNoUseInFile     = errors.New("not use GetAllURL in file")
-	ErrShortURLNoFound = errors.New("короткий URL не найден")
+	ErrShortURLNoFound = errors2.ErrNotFound
 )
 
-// GetURL возвращает оригинальный URL по короткому URL.
-func (s *SaveFile) GetURL(shortURL string) (string, error) {
-	// Читаем содержимое файла
-	readFile, err := os.Open(s.file.Name())
-	if err != nil {
-		return "", err
+// GetURL возвращает копию записи по короткому URL.
+func (s *SaveFile) GetURL(ctx context.Context, shortURL string) (*models.Storage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.urls[shortURL]
+	if !ok {
+		return nil, ErrShortURLNoFound
 	}
-	defer readFile.Close()
 
-	scanner := bufio.NewScanner(readFile)
+	if record.DeletedFlag {
+		return nil, errors2.ErrDeletedURL
+	}
 
-	for scanner.Scan() {
-		var event Event
-		line := scanner.Text()
-		err = json.Unmarshal([]byte(line), &event)
-		if err != nil {
-			continue // Пропуск некорректных JSON строк
-		}
-		if event.ShortURL == shortURL {
-			return event.OriginalURL, nil
-		}
+	result := *record
+	return &result, nil
+}
+
+// UseClick списывает переход по ссылке с ограничением, дописывая в файл
+// событие перехода, и возвращает остаток. Для ссылки без ограничения возвращает -1.
+func (s *SaveFile) UseClick(ctx context.Context, shortURL string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.urls[shortURL]
+	if !ok {
+		return 0, ErrShortURLNoFound
+	}
+	if record.DeletedFlag {
+		return 0, errors2.ErrDeletedURL
+	}
+	if record.ClicksLeft == nil {
+		return -1, nil
+	}
+	if *record.ClicksLeft <= 0 {
+		return 0, errors2.ErrExhaustedURL
+	}
+
+	if err := s.write(&Event{
+		ShortURL: shortURL,
+		Clicked:  true,
+	}); err != nil {
+		return 0, err
 	}
 
-	return "", ErrShortURLNoFound
+	return *record.ClicksLeft, nil
 }
 
-// GetAllURL возвращает все сохраненные URL-адреса пользователя.
-func (s *SaveFile) GetAllURL(userID, baseURL string) ([]*models.UserURLs, error) {
-	return nil, ErrNoUseInFile
+// GetAllURL возвращает все неудаленные URL-адреса пользователя.
+func (s *SaveFile) GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var userURLs []*models.UserURLs
+	for _, shortURL := range s.users[userID] {
+		record := s.urls[shortURL]
+		if record.DeletedFlag {
+			continue
+		}
+		userURLs = append(userURLs, &models.UserURLs{
+			ShortURL:    fmt.Sprintf("%s/%s", baseURL, shortURL),
+			OriginalURL: record.OriginalURL,
+		})
+	}
+
+	return userURLs, nil
 }
@@ -1,9 +1,13 @@
 package filestorage
 
 import (
+	"context"
 	"errors"
 	"os"
 	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
 func TestSaveFile_GetURL(t *testing.T) {
@@ -34,13 +38,13 @@ func TestSaveFile_GetURL(t *testing.T) {
 			defer os.Remove("testStorage_" + tt.name + ".txt")
 
 			if tt.shortURLSave != "" {
-				err = storage.SaveURL(tt.shortURLSave, "www.test.ru", "test")
+				_, err = storage.SaveURL(context.Background(), tt.shortURLSave, "www.test.ru", "test", models.LinkOptions{})
 				if err != nil {
 					t.Fatalf("ошибка при сохранении URL: %v", err)
 				}
 			}
 
-			_, err = storage.GetURL(tt.shortURLGate)
+			_, err = storage.GetURL(context.Background(), tt.shortURLGate)
 			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("ожидали ошибку %v, получили %v", tt.expectedErr, err)
 			}
@@ -49,27 +53,81 @@ func TestSaveFile_GetURL(t *testing.T) {
 }
 
 func TestSaveFile_GetAllURL(t *testing.T) {
-	cases := []struct {
-		name        string
-		expectedErr error
-	}{
-		{
-			name:        "successful",
-			expectedErr: ErrNoUseInFile,
-		},
+	storage, err := NewSaveFile("testStorage.txt")
+	if err != nil {
+		t.Fatal("problam create test file")
 	}
+	defer os.Remove("testStorage.txt")
+	defer storage.Close()
 
-	storage, err := NewSaveFile("testStorage.txt")
+	if _, err = storage.SaveURL(context.Background(), "qwert", "https://ya.ru", "test", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	if _, err = storage.SaveURL(context.Background(), "asdfg", "https://go.dev", "other", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+
+	urls, err := storage.GetAllURL(context.Background(), "test", "http://localhost:8080")
 	if err != nil {
-		t.Error("problam create test file")
+		t.Fatalf("неожиданная ошибка: %v", err)
 	}
 
-	defer os.Remove("testStorage.txt")
+	if len(urls) != 1 || urls[0].ShortURL != "http://localhost:8080/qwert" || urls[0].OriginalURL != "https://ya.ru" {
+		t.Errorf("неожиданный список URL: %+v", urls)
+	}
+
+	urls, err = storage.GetAllURL(context.Background(), "unknown", "http://localhost:8080")
+	if err != nil || len(urls) != 0 {
+		t.Errorf("ожидали пустой список, получили %+v, %v", urls, err)
+	}
+}
 
-	_, err = storage.GetAllURL("test", "http://localhost:8080")
+// TestSaveFile_UseClick - тестирует восстановление остатка переходов после перезапуска и компактизации.
+func TestSaveFile_UseClick(t *testing.T) {
+	fileName := "testStorage_click.txt"
+	defer os.Remove(fileName)
+	ctx := context.Background()
 
-	if !errors.Is(err, cases[0].expectedErr) {
-		t.Errorf("ожидали ошибку %v, получили %v", cases[0].expectedErr, err)
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+	if _, err = storage.SaveURL(ctx, "qwert", "https://ya.ru", "owner", models.LinkOptions{MaxClicks: 3}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	if clicks, err := storage.UseClick(ctx, "qwert"); err != nil || clicks != 2 {
+		t.Fatalf("ожидали остаток 2, получили %d, %v", clicks, err)
+	}
+	storage.Close()
+
+	// списанный переход восстанавливается из журнала
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	if clicks, err := storage.UseClick(ctx, "qwert"); err != nil || clicks != 1 {
+		t.Fatalf("ожидали остаток 1, получили %d, %v", clicks, err)
 	}
 
+	// снимок хранит остаток в самой записи
+	if err = storage.Compact(); err != nil {
+		t.Fatalf("ошибка компактизации: %v", err)
+	}
+	if lines := countLines(t, fileName); lines != 1 {
+		t.Errorf("ожидали 1 строку после компактизации, получили %d", lines)
+	}
+	storage.Close()
+
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	if clicks, err := storage.UseClick(ctx, "qwert"); err != nil || clicks != 0 {
+		t.Fatalf("ожидали остаток 0, получили %d, %v", clicks, err)
+	}
+	if _, err = storage.UseClick(ctx, "qwert"); !errors.Is(err, errors2.ErrExhaustedURL) {
+		t.Errorf("ожидали ошибку %v, получили %v", errors2.ErrExhaustedURL, err)
+	}
 }
@@ -0,0 +1,114 @@
+package filestorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// SaveDeletionJob дописывает в файл событие нового задания на удаление
+// и возвращает его ID.
+func (s *SaveFile) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.ID = s.jobSeq + 1
+	job = job.Clone()
+	if err := s.write(&Event{Job: &job}); err != nil {
+		return 0, err
+	}
+
+	return job.ID, nil
+}
+
+// GetDeletionJob возвращает задание на удаление по ID.
+func (s *SaveFile) GetDeletionJob(ctx context.Context, id int64) (*models.DeletionJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, errorscustom.ErrNotFound
+	}
+	job = job.Clone()
+
+	return &job, nil
+}
+
+// DueDeletionJobs возвращает задания на удаление, которые пора выполнить.
+func (s *SaveFile) DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return models.DueJobs(s.jobs, before, afterID, limit), nil
+}
+
+// ClaimDeletionJobs берет в работу задания, которые пора выполнить, до until,
+// и дописывает в файл события с их новым состоянием.
+func (s *SaveFile) ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claimed := models.DueJobs(s.jobs, before, 0, limit)
+	for i := range claimed {
+		claimed[i].NextAttempt = until
+		job := claimed[i].Clone()
+		if err := s.write(&Event{Job: &job}); err != nil {
+			return nil, err
+		}
+	}
+
+	return claimed, nil
+}
+
+// UpdateDeletionJobs дописывает в файл события с новым состоянием заданий.
+func (s *SaveFile) UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range jobs {
+		stored, ok := s.jobs[job.ID]
+		if !ok {
+			continue
+		}
+
+		stored = models.UpdateJob(stored, job)
+		if err := s.write(&Event{Job: &stored}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PurgeDeletionJobs дописывает в файл отметки об удалении заданий,
+// выполненных или отложенных раньше before.
+func (s *SaveFile) PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int
+	for _, id := range models.FinishedJobs(s.jobs, before) {
+		if err := s.write(&Event{Job: &models.DeletionJob{ID: id}, JobDone: true}); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// applyJob применяет событие задания к индексу. Вызывается под блокировкой.
+func (s *SaveFile) applyJob(job *models.DeletionJob, done bool) {
+	if job.ID > s.jobSeq {
+		s.jobSeq = job.ID
+	}
+
+	if done {
+		delete(s.jobs, job.ID)
+		return
+	}
+	s.jobs[job.ID] = *job
+}
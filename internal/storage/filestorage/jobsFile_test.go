@@ -0,0 +1,87 @@
+package filestorage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+func TestSaveFile_DeletionJobs(t *testing.T) {
+	fileName := "testStorage_jobs.txt"
+	defer os.Remove(fileName)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+	var ids []int64
+	for _, user := range []string{"first", "second", "third"} {
+		id, err := storage.SaveDeletionJob(ctx, models.DeletionJob{UserID: user, URLs: []string{"qwert"}, NextAttempt: now})
+		if err != nil {
+			t.Fatalf("ошибка при сохранении задания: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	err = storage.UpdateDeletionJobs(ctx, []models.DeletionJob{
+		{ID: ids[1], Status: models.JobFailed, Attempts: 3, NextAttempt: now, FinishedAt: &now},
+		{ID: ids[2], Status: models.JobDone, NextAttempt: now, Deleted: []string{"qwert"}, FinishedAt: &expired},
+	})
+	if err != nil {
+		t.Fatalf("ошибка при обновлении заданий: %v", err)
+	}
+	if _, err = storage.PurgeDeletionJobs(ctx, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("ошибка при удалении заданий: %v", err)
+	}
+	if lines := countLines(t, fileName); lines != 6 {
+		t.Errorf("ожидали 6 строк, получили %d", lines)
+	}
+	storage.Close()
+
+	// задания восстанавливаются из журнала и переживают компактизацию
+	for i := 0; i < 2; i++ {
+		storage, err = NewSaveFile(fileName)
+		if err != nil {
+			t.Fatalf("ошибка открытия тестового файла: %v", err)
+		}
+		jobs, err := storage.DueDeletionJobs(ctx, now, 0, 10)
+		if err != nil {
+			t.Fatalf("ошибка получения заданий: %v", err)
+		}
+		if len(jobs) != 1 || jobs[0].ID != ids[0] || jobs[0].UserID != "first" {
+			t.Errorf("неожиданные задания: %+v", jobs)
+		}
+		if failed := storage.jobs[ids[1]]; failed.Status != models.JobFailed || failed.Attempts != 3 {
+			t.Errorf("отложенное задание потеряно: %+v", failed)
+		}
+		if _, ok := storage.jobs[ids[2]]; ok {
+			t.Errorf("удаленное задание восстановлено")
+		}
+
+		// ID новых заданий продолжают последовательность
+		id, err := storage.SaveDeletionJob(ctx, models.DeletionJob{UserID: "next", NextAttempt: now})
+		if err != nil {
+			t.Fatalf("ошибка при сохранении задания: %v", err)
+		}
+		if id <= ids[1] {
+			t.Errorf("ожидали ID больше %d, получили %d", ids[1], id)
+		}
+		err = storage.UpdateDeletionJobs(ctx, []models.DeletionJob{{ID: id, Status: models.JobDone, NextAttempt: now, FinishedAt: &expired}})
+		if err != nil {
+			t.Fatalf("ошибка при завершении задания: %v", err)
+		}
+		if _, err = storage.PurgeDeletionJobs(ctx, now.Add(-time.Minute)); err != nil {
+			t.Fatalf("ошибка при удалении заданий: %v", err)
+		}
+
+		if err = storage.Compact(); err != nil {
+			t.Fatalf("ошибка компактизации: %v", err)
+		}
+		storage.Close()
+	}
+}
@@ -0,0 +1,53 @@
+package filestorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// PurgeExpired удаляет не более limit ссылок, истекших до before,
+// дописывая в файл purge-события. Возвращает количество удаленных ссылок.
+func (s *SaveFile) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	return s.purgeMatching(limit, func(record *models.Storage) bool {
+		return record.Expired(before)
+	})
+}
+
+// PurgeDeleted удаляет не более limit ссылок, помеченных удаленными,
+// дописывая в файл purge-события. Возвращает количество удаленных ссылок.
+func (s *SaveFile) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	return s.purgeMatching(limit, func(record *models.Storage) bool {
+		return record.DeletedFlag
+	})
+}
+
+// purgeMatching удаляет не более limit ссылок, подходящих под match.
+// Удаленные ссылки убираются из order одним проходом после всей пачки.
+func (s *SaveFile) purgeMatching(limit int, match func(record *models.Storage) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.dropPurged()
+
+	var matched []string
+	for _, shortURL := range s.order {
+		if len(matched) >= limit {
+			break
+		}
+		if match(s.urls[shortURL]) {
+			matched = append(matched, shortURL)
+		}
+	}
+
+	for i, shortURL := range matched {
+		if err := s.write(&Event{
+			ShortURL: shortURL,
+			Purged:   true,
+		}); err != nil {
+			return i, err
+		}
+	}
+
+	return len(matched), nil
+}
@@ -0,0 +1,129 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// TestSaveFile_PurgeExpired - тестирует удаление истекших urls и его восстановление после перезапуска.
+func TestSaveFile_PurgeExpired(t *testing.T) {
+	fileName := "testStorage_purge.txt"
+	defer os.Remove(fileName)
+	ctx := context.Background()
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	alive := now.Add(time.Hour).Truncate(time.Millisecond)
+	if _, err = storage.SaveURL(ctx, "qwert", "https://ya.ru", "owner", models.LinkOptions{ExpiresAt: &expired}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	if _, err = storage.SaveURL(ctx, "asdfg", "https://go.dev", "owner", models.LinkOptions{ExpiresAt: &alive}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+
+	if purged, err := storage.PurgeExpired(ctx, now, 10); err != nil || purged != 1 {
+		t.Fatalf("ожидали 1 удаленную ссылку, получили %d, %v", purged, err)
+	}
+	storage.Close()
+
+	// удаление и момент истечения восстанавливаются из журнала
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	if _, err = storage.GetURL(ctx, "qwert"); !errors.Is(err, errors2.ErrNotFound) {
+		t.Errorf("ожидали ошибку %v, получили %v", errors2.ErrNotFound, err)
+	}
+	record, err := storage.GetURL(ctx, "asdfg")
+	if err != nil || record.ExpiresAt == nil || !record.ExpiresAt.Equal(alive) {
+		t.Errorf("ожидали ссылку с истечением %v, получили %v, %v", alive, record, err)
+	}
+
+	// снимок не содержит удаленную ссылку, но сохраняет момент истечения
+	if err = storage.Compact(); err != nil {
+		t.Fatalf("ошибка компактизации: %v", err)
+	}
+	if lines := countLines(t, fileName); lines != 1 {
+		t.Errorf("ожидали 1 строку после компактизации, получили %d", lines)
+	}
+	storage.Close()
+
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	record, err = storage.GetURL(ctx, "asdfg")
+	if err != nil || record.ExpiresAt == nil || !record.ExpiresAt.Equal(alive) {
+		t.Errorf("ожидали ссылку с истечением %v, получили %v, %v", alive, record, err)
+	}
+
+	// освобожденный URL можно сохранить снова
+	if _, err = storage.SaveURL(ctx, "zxcvb", "https://ya.ru", "owner", models.LinkOptions{}); err != nil {
+		t.Errorf("ошибка при повторном сохранении URL: %v", err)
+	}
+}
+
+// TestSaveFile_PurgeDeleted_Order - тестирует, что пачка удалений и повторное создание ссылки не оставляют лишних записей в order.
+func TestSaveFile_PurgeDeleted_Order(t *testing.T) {
+	fileName := "testStorage_purge_order.txt"
+	defer os.Remove(fileName)
+	ctx := context.Background()
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+
+	for _, shortURL := range []string{"aaaaa", "bbbbb", "ccccc", "ddddd"} {
+		if _, err = storage.SaveURL(ctx, shortURL, "https://"+shortURL+".ru", "owner", models.LinkOptions{}); err != nil {
+			t.Fatalf("ошибка при сохранении URL: %v", err)
+		}
+	}
+	if err = storage.DeletedURLs(ctx, []string{"aaaaa", "ccccc", "ddddd"}, "owner"); err != nil {
+		t.Fatalf("ошибка при удалении URL: %v", err)
+	}
+
+	if purged, err := storage.PurgeDeleted(ctx, 2); err != nil || purged != 2 {
+		t.Fatalf("ожидали 2 удаленные ссылки, получили %d, %v", purged, err)
+	}
+	if want := []string{"bbbbb", "ddddd"}; !slices.Equal(storage.order, want) {
+		t.Errorf("ожидали order %v, получили %v", want, storage.order)
+	}
+
+	// удаленный код можно занять снова, в order он попадает один раз
+	if _, err = storage.SaveURL(ctx, "aaaaa", "https://aaaaa.ru", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при повторном сохранении URL: %v", err)
+	}
+	if purged, err := storage.PurgeDeleted(ctx, 10); err != nil || purged != 1 {
+		t.Fatalf("ожидали 1 удаленную ссылку, получили %d, %v", purged, err)
+	}
+	if want := []string{"bbbbb", "aaaaa"}; !slices.Equal(storage.order, want) {
+		t.Errorf("ожидали order %v, получили %v", want, storage.order)
+	}
+	storage.Close()
+
+	// при воспроизведении журнала повторно созданный код тоже не дублируется
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	if want := []string{"bbbbb", "aaaaa"}; !slices.Equal(storage.order, want) {
+		t.Errorf("ожидали order %v, получили %v", want, storage.order)
+	}
+}
@@ -2,57 +2,289 @@ package filestorage
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
 )
 
 // IFileStorage - интерфейс для хранения в файле.
 type IFileStorage interface {
-	SaveURL(shortURL, originalURL, userID string) error
-	GetURL(shortURL string) (string, error)
+	SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error)
+	GetURL(ctx context.Context, shortURL string) (*models.Storage, error)
 	Close() error
 }
 
-// Count - счетчик для уникального идентификатора.
-var Count int
+// maxLineSize - максимальный размер одной строки в файле.
+const maxLineSize = 1024 * 1024
 
 // Event - структура для хранения событий.
+//
+// Событие с DeletedFlag = true и пустым OriginalURL - это tombstone: отметка
+// об удалении короткой ссылки ShortURL пользователем UserID. Событие с
+// DeletedFlag = true и заполненным OriginalURL - удаленная запись из снимка.
+// Событие с Purged = true удаляет истекшую короткую ссылку ShortURL из индекса.
+// Событие с Clicked = true списывает переход по ссылке ShortURL с ограничением,
+// ClicksLeft у записи - оставшееся число переходов, PasswordHash - хеш пароля.
+// Событие с заполненным Click - переход по ссылке ShortURL для статистики,
+// с заполненным Clicks - сводка переходов по ссылке из снимка журнала.
+// Событие с заполненным Job - новое состояние задания на удаление,
+// с JobDone = true - удаление задания с ID Job.ID.
+// Seq - значение счетчика коротких ссылок на момент записи события.
 type Event struct {
-	UUID        int    `json:"uuid"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	UUID         int                 `json:"uuid"`
+	ShortURL     string              `json:"short_url"`
+	OriginalURL  string              `json:"original_url,omitempty"`
+	UserID       string              `json:"user_id,omitempty"`
+	DeletedFlag  bool                `json:"is_deleted,omitempty"`
+	ExpiresAt    *time.Time          `json:"expires_at,omitempty"`
+	ClicksLeft   *int                `json:"clicks_left,omitempty"`
+	PasswordHash string              `json:"password_hash,omitempty"`
+	Purged       bool                `json:"purged,omitempty"`
+	Clicked      bool                `json:"clicked,omitempty"`
+	Click        *models.Click       `json:"click,omitempty"`
+	Clicks       *ClickSummary       `json:"clicks,omitempty"`
+	Job          *models.DeletionJob `json:"job,omitempty"`
+	JobDone      bool                `json:"job_done,omitempty"`
+	Seq          uint64              `json:"seq,omitempty"`
 }
 
 // SaveFile - структура для хранения в файле.
+//
+// Файл - это журнал событий, который при запуске воспроизводится
+// в индекс в памяти. Все чтения идут из индекса, запись - в конец файла.
 type SaveFile struct {
 	file    *os.File
 	encoder *json.Encoder
+
+	mu        sync.RWMutex
+	count     int
+	order     []string
+	urls      map[string]*models.Storage
+	originals map[string]string
+	users     map[string][]string
+	clicks    map[string]*linkClicks
+
+	// purged - число удаленных ссылок, которые еще не убраны из order.
+	purged int
+
+	// clickRetention - окно хранения поминутной истории переходов.
+	clickRetention time.Duration
+
+	// jobs - задания на удаление, jobSeq - последний выданный ID задания.
+	jobs   map[int64]models.DeletionJob
+	jobSeq int64
+
+	// compacting и pending - состояние идущей компактизации,
+	// compactedSize - размер журнала после последней.
+	compacting    bool
+	pending       []Event
+	compactedSize int64
+	closed        bool
+
+	// codes - стратегия генерации ссылок для SaveSlice, seq - счетчик для нее.
+	// Счетчик не берет блокировку, так как генерация идет под ней.
+	codes codegen.CodeGenerator
+	seq   atomic.Uint64
+}
+
+// Option - опция файлового хранилища.
+type Option func(s *SaveFile)
+
+// WithClickRetention задает окно хранения поминутной истории переходов,
+// по умолчанию 30 дней. Минуты старше последнего перехода по ссылке
+// на retention отбрасываются, общее число переходов и посетителей
+// сохраняется. Нулевое окно хранит историю целиком.
+func WithClickRetention(retention time.Duration) Option {
+	return func(s *SaveFile) {
+		s.clickRetention = retention
+	}
 }
 
 // NewSaveFile создает новый SaveFile.
-func NewSaveFile(filePath string) (*SaveFile, error) {
+func NewSaveFile(filePath string, opts ...Option) (*SaveFile, error) {
 	// откройте файл и создайте для него json.Encoder
 	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		return nil, err
 	}
 
+	s := &SaveFile{
+		file:      file,
+		encoder:   json.NewEncoder(file),
+		urls:      make(map[string]*models.Storage),
+		originals: make(map[string]string),
+		users:     make(map[string][]string),
+		clicks:    make(map[string]*linkClicks),
+		jobs:      make(map[int64]models.DeletionJob),
+		codes:     codegen.Default(),
+
+		clickRetention: defaultClickRetention,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err = s.replay(filePath); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// replay читает журнал и восстанавливает индекс в памяти.
+func (s *SaveFile) replay(filePath string) error {
 	readFile, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer readFile.Close()
 
 	scanner := bufio.NewScanner(readFile)
-	// optionally, resize scanner's capacity for lines over 64K, see next example
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
 	for scanner.Scan() {
-		Count++
+		s.count++
+
+		var event Event
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue // Пропуск некорректных JSON строк
+		}
+		s.apply(&event)
+		if event.Seq > s.seq.Load() {
+			s.seq.Store(event.Seq)
+		}
+	}
+	s.dropPurged()
+
+	return scanner.Err()
+}
+
+// SetCodeGenerator задает стратегию генерации коротких ссылок для SaveSlice.
+func (s *SaveFile) SetCodeGenerator(gen codegen.CodeGenerator) {
+	s.codes = gen
+}
+
+// NextID возвращает следующее значение счетчика коротких ссылок.
+// Значение сохраняется в журнале со следующим событием.
+func (s *SaveFile) NextID(ctx context.Context) (uint64, error) {
+	return s.seq.Add(1), nil
+}
+
+// apply применяет событие к индексу. Вызывается под блокировкой.
+func (s *SaveFile) apply(event *Event) {
+	if event.Job != nil {
+		s.applyJob(event.Job, event.JobDone)
+		return
+	}
+
+	if event.Purged {
+		s.purge(event.ShortURL)
+		return
+	}
+
+	if event.Clicked {
+		s.click(event.ShortURL)
+		return
+	}
+
+	if event.Click != nil || event.Clicks != nil {
+		if _, ok := s.urls[event.ShortURL]; !ok {
+			return
+		}
+		clicks, ok := s.clicks[event.ShortURL]
+		if !ok {
+			clicks = newLinkClicks()
+			s.clicks[event.ShortURL] = clicks
+		}
+		if event.Click != nil {
+			clicks.add(*event.Click, s.clickRetention)
+		} else {
+			clicks.merge(event.Clicks, s.clickRetention)
+		}
+		return
+	}
+
+	if event.DeletedFlag && event.OriginalURL == "" {
+		if record, ok := s.urls[event.ShortURL]; ok && record.UUID == event.UserID {
+			record.DeletedFlag = true
+		}
+		return
 	}
 
-	return &SaveFile{
-		file:    file,
-		encoder: json.NewEncoder(file),
-	}, nil
+	if _, ok := s.urls[event.ShortURL]; ok {
+		return
+	}
+
+	// код мог быть удален и занят снова: старую запись убираем из order до добавления
+	s.dropPurged()
+	s.urls[event.ShortURL] = &models.Storage{
+		UUID:         event.UserID,
+		ShortURL:     event.ShortURL,
+		OriginalURL:  event.OriginalURL,
+		DeletedFlag:  event.DeletedFlag,
+		ExpiresAt:    event.ExpiresAt,
+		ClicksLeft:   event.ClicksLeft,
+		PasswordHash: event.PasswordHash,
+	}
+	s.order = append(s.order, event.ShortURL)
+	if _, ok := s.originals[event.OriginalURL]; !ok {
+		s.originals[event.OriginalURL] = event.ShortURL
+	}
+	if event.UserID != "" {
+		s.users[event.UserID] = append(s.users[event.UserID], event.ShortURL)
+	}
+}
+
+// purge удаляет короткую ссылку из всех индексов. Вызывается под блокировкой.
+func (s *SaveFile) purge(shortURL string) {
+	record, ok := s.urls[shortURL]
+	if !ok {
+		return
+	}
+
+	delete(s.urls, shortURL)
+	delete(s.clicks, shortURL)
+	s.purged++
+	if s.originals[record.OriginalURL] == shortURL {
+		delete(s.originals, record.OriginalURL)
+	}
+	if record.UUID != "" {
+		s.users[record.UUID] = slices.DeleteFunc(s.users[record.UUID], func(u string) bool {
+			return u == shortURL
+		})
+	}
+}
+
+// dropPurged за один проход убирает из order удаленные ссылки. Вызывается под блокировкой.
+func (s *SaveFile) dropPurged() {
+	if s.purged == 0 {
+		return
+	}
+
+	s.order = slices.DeleteFunc(s.order, func(shortURL string) bool {
+		_, ok := s.urls[shortURL]
+		return !ok
+	})
+	s.purged = 0
+}
+
+// click уменьшает остаток переходов по ссылке. Вызывается под блокировкой.
+// Копии записи делят указатель на остаток, поэтому он заменяется, а не меняется на месте.
+func (s *SaveFile) click(shortURL string) {
+	record, ok := s.urls[shortURL]
+	if !ok || record.ClicksLeft == nil || *record.ClicksLeft <= 0 {
+		return
+	}
+
+	clicks := *record.ClicksLeft - 1
+	record.ClicksLeft = &clicks
 }
 
 // WriteSaveModel добавляет Event в файл.
@@ -60,13 +292,35 @@ func (s *SaveFile) WriteSaveModel(event *Event) error {
 	return s.encoder.Encode(&event)
 }
 
+// write записывает событие в файл и применяет его к индексу. Вызывается под блокировкой.
+func (s *SaveFile) write(event *Event) error {
+	s.count++
+	event.UUID = s.count
+	event.Seq = s.seq.Load()
+
+	if err := s.WriteSaveModel(event); err != nil {
+		return err
+	}
+
+	if s.compacting {
+		s.pending = append(s.pending, *event)
+	}
+
+	s.apply(event)
+	return nil
+}
+
 // Close закрывает файл.
 func (s *SaveFile) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
 	return s.file.Close()
 }
 
 // Ping проверяет соединение с файлом.
-func (s *SaveFile) Ping() error {
+func (s *SaveFile) Ping(ctx context.Context) error {
 	return nil
 }
 
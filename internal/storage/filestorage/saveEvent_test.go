@@ -1,8 +1,11 @@
 package filestorage
 
 import (
+	"context"
 	"os"
 	"testing"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
 func TestNewSaveFile(t *testing.T) {
@@ -40,3 +43,37 @@ func TestNewSaveFile(t *testing.T) {
 		}
 	})
 }
+
+func TestSaveFile_NextID_Reopen(t *testing.T) {
+	fileName := "testStorage_seq.txt"
+	defer os.Remove(fileName)
+	ctx := context.Background()
+
+	storage, err := NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка создания тестового файла: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = storage.NextID(ctx); err != nil {
+			t.Fatalf("ошибка получения значения счетчика: %v", err)
+		}
+	}
+	if _, err = storage.SaveURL(ctx, "short", "https://example.com", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+	// снимок тоже сохраняет счетчик
+	if err = storage.Compact(); err != nil {
+		t.Fatalf("ошибка компактизации: %v", err)
+	}
+	storage.Close()
+
+	storage, err = NewSaveFile(fileName)
+	if err != nil {
+		t.Fatalf("ошибка открытия тестового файла: %v", err)
+	}
+	defer storage.Close()
+
+	if id, err := storage.NextID(ctx); err != nil || id != 4 {
+		t.Errorf("ожидали 4, получили %d, %v", id, err)
+	}
+}
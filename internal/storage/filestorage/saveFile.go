@@ -1,24 +1,96 @@
 package filestorage
 
-import "github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+import (
+	"context"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+)
 
 // SaveURL - функция для записи в файл.
-func (s *SaveFile) SaveURL(shortURL, originalURL, userID string) error {
-	Count++
-	event := &Event{
-		UUID:        Count,
-		ShortURL:    shortURL,
-		OriginalURL: originalURL,
+// Для уже сохраненного URL возвращает его короткую ссылку и ErrConflict.
+func (s *SaveFile) SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.originals[originalURL]; ok {
+		return existing, errors2.ErrConflict
+	}
+	if _, ok := s.urls[shortURL]; ok {
+		return "", errors2.ErrShortURLCollision
 	}
 
 	// Записываем событие напрямую, избегая создания массива.
-	if err := s.WriteSaveModel(event); err != nil {
-		return err
+	err := s.write(&Event{
+		ShortURL:     shortURL,
+		OriginalURL:  originalURL,
+		UserID:       userID,
+		ExpiresAt:    opts.ExpiresAt,
+		ClicksLeft:   opts.ClicksLeft(),
+		PasswordHash: opts.PasswordHash,
+	})
+	if err != nil {
+		return "", err
 	}
-	return nil
+
+	return shortURL, nil
 }
 
-// SaveSliceOfDB - функция для записи в файл.
-func (s *SaveFile) SaveSlice(urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
-	return nil, nil
+// SaveSlice - функция для записи в файл множества URL.
+// Для уже сохраненных URL возвращается существующая короткая ссылка.
+// Короткие ссылки создаются до записи, поэтому занятая пользовательская
+// ссылка отменяет пакет целиком.
+func (s *SaveFile) SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := make(map[string]string)
+	reserved := make(map[string]bool)
+	resultMultipleURL := make([]models.ResultMultipleURL, 0, len(urls))
+	for _, req := range urls {
+		shortURL, ok := s.originals[req.OriginalURL]
+		if !ok {
+			shortURL, ok = created[req.OriginalURL]
+		}
+		if !ok {
+			encodeURL, err := codegen.UniqueOrAlias(ctx, s.codes, req.OriginalURL, req.CustomAlias, func(shortURL string) error {
+				if _, ok := s.urls[shortURL]; ok || reserved[shortURL] {
+					return errors2.ErrShortURLCollision
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			created[req.OriginalURL] = encodeURL
+			reserved[encodeURL] = true
+			shortURL = encodeURL
+		}
+
+		resultMultipleURL = append(resultMultipleURL, models.ResultMultipleURL{
+			CorrelationID: req.CorrelationID,
+			ShortURL:      baseURL + "/" + shortURL,
+		})
+	}
+
+	for _, req := range urls {
+		shortURL, ok := created[req.OriginalURL]
+		if !ok {
+			continue
+		}
+		err := s.write(&Event{
+			ShortURL:    shortURL,
+			OriginalURL: req.OriginalURL,
+			UserID:      userID,
+			ExpiresAt:   req.ExpiresAt,
+			ClicksLeft:  req.LinkOptions().ClicksLeft(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		delete(created, req.OriginalURL)
+	}
+
+	return resultMultipleURL, nil
 }
@@ -1,8 +1,13 @@
 package filestorage
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
 // TestSaveFile_SaveURL - тестируем сохранение в файл.
@@ -11,15 +16,53 @@ func TestSaveFile_SaveURL(t *testing.T) {
 	if err != nil {
 		t.Error("problam create test file")
 	}
+	defer os.Remove("testStorage.txt")
 
 	shortURL := "qwert"
 	originURL := "https://www.ya.ru"
 	userID := "test"
 
-	err = storage.SaveURL(shortURL, originURL, userID)
+	_, err = storage.SaveURL(context.Background(), shortURL, originURL, userID, models.LinkOptions{})
 
 	if err != nil {
 		t.Error("problam save url")
 	}
-	os.Remove("testStorage.txt")
+
+	_, err = storage.SaveURL(context.Background(), "other", originURL, userID, models.LinkOptions{})
+	if !errors.Is(err, errors2.ErrConflict) {
+		t.Errorf("ожидали ошибку %v, получили %v", errors2.ErrConflict, err)
+	}
+}
+
+// TestSaveFile_SaveSlice - тестируем сохранение множества URL в файл.
+func TestSaveFile_SaveSlice(t *testing.T) {
+	storage, err := NewSaveFile("testStorage_slice.txt")
+	if err != nil {
+		t.Fatal("problam create test file")
+	}
+	defer os.Remove("testStorage_slice.txt")
+
+	if _, err = storage.SaveURL(context.Background(), "qwert", "https://ya.ru", "test", models.LinkOptions{}); err != nil {
+		t.Fatalf("ошибка при сохранении URL: %v", err)
+	}
+
+	result, err := storage.SaveSlice(context.Background(), []models.MultipleURL{
+		{CorrelationID: "1", OriginalURL: "https://ya.ru"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev"},
+	}, "http://localhost:8080", "test")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("ожидали 2 результата, получили %d", len(result))
+	}
+	if result[0].CorrelationID != "1" || result[0].ShortURL != "http://localhost:8080/qwert" {
+		t.Errorf("ожидали существующую ссылку, получили %+v", result[0])
+	}
+
+	urls, err := storage.GetAllURL(context.Background(), "test", "http://localhost:8080")
+	if err != nil || len(urls) != 2 {
+		t.Errorf("ожидали 2 URL пользователя, получили %d, %v", len(urls), err)
+	}
 }
@@ -0,0 +1,20 @@
+package filestorage
+
+import (
+	"context"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// GetStats возвращает число неудаленных ссылок и их владельцев.
+func (s *SaveFile) GetStats(ctx context.Context) (*models.InternalStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var counter models.StatsCounter
+	for _, record := range s.urls {
+		counter.Add(record)
+	}
+
+	return counter.Stats(), nil
+}
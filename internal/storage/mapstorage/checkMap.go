@@ -1,6 +1,19 @@
 package mapstorage
 
+import (
+	"context"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+)
+
 // CheckURL проверяет существует ли URL в мапе.
-func (s *MapStorage) CheckURL(originalURL string) (string, error) {
+func (s *MapStorage) CheckURL(ctx context.Context, originalURL string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if shortURL, ok := s.originals[originalURL]; ok {
+		return shortURL, errors2.ErrConflict
+	}
+
 	return "", nil
 }
@@ -1,16 +1,28 @@
 package mapstorage
 
 import (
+	"context"
+	"errors"
 	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
 // TestMapStorage_CheckURL - проверяет есть ли url в мапе уже.
 func TestMapStorage_CheckURL(t *testing.T) {
 	storage := NewMapURL()
 	url := "www"
-	_, err := storage.CheckURL(url)
+	_, err := storage.CheckURL(context.Background(), url)
 	if err != nil {
 		t.Error("no way")
 	}
 
+	if _, err = storage.SaveURL(context.Background(), "short", url, "test", models.LinkOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	shortURL, err := storage.CheckURL(context.Background(), url)
+	if !errors.Is(err, errors2.ErrConflict) || shortURL != "short" {
+		t.Errorf("expected conflict with short, got %q, %v", shortURL, err)
+	}
 }
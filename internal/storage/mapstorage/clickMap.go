@@ -0,0 +1,31 @@
+package mapstorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// SaveClicks сохраняет переходы по ссылкам.
+// Переходы по несуществующим ссылкам пропускаются.
+func (s *MapStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		if _, ok := s.storage[click.ShortURL]; ok {
+			s.clicks[click.ShortURL] = append(s.clicks[click.ShortURL], click)
+		}
+	}
+
+	return nil
+}
+
+// ClickStats возвращает статистику переходов по ссылке.
+func (s *MapStorage) ClickStats(ctx context.Context, shortURL string, since time.Time, bucket time.Duration) (*models.LinkStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return models.CountClicks(s.clicks[shortURL], since, bucket), nil
+}
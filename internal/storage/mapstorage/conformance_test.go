@@ -0,0 +1,14 @@
+package mapstorage
+
+import (
+	"testing"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		return NewMapURL()
+	})
+}
@@ -1,8 +1,39 @@
 package mapstorage
 
-import "errors"
+import (
+	"context"
 
-// DeletedURLs удаляет URL из мапы.
-func (s *MapStorage) DeletedURLs(url []string, userID string) error {
-	return errors.New("there is no such implementation")
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// DeletedURLs помечает URL пользователя удаленными.
+// Чужие и несуществующие URL пропускаются.
+func (s *MapStorage) DeletedURLs(ctx context.Context, urls []string, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, shortURL := range urls {
+		if record, ok := s.storage[shortURL]; ok && record.UUID == userID {
+			record.DeletedFlag = true
+		}
+	}
+
+	return nil
+}
+
+// DeleteURLsBatch помечает удаленными URL нескольких пользователей и
+// возвращает URL своих владельцев. Чужие и несуществующие URL пропускаются.
+func (s *MapStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := make([]models.DeletedURL, 0, len(urls))
+	for _, url := range urls {
+		if record, ok := s.storage[url.ShortURL]; ok && record.UUID == url.UserID {
+			record.DeletedFlag = true
+			deleted = append(deleted, url)
+		}
+	}
+
+	return deleted, nil
 }
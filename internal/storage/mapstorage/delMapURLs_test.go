@@ -1,16 +1,33 @@
 package mapstorage
 
 import (
+	"context"
+	"errors"
 	"testing"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 )
 
 // TestMapStorage_DeletedURLs - тестирует удаление urls из мапы.
 func TestMapStorage_DeletedURLs(t *testing.T) {
 	storage := NewMapURL()
-	var testURLs []string
-	testURLs = append(testURLs, "www")
-	err := storage.DeletedURLs(testURLs, "test")
-	if err == nil {
-		t.Error("no way")
+	if _, err := storage.SaveURL(context.Background(), "www", "https://example.com", "owner", models.LinkOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// чужой пользователь не может удалить ссылку
+	if err := storage.DeletedURLs(context.Background(), []string{"www"}, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := storage.GetURL(context.Background(), "www"); err != nil {
+		t.Errorf("url must not be deleted: %v", err)
+	}
+
+	if err := storage.DeletedURLs(context.Background(), []string{"www", "unknown"}, "owner"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := storage.GetURL(context.Background(), "www"); !errors.Is(err, errors2.ErrDeletedURL) {
+		t.Errorf("expected %v, got %v", errors2.ErrDeletedURL, err)
 	}
 }
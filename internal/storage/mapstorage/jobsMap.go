@@ -0,0 +1,79 @@
+package mapstorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// SaveDeletionJob сохраняет задание на удаление и возвращает его ID.
+// Задания живут только в памяти, как и ссылки.
+func (s *MapStorage) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobSeq++
+	job.ID = s.jobSeq
+	s.jobs[job.ID] = job.Clone()
+
+	return job.ID, nil
+}
+
+// GetDeletionJob возвращает задание на удаление по ID.
+func (s *MapStorage) GetDeletionJob(ctx context.Context, id int64) (*models.DeletionJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, errorscustom.ErrNotFound
+	}
+	job = job.Clone()
+
+	return &job, nil
+}
+
+// DueDeletionJobs возвращает задания на удаление, которые пора выполнить.
+func (s *MapStorage) DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return models.DueJobs(s.jobs, before, afterID, limit), nil
+}
+
+// ClaimDeletionJobs берет в работу задания, которые пора выполнить, до until.
+func (s *MapStorage) ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return models.ClaimJobs(s.jobs, before, until, limit), nil
+}
+
+// UpdateDeletionJobs сохраняет состояние заданий.
+func (s *MapStorage) UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range jobs {
+		if stored, ok := s.jobs[job.ID]; ok {
+			s.jobs[job.ID] = models.UpdateJob(stored, job)
+		}
+	}
+
+	return nil
+}
+
+// PurgeDeletionJobs удаляет задания, выполненные или отложенные раньше before.
+func (s *MapStorage) PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := models.FinishedJobs(s.jobs, before)
+	for _, id := range ids {
+		delete(s.jobs, id)
+	}
+
+	return len(ids), nil
+}
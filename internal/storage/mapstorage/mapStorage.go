@@ -1,52 +1,146 @@
 package mapstorage
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
 )
 
+// ErrURLNotFound - ошибка, если короткий URL не найден.
+var ErrURLNotFound = errors2.ErrNotFound
+
+// ErrEmptyURL - ошибка, если передан пустой URL.
+var ErrEmptyURL = errors.New("URL is empty")
+
 // IMapStorage - интерфейс хранилища URL-адресов.
 type IMapStorage interface {
-	SaveURL(shortURL, url, userID string) error
-	GetURL(shortURL string) (string, error)
+	SaveURL(ctx context.Context, shortURL, url, userID string, opts models.LinkOptions) (string, error)
+	GetURL(ctx context.Context, shortURL string) (*models.Storage, error)
 	Close() error
 }
 
 // MapStorage - хранилище URL-адресов.
 type MapStorage struct {
-	storage map[string]string
-	mu      sync.RWMutex
+	// storage - короткая ссылка -> запись.
+	storage map[string]*models.Storage
+	// originals - оригинальный URL -> короткая ссылка.
+	originals map[string]string
+	// users - пользователь -> его короткие ссылки в порядке сохранения.
+	users map[string][]string
+	// clicks - короткая ссылка -> переходы по ней.
+	clicks map[string][]models.Click
+	// jobs - задания на удаление, jobSeq - последний выданный ID задания.
+	jobs   map[int64]models.DeletionJob
+	jobSeq int64
+	mu     sync.RWMutex
+
+	// codes - стратегия генерации ссылок для SaveSlice, seq - счетчик для нее.
+	codes codegen.CodeGenerator
+	seq   atomic.Uint64
 }
 
 // NewMapURL возвращает новый хранилище URL-адресов.
 func NewMapURL() *MapStorage {
 	return &MapStorage{
-		storage: make(map[string]string),
+		storage:   make(map[string]*models.Storage),
+		originals: make(map[string]string),
+		users:     make(map[string][]string),
+		clicks:    make(map[string][]models.Click),
+		jobs:      make(map[int64]models.DeletionJob),
+		codes:     codegen.Default(),
 	}
 }
 
+// SetCodeGenerator задает стратегию генерации коротких ссылок для SaveSlice.
+func (s *MapStorage) SetCodeGenerator(gen codegen.CodeGenerator) {
+	s.codes = gen
+}
+
+// NextID возвращает следующее значение счетчика коротких ссылок.
+func (s *MapStorage) NextID(ctx context.Context) (uint64, error) {
+	return s.seq.Add(1), nil
+}
+
 // SaveURL сохраняет URL в хранилище.
-func (s *MapStorage) SaveURL(shortURL, url, userID string) error {
+// Для уже сохраненного URL возвращает его короткую ссылку и ErrConflict.
+func (s *MapStorage) SaveURL(ctx context.Context, shortURL, url, userID string, opts models.LinkOptions) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if url == "" {
-		return errors.New("URL is empty")
+		return "", ErrEmptyURL
 	}
 
-	s.storage[shortURL] = url
-	return nil
+	if existing, ok := s.originals[url]; ok {
+		return existing, errors2.ErrConflict
+	}
+	if _, ok := s.storage[shortURL]; ok {
+		return "", errors2.ErrShortURLCollision
+	}
+
+	s.save(shortURL, url, userID, opts)
+	return shortURL, nil
+}
+
+// save добавляет запись во все индексы. Вызывается под блокировкой.
+func (s *MapStorage) save(shortURL, url, userID string, opts models.LinkOptions) {
+	s.storage[shortURL] = &models.Storage{
+		UUID:         userID,
+		ShortURL:     shortURL,
+		OriginalURL:  url,
+		ExpiresAt:    opts.ExpiresAt,
+		ClicksLeft:   opts.ClicksLeft(),
+		PasswordHash: opts.PasswordHash,
+	}
+	s.originals[url] = shortURL
+	if userID != "" {
+		s.users[userID] = append(s.users[userID], shortURL)
+	}
 }
 
-// GetURL возвращает URL из хранилища.
-func (s *MapStorage) GetURL(shortURL string) (string, error) {
+// GetURL возвращает копию записи из хранилища.
+func (s *MapStorage) GetURL(ctx context.Context, shortURL string) (*models.Storage, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if _, ok := s.storage[shortURL]; !ok {
-		return "", errors.New("URL not found")
+	record, ok := s.storage[shortURL]
+	if !ok {
+		return nil, ErrURLNotFound
+	}
+	if record.DeletedFlag {
+		return nil, errors2.ErrDeletedURL
+	}
+	result := *record
+	return &result, nil
+}
+
+// UseClick списывает переход по ссылке с ограничением и возвращает остаток.
+// Для ссылки без ограничения возвращает -1.
+func (s *MapStorage) UseClick(ctx context.Context, shortURL string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.storage[shortURL]
+	if !ok {
+		return 0, ErrURLNotFound
+	}
+	if record.DeletedFlag {
+		return 0, errors2.ErrDeletedURL
+	}
+	if record.ClicksLeft == nil {
+		return -1, nil
+	}
+	if *record.ClicksLeft <= 0 {
+		return 0, errors2.ErrExhaustedURL
 	}
-	return s.storage[shortURL], nil
+
+	// копии записи из GetURL делят указатель, поэтому остаток заменяется, а не меняется на месте
+	clicks := *record.ClicksLeft - 1
+	record.ClicksLeft = &clicks
+	return clicks, nil
 }
 
 // Close закрывает хранилище.
@@ -55,16 +149,73 @@ func (s *MapStorage) Close() error {
 }
 
 // Ping проверяет соединение с хранилищем.
-func (s *MapStorage) Ping() error {
+func (s *MapStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
-// SaveSliceOfDB сохраняет срез URL в хранилище.
-func (s *MapStorage) SaveSlice(urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
-	return nil, nil
+// SaveSlice сохраняет срез URL в хранилище.
+// Для уже сохраненных URL возвращается существующая короткая ссылка,
+// занятая пользовательская ссылка отменяет пакет целиком.
+func (s *MapStorage) SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// сначала создаем все короткие ссылки, чтобы сохранить пакет целиком или не сохранять вовсе
+	created := make(map[string]string)
+	reserved := make(map[string]bool)
+	resultMultipleURL := make([]models.ResultMultipleURL, 0, len(urls))
+	for _, req := range urls {
+		shortURL, ok := s.originals[req.OriginalURL]
+		if !ok {
+			shortURL, ok = created[req.OriginalURL]
+		}
+		if !ok {
+			encodeURL, err := codegen.UniqueOrAlias(ctx, s.codes, req.OriginalURL, req.CustomAlias, func(shortURL string) error {
+				if _, ok := s.storage[shortURL]; ok || reserved[shortURL] {
+					return errors2.ErrShortURLCollision
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			created[req.OriginalURL] = encodeURL
+			reserved[encodeURL] = true
+			shortURL = encodeURL
+		}
+
+		resultMultipleURL = append(resultMultipleURL, models.ResultMultipleURL{
+			CorrelationID: req.CorrelationID,
+			ShortURL:      baseURL + "/" + shortURL,
+		})
+	}
+
+	for _, req := range urls {
+		if shortURL, ok := created[req.OriginalURL]; ok {
+			s.save(shortURL, req.OriginalURL, userID, req.LinkOptions())
+			delete(created, req.OriginalURL)
+		}
+	}
+
+	return resultMultipleURL, nil
 }
 
-// GetAllURL возвращает срез URL из хранилища.
-func (s *MapStorage) GetAllURL(userID, baseURL string) ([]*models.UserURLs, error) {
-	return nil, errors.New("not use GetAllURL in map")
+// GetAllURL возвращает срез неудаленных URL пользователя из хранилища.
+func (s *MapStorage) GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var userURLs []*models.UserURLs
+	for _, shortURL := range s.users[userID] {
+		record := s.storage[shortURL]
+		if record.DeletedFlag {
+			continue
+		}
+		userURLs = append(userURLs, &models.UserURLs{
+			ShortURL:    fmt.Sprintf("%s/%s", baseURL, shortURL),
+			OriginalURL: record.OriginalURL,
+		})
+	}
+
+	return userURLs, nil
 }
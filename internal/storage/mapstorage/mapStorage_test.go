@@ -1,19 +1,23 @@
 package mapstorage
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMapStorage_SaveURL(t *testing.T) {
 	t.Run("successful_saving", func(t *testing.T) {
 		s := NewMapURL()
-		err := s.SaveURL("test", "", "")
+		_, err := s.SaveURL(context.Background(), "test", "", "", models.LinkOptions{})
 		assert.NotNil(t, err)
 		assert.Equal(t, errors.New("URL is empty"), err)
-		err = s.SaveURL("test", "https://example.com", "")
+		_, err = s.SaveURL(context.Background(), "test", "https://example.com", "", models.LinkOptions{})
 		assert.Nil(t, err)
 	})
 }
@@ -21,13 +25,74 @@ func TestMapStorage_SaveURL(t *testing.T) {
 func TestMapStorage_GetURL(t *testing.T) {
 	t.Run("successful_getting", func(t *testing.T) {
 		s := NewMapURL()
-		err := s.SaveURL("test", "https://example.com", "")
+		_, err := s.SaveURL(context.Background(), "test", "https://example.com", "", models.LinkOptions{})
 		assert.Nil(t, err)
-		_, err = s.GetURL("")
+		_, err = s.GetURL(context.Background(), "")
 		assert.NotNil(t, err)
 		assert.Equal(t, errors.New("URL not found"), err)
-		url, err := s.GetURL("test")
+		record, err := s.GetURL(context.Background(), "test")
 		assert.Nil(t, err)
-		assert.Equal(t, "https://example.com", url)
+		assert.Equal(t, "https://example.com", record.OriginalURL)
 	})
 }
+
+func TestMapStorage_SaveSlice(t *testing.T) {
+	s := NewMapURL()
+	_, err := s.SaveURL(context.Background(), "test", "https://example.com", "user", models.LinkOptions{})
+	assert.Nil(t, err)
+
+	result, err := s.SaveSlice(context.Background(), []models.MultipleURL{
+		{CorrelationID: "1", OriginalURL: "https://example.com"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev"},
+		{CorrelationID: "3", OriginalURL: "https://go.dev"},
+	}, "http://localhost:8080", "user")
+	assert.Nil(t, err)
+	assert.Len(t, result, 3)
+	assert.Equal(t, "http://localhost:8080/test", result[0].ShortURL)
+	assert.Equal(t, result[1].ShortURL, result[2].ShortURL)
+
+	urls, err := s.GetAllURL(context.Background(), "user", "http://localhost:8080")
+	assert.Nil(t, err)
+	assert.Len(t, urls, 2)
+}
+
+func TestMapStorage_GetAllURL(t *testing.T) {
+	s := NewMapURL()
+	_, err := s.SaveURL(context.Background(), "a", "https://a.com", "user", models.LinkOptions{})
+	assert.Nil(t, err)
+	_, err = s.SaveURL(context.Background(), "b", "https://b.com", "other", models.LinkOptions{})
+	assert.Nil(t, err)
+
+	urls, err := s.GetAllURL(context.Background(), "user", "http://localhost:8080")
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.UserURLs{{ShortURL: "http://localhost:8080/a", OriginalURL: "https://a.com"}}, urls)
+
+	urls, err = s.GetAllURL(context.Background(), "unknown", "http://localhost:8080")
+	assert.Nil(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestMapStorage_Concurrent(t *testing.T) {
+	s := NewMapURL()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			url := fmt.Sprintf("https://example.com/%d", i)
+			short := fmt.Sprintf("s%d", i)
+			_, err := s.SaveURL(context.Background(), short, url, "user", models.LinkOptions{})
+			assert.Nil(t, err)
+			_, err = s.GetURL(context.Background(), short)
+			assert.Nil(t, err)
+			_, _ = s.CheckURL(context.Background(), url)
+			_, _ = s.GetAllURL(context.Background(), "user", "")
+			assert.Nil(t, s.DeletedURLs(context.Background(), []string{short}, "user"))
+		}(i)
+	}
+	wg.Wait()
+
+	urls, err := s.GetAllURL(context.Background(), "user", "")
+	assert.Nil(t, err)
+	assert.Empty(t, urls)
+}
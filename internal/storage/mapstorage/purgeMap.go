@@ -0,0 +1,54 @@
+package mapstorage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// PurgeExpired удаляет из хранилища не более limit ссылок, истекших до before.
+// Возвращает количество удаленных ссылок.
+func (s *MapStorage) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	return s.purge(limit, func(record *models.Storage) bool {
+		return record.Expired(before)
+	}), nil
+}
+
+// PurgeDeleted удаляет из хранилища не более limit ссылок, помеченных удаленными.
+// Возвращает количество удаленных ссылок.
+func (s *MapStorage) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	return s.purge(limit, func(record *models.Storage) bool {
+		return record.DeletedFlag
+	}), nil
+}
+
+// purge удаляет не более limit ссылок, подходящих под match, вместе с переходами.
+func (s *MapStorage) purge(limit int, match func(record *models.Storage) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for shortURL, record := range s.storage {
+		if purged >= limit {
+			break
+		}
+		if !match(record) {
+			continue
+		}
+		delete(s.storage, shortURL)
+		delete(s.clicks, shortURL)
+		if s.originals[record.OriginalURL] == shortURL {
+			delete(s.originals, record.OriginalURL)
+		}
+		if record.UUID != "" {
+			s.users[record.UUID] = slices.DeleteFunc(s.users[record.UUID], func(u string) bool {
+				return u == shortURL
+			})
+		}
+		purged++
+	}
+
+	return purged
+}
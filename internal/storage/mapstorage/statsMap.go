@@ -0,0 +1,20 @@
+package mapstorage
+
+import (
+	"context"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// GetStats возвращает число неудаленных ссылок и их владельцев.
+func (s *MapStorage) GetStats(ctx context.Context) (*models.InternalStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var counter models.StatsCounter
+	for _, record := range s.storage {
+		counter.Add(record)
+	}
+
+	return counter.Stats(), nil
+}
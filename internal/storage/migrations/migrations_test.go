@@ -0,0 +1,202 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		fsys        fstest.MapFS
+		expected    []int64
+		expectedErr bool
+	}{
+		{
+			name: "successful",
+			fsys: fstest.MapFS{
+				"db/0002_second.up.sql":   {Data: []byte("B")},
+				"db/0002_second.down.sql": {Data: []byte("b")},
+				"db/0001_first.up.sql":    {Data: []byte("A")},
+			},
+			expected: []int64{1, 2},
+		},
+		{
+			name: "bad_name",
+			fsys: fstest.MapFS{
+				"db/first.up.sql": {Data: []byte("A")},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "no_up",
+			fsys: fstest.MapFS{
+				"db/0001_first.down.sql": {Data: []byte("a")},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "empty",
+			fsys: fstest.MapFS{
+				"db/README": {Data: []byte("")},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.fsys, "db")
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var versions []int64
+			for _, m := range migrations {
+				versions = append(versions, m.Version)
+			}
+			assert.Equal(t, tt.expected, versions)
+		})
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	postgres, err := Load(files, Postgres.Name)
+	require.NoError(t, err)
+
+	for _, dialect := range []Dialect{Postgres, SQLite} {
+		t.Run(dialect.Name, func(t *testing.T) {
+			migrations, err := Load(files, dialect.Name)
+			require.NoError(t, err)
+
+			// диалекты описывают одну и ту же историю схемы
+			require.Len(t, migrations, len(postgres))
+			for i, m := range migrations {
+				assert.Equal(t, postgres[i].Version, m.Version)
+				assert.Equal(t, postgres[i].Name, m.Name)
+				assert.NotEmpty(t, m.Down, "migration %d_%s has no down script", m.Version, m.Name)
+			}
+		})
+	}
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return &Migrator{
+		db:      db,
+		dialect: Postgres,
+		migrations: []Migration{
+			{Version: 1, Name: "first", Up: "CREATE first", Down: "DROP first"},
+			{Version: 2, Name: "second", Up: "CREATE second", Down: "DROP second"},
+		},
+	}, mock
+}
+
+func expectLocked(mock sqlmock.Sqlmock, applied ...int64) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, v := range applied {
+		rows.AddRow(v, time.Now())
+	}
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(rows)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	t.Run("unknown_version", func(t *testing.T) {
+		m, mock := newTestMigrator(t)
+		expectLocked(mock, 1, 3)
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		// миграция 2 не применяется поверх схемы более новой версии
+		applied, err := m.Up(context.Background())
+		assert.ErrorIs(t, err, ErrUnknownVersion)
+		assert.Zero(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skip_applied", func(t *testing.T) {
+		m, mock := newTestMigrator(t)
+		expectLocked(mock, 1)
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE second").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(2, "second").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		applied, err := m.Up(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback_on_error", func(t *testing.T) {
+		m, mock := newTestMigrator(t)
+		expectLocked(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE first").WillReturnError(errors.New("syntax error"))
+		mock.ExpectRollback()
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		applied, err := m.Up(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, 0, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Down(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectLocked(mock, 1, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP second").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rolledBack, err := m.Down(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Status(t *testing.T) {
+	t.Run("successful", func(t *testing.T) {
+		m, mock := newTestMigrator(t)
+		expectLocked(mock, 1)
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		statuses, err := m.Status(context.Background())
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	})
+
+	t.Run("unknown_version", func(t *testing.T) {
+		m, mock := newTestMigrator(t)
+		expectLocked(mock, 1, 2, 3)
+		mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := m.Status(context.Background())
+		assert.ErrorIs(t, err, ErrUnknownVersion)
+	})
+}
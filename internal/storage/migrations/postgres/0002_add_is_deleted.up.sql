@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_deleted BOOL NOT NULL DEFAULT FALSE;
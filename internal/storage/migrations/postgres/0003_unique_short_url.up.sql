@@ -0,0 +1,5 @@
+-- дубли короткой ссылки получают новый код: первая сохраненная запись
+-- оставляет свой, остальные - с суффиксом .<id>, которого нет у алиасов
+UPDATE urls SET short_url = short_url || '.' || id
+WHERE EXISTS (SELECT 1 FROM urls AS first WHERE first.short_url = urls.short_url AND first.id < urls.id);
+CREATE UNIQUE INDEX IF NOT EXISTS urls_short_url_key ON urls (short_url);
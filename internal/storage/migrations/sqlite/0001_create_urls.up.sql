@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS urls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    original_url TEXT NOT NULL,
+    short_url TEXT NOT NULL,
+    user_id TEXT,
+    UNIQUE (original_url)
+);
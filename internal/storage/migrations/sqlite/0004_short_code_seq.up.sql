@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS short_code_seq (id INTEGER NOT NULL);
+INSERT INTO short_code_seq (id) SELECT 0 WHERE NOT EXISTS (SELECT 1 FROM short_code_seq);
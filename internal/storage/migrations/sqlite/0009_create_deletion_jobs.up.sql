@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS deletion_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    short_urls TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'queued',
+    deleted_urls TEXT NOT NULL DEFAULT '[]',
+    skipped_urls TEXT NOT NULL DEFAULT '[]',
+    finished_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS deletion_jobs_next_attempt_at_idx ON deletion_jobs (next_attempt_at) WHERE status NOT IN ('done', 'failed');
+CREATE INDEX IF NOT EXISTS deletion_jobs_finished_at_idx ON deletion_jobs (finished_at) WHERE finished_at IS NOT NULL;
@@ -0,0 +1,17 @@
+package sqlitestorage
+
+import (
+	"context"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+)
+
+// CheckURL - проверяет есть ли в базе уже данный URL.
+func (s *SQLiteStorage) CheckURL(ctx context.Context, originalURL string) (string, error) {
+	shortURL, err := checkURL(ctx, s.storage, originalURL)
+	if err != nil || shortURL == "" {
+		return "", err
+	}
+
+	return shortURL, errors2.ErrConflict
+}
@@ -0,0 +1,20 @@
+package sqlitestorage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) service.Storage {
+		storage, err := NewSQLiteStorage(Scheme + filepath.Join(t.TempDir(), "shortener.db"))
+		if err != nil {
+			t.Fatalf("ошибка открытия базы: %v", err)
+		}
+		t.Cleanup(func() { storage.Close() })
+		return storage
+	})
+}
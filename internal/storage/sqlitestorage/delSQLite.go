@@ -0,0 +1,92 @@
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// deleteBatchSize - число ссылок в одном UPDATE пакетного удаления.
+// По 2 параметра на ссылку, чтобы не превысить ограничение SQLite
+// в 32766 параметров.
+const deleteBatchSize = 1000
+
+// DeletedURLs помечает удаленными ссылки пользователя.
+// Чужие и несуществующие ссылки пропускаются.
+func (s *SQLiteStorage) DeletedURLs(ctx context.Context, urls []string, userID string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	// SQLite не поддерживает массивы, поэтому список передается через IN (?, ?, ...).
+	args := make([]any, 0, len(urls)+1)
+	args = append(args, userID)
+	for _, url := range urls {
+		args = append(args, url)
+	}
+
+	query := "UPDATE urls SET is_deleted = TRUE WHERE user_id = ? AND short_url IN (?" +
+		strings.Repeat(", ?", len(urls)-1) + ")"
+
+	_, err := s.storage.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteURLsBatch помечает удаленными ссылки нескольких пользователей
+// в одной транзакции пачками по deleteBatchSize и возвращает ссылки
+// своих владельцев. Чужие и несуществующие ссылки пропускаются.
+func (s *SQLiteStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	deleted := make([]models.DeletedURL, 0, len(urls))
+	if len(urls) == 0 {
+		return deleted, nil
+	}
+
+	tx, err := s.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(urls); start += deleteBatchSize {
+		batch := urls[start:min(start+deleteBatchSize, len(urls))]
+		if deleted, err = deleteURLs(ctx, tx, batch, deleted); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// deleteURLs помечает удаленными пачку ссылок одним запросом и дописывает
+// в deleted ссылки своих владельцев, включая удаленные раньше.
+func deleteURLs(ctx context.Context, tx *sql.Tx, urls, deleted []models.DeletedURL) ([]models.DeletedURL, error) {
+	// пары (short_url, user_id) сравниваются как значения строк
+	args := make([]any, 0, 2*len(urls))
+	for _, url := range urls {
+		args = append(args, url.ShortURL, url.UserID)
+	}
+	query := "UPDATE urls SET is_deleted = TRUE WHERE (short_url, user_id) IN (VALUES (?, ?)" +
+		strings.Repeat(", (?, ?)", len(urls)-1) + ") RETURNING short_url, user_id"
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url models.DeletedURL
+		if err = rows.Scan(&url.ShortURL, &url.UserID); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, url)
+	}
+
+	return deleted, rows.Err()
+}
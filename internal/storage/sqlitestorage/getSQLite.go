@@ -0,0 +1,66 @@
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// GetURL возвращает запись по короткой ссылке.
+func (s *SQLiteStorage) GetURL(ctx context.Context, shortURL string) (*models.Storage, error) {
+	var (
+		record    models.Storage
+		user      sql.NullString
+		expiresAt sql.NullInt64
+		clicks    sql.NullInt64
+	)
+
+	err := s.storage.QueryRowContext(ctx,
+		"SELECT short_url, original_url, user_id, is_deleted, expires_at, clicks_left, password_hash FROM urls WHERE short_url = $1",
+		shortURL).Scan(&record.ShortURL, &record.OriginalURL, &user, &record.DeletedFlag, &expiresAt, &clicks, &record.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %w", errors2.ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.DeletedFlag {
+		return nil, errors2.ErrDeletedURL
+	}
+	record.UUID = user.String
+	record.ExpiresAt = fromMillis(expiresAt)
+	if clicks.Valid {
+		left := int(clicks.Int64)
+		record.ClicksLeft = &left
+	}
+
+	return &record, nil
+}
+
+// GetAllURL возвращает все неудаленные ссылки пользователя.
+func (s *SQLiteStorage) GetAllURL(ctx context.Context, userID, baseURL string) ([]*models.UserURLs, error) {
+	rows, err := s.storage.QueryContext(ctx,
+		"SELECT short_url, original_url FROM urls WHERE user_id = $1 AND is_deleted = FALSE ORDER BY id",
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userURLs []*models.UserURLs
+	for rows.Next() {
+		var userURL models.UserURLs
+		if err = rows.Scan(&userURL.ShortURL, &userURL.OriginalURL); err != nil {
+			return nil, err
+		}
+		userURL.ShortURL = fmt.Sprintf("%s/%s", baseURL, userURL.ShortURL)
+		userURLs = append(userURLs, &userURL)
+	}
+
+	return userURLs, rows.Err()
+}
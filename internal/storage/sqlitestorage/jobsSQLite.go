@@ -0,0 +1,195 @@
+package sqlitestorage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// jobColumns - столбцы задания в порядке scanJob.
+const jobColumns = "id, user_id, short_urls, status, attempts, next_attempt_at, last_error, deleted_urls, skipped_urls, finished_at"
+
+// SaveDeletionJob сохраняет задание на удаление и возвращает его ID.
+// Ссылки задания хранятся JSON-массивами, моменты - в миллисекундах.
+func (s *SQLiteStorage) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	urls, err := marshalURLs(job.URLs)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = s.storage.QueryRowContext(ctx,
+		"INSERT INTO deletion_jobs (user_id, short_urls, status, attempts, next_attempt_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		job.UserID, urls, job.Status, job.Attempts, job.NextAttempt.UnixMilli()).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetDeletionJob возвращает задание на удаление по ID.
+func (s *SQLiteStorage) GetDeletionJob(ctx context.Context, id int64) (*models.DeletionJob, error) {
+	row := s.storage.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM deletion_jobs WHERE id = $1", id)
+
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errorscustom.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// DueDeletionJobs возвращает задания на удаление, которые пора выполнить.
+func (s *SQLiteStorage) DueDeletionJobs(ctx context.Context, before time.Time, afterID int64, limit int) ([]models.DeletionJob, error) {
+	return s.queryJobs(ctx,
+		"SELECT "+jobColumns+" FROM deletion_jobs"+
+			" WHERE status NOT IN ('done', 'failed') AND next_attempt_at <= $1 AND id > $2 ORDER BY id LIMIT $3",
+		before.UnixMilli(), afterID, limit)
+}
+
+// ClaimDeletionJobs берет в работу задания, которые пора выполнить, до until.
+// RETURNING в SQLite не сохраняет порядок, поэтому задания сортируются после.
+func (s *SQLiteStorage) ClaimDeletionJobs(ctx context.Context, before, until time.Time, limit int) ([]models.DeletionJob, error) {
+	jobs, err := s.queryJobs(ctx,
+		"UPDATE deletion_jobs SET next_attempt_at = $2 WHERE id IN ("+
+			"SELECT id FROM deletion_jobs WHERE status NOT IN ('done', 'failed') AND next_attempt_at <= $1"+
+			" ORDER BY id LIMIT $3) RETURNING "+jobColumns,
+		before.UnixMilli(), until.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(jobs, func(a, b models.DeletionJob) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return jobs, nil
+}
+
+// queryJobs выполняет запрос, который возвращает задания с jobColumns.
+func (s *SQLiteStorage) queryJobs(ctx context.Context, query string, args ...any) ([]models.DeletionJob, error) {
+	rows, err := s.storage.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]models.DeletionJob, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// UpdateDeletionJobs сохраняет состояние заданий в одной транзакции.
+func (s *SQLiteStorage) UpdateDeletionJobs(ctx context.Context, jobs []models.DeletionJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	tx, err := s.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"UPDATE deletion_jobs SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4,"+
+			" deleted_urls = $5, skipped_urls = $6, finished_at = $7 WHERE id = $8")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, job := range jobs {
+		deleted, err := marshalURLs(job.Deleted)
+		if err != nil {
+			return err
+		}
+		skipped, err := marshalURLs(job.Skipped)
+		if err != nil {
+			return err
+		}
+
+		var finishedAt sql.NullInt64
+		if job.FinishedAt != nil {
+			finishedAt = sql.NullInt64{Int64: job.FinishedAt.UnixMilli(), Valid: true}
+		}
+
+		_, err = stmt.ExecContext(ctx, job.Status, job.Attempts, job.NextAttempt.UnixMilli(), job.LastError,
+			deleted, skipped, finishedAt, job.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletionJobs удаляет задания, выполненные или отложенные раньше before.
+func (s *SQLiteStorage) PurgeDeletionJobs(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.storage.ExecContext(ctx, "DELETE FROM deletion_jobs WHERE finished_at < $1", before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// scanJob читает задание из строки с jobColumns.
+func scanJob(row interface{ Scan(dest ...any) error }) (models.DeletionJob, error) {
+	var (
+		job                    models.DeletionJob
+		urls, deleted, skipped string
+		nextAttempt            int64
+		finishedAt             sql.NullInt64
+	)
+	err := row.Scan(&job.ID, &job.UserID, &urls, &job.Status, &job.Attempts, &nextAttempt,
+		&job.LastError, &deleted, &skipped, &finishedAt)
+	if err != nil {
+		return models.DeletionJob{}, err
+	}
+
+	for _, field := range []struct {
+		data string
+		dest *[]string
+	}{{urls, &job.URLs}, {deleted, &job.Deleted}, {skipped, &job.Skipped}} {
+		if err = json.Unmarshal([]byte(field.data), field.dest); err != nil {
+			return models.DeletionJob{}, fmt.Errorf("deletion job %d: %w", job.ID, err)
+		}
+	}
+	job.NextAttempt = time.UnixMilli(nextAttempt).UTC()
+	if finishedAt.Valid {
+		finished := time.UnixMilli(finishedAt.Int64).UTC()
+		job.FinishedAt = &finished
+	}
+
+	return job, nil
+}
+
+// marshalURLs кодирует ссылки JSON-массивом, пустой список - как [].
+func marshalURLs(urls []string) (string, error) {
+	if urls == nil {
+		urls = []string{}
+	}
+
+	data, err := json.Marshal(urls)
+	return string(data), err
+}
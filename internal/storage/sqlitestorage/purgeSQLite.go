@@ -0,0 +1,34 @@
+package sqlitestorage
+
+import (
+	"context"
+	"time"
+)
+
+// PurgeExpired удаляет не более limit ссылок, истекших до before.
+// Возвращает количество удаленных ссылок.
+func (s *SQLiteStorage) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	result, err := s.storage.ExecContext(ctx,
+		"DELETE FROM urls WHERE id IN (SELECT id FROM urls WHERE expires_at <= $1 LIMIT $2)",
+		before.UnixMilli(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// PurgeDeleted удаляет не более limit ссылок, помеченных удаленными.
+// Возвращает количество удаленных ссылок.
+func (s *SQLiteStorage) PurgeDeleted(ctx context.Context, limit int) (int, error) {
+	result, err := s.storage.ExecContext(ctx,
+		"DELETE FROM urls WHERE id IN (SELECT id FROM urls WHERE is_deleted = TRUE LIMIT $1)",
+		limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
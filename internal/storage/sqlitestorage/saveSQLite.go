@@ -0,0 +1,147 @@
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	errors2 "github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+)
+
+// querier - общее у *sql.DB и *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SaveURL сохраняет URL в базе данных.
+// Для уже сохраненного URL возвращает его короткую ссылку и ErrConflict.
+// Вставка и поиск существующей ссылки идут в одной транзакции, которая
+// сразу захватывает запись, поэтому конкурентная вставка между ними невозможна.
+func (s *SQLiteStorage) SaveURL(ctx context.Context, shortURL, originalURL, userID string, opts models.LinkOptions) (string, error) {
+	tx, err := s.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	existing, err := checkURL(ctx, tx, originalURL)
+	if err != nil {
+		return "", err
+	}
+	if existing != "" {
+		return existing, errors2.ErrConflict
+	}
+
+	if err = s.insertURL(ctx, tx, shortURL, originalURL, userID, opts); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return shortURL, nil
+}
+
+// SaveSlice сохраняет множество URL в одной транзакции.
+// Для уже сохраненных URL возвращается существующая короткая ссылка.
+func (s *SQLiteStorage) SaveSlice(ctx context.Context, urls []models.MultipleURL, baseURL, userID string) ([]models.ResultMultipleURL, error) {
+	tx, err := s.storage.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resultMultipleURL := make([]models.ResultMultipleURL, 0, len(urls))
+	for _, req := range urls {
+		// повторы внутри пакета находятся этим же запросом,
+		// так как транзакция видит свои записи
+		shortURL, err := checkURL(ctx, tx, req.OriginalURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if shortURL == "" {
+			// нарушение уникальности откатывает только оператор, а не транзакцию,
+			// поэтому при коллизии можно повторить вставку с новой ссылкой
+			shortURL, err = codegen.UniqueOrAlias(ctx, s.codes, req.OriginalURL, req.CustomAlias, func(shortURL string) error {
+				return s.insertURL(ctx, tx, shortURL, req.OriginalURL, userID, req.LinkOptions())
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		resultMultipleURL = append(resultMultipleURL, models.ResultMultipleURL{
+			CorrelationID: req.CorrelationID,
+			ShortURL:      baseURL + "/" + shortURL,
+		})
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return resultMultipleURL, nil
+}
+
+// insertURL добавляет запись в таблицу urls и сохраняет счетчик коротких ссылок.
+// Вызывающий заранее проверяет оригинальный URL, поэтому нарушение
+// уникальности - это занятая короткая ссылка.
+func (s *SQLiteStorage) insertURL(ctx context.Context, q querier, shortURL, originalURL, userID string, opts models.LinkOptions) error {
+	var user *string
+	if userID != "" {
+		user = &userID
+	}
+
+	_, err := q.ExecContext(ctx,
+		"INSERT INTO urls (original_url, short_url, user_id, expires_at, clicks_left, password_hash) VALUES ($1, $2, $3, $4, $5, $6)",
+		originalURL, shortURL, user, toMillis(opts.ExpiresAt), opts.ClicksLeft(), opts.PasswordHash)
+	if isUniqueViolation(err) {
+		return errors2.ErrShortURLCollision
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx,
+		"UPDATE short_code_seq SET id = $1 WHERE id < $1",
+		s.seq.Load())
+	return err
+}
+
+// checkURL возвращает короткую ссылку для URL или пустую строку, если его нет.
+func checkURL(ctx context.Context, q querier, originalURL string) (string, error) {
+	var shortURL string
+
+	err := q.QueryRowContext(ctx,
+		"SELECT short_url FROM urls WHERE original_url = $1",
+		originalURL).Scan(&shortURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return shortURL, err
+}
+
+// toMillis переводит момент истечения в миллисекунды Unix для колонки expires_at.
+func toMillis(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	ms := t.UnixMilli()
+	return &ms
+}
+
+// fromMillis переводит значение колонки expires_at обратно во время.
+func fromMillis(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := time.UnixMilli(ms.Int64)
+	return &t
+}
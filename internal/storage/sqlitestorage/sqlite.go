@@ -0,0 +1,127 @@
+// Package sqlitestorage - хранилище во встроенной базе SQLite.
+//
+// Подходит для установки на одном узле: данные переживают перезапуск,
+// а отдельный сервер базы данных не нужен. Используется драйвер на чистом Go,
+// поэтому сборка не требует cgo.
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/migrations"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Scheme - префикс DSN, по которому выбирается хранилище SQLite,
+// например sqlite:///var/lib/shortener.db.
+const Scheme = "sqlite://"
+
+// busyTimeout - сколько миллисекунд ждать освобождения базы другим писателем.
+const busyTimeout = "5000"
+
+// ErrInvalidDSN - ошибка, если в DSN не указан путь к файлу базы.
+var ErrInvalidDSN = errors.New("sqlite DSN must be sqlite://<path>")
+
+// SQLiteStorage - хранилище в SQLite.
+type SQLiteStorage struct {
+	storage *sql.DB
+
+	// codes - стратегия генерации ссылок для SaveSlice, seq - счетчик для нее.
+	// Счетчик живет в памяти, так как генерация идет внутри транзакции записи,
+	// а в таблицу short_code_seq попадает вместе со следующей записью.
+	codes codegen.CodeGenerator
+	seq   atomic.Uint64
+}
+
+// IsDSN проверяет, что DSN указывает на SQLite.
+func IsDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, Scheme)
+}
+
+// Open открывает базу SQLite по DSN вида sqlite://<path>.
+// Включает журнал WAL, чтобы чтения не блокировались записью,
+// и ожидание занятой базы вместо немедленной ошибки.
+func Open(dsn string) (*sql.DB, error) {
+	path, ok := strings.CutPrefix(dsn, Scheme)
+	if !ok || path == "" {
+		return nil, ErrInvalidDSN
+	}
+
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout("+busyTimeout+")")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(ON)")
+	params.Set("_txlock", "immediate")
+
+	return sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+}
+
+// NewSQLiteStorage - создает хранилище SQLite и применяет миграции.
+func NewSQLiteStorage(dsn string) (*SQLiteStorage, error) {
+	db, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SQLiteStorage{
+		storage: db,
+		codes:   codegen.Default(),
+	}
+	if err = s.Migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	var seq uint64
+	if err = db.QueryRow("SELECT id FROM short_code_seq").Scan(&seq); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.seq.Store(seq)
+
+	return s, nil
+}
+
+// SetCodeGenerator задает стратегию генерации коротких ссылок для SaveSlice.
+func (s *SQLiteStorage) SetCodeGenerator(gen codegen.CodeGenerator) {
+	s.codes = gen
+}
+
+// NextID возвращает следующее значение счетчика коротких ссылок.
+func (s *SQLiteStorage) NextID(ctx context.Context) (uint64, error) {
+	return s.seq.Add(1), nil
+}
+
+// Migrate применяет непримененные миграции схемы.
+func (s *SQLiteStorage) Migrate(ctx context.Context) error {
+	migrator, err := migrations.NewMigrator(s.storage, migrations.SQLite)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(ctx)
+	return err
+}
+
+// Ping проверяет соединение с базой данных.
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	return s.storage.PingContext(ctx)
+}
+
+// Close закрывает базу данных.
+func (s *SQLiteStorage) Close() error {
+	return s.storage.Close()
+}
+
+// isUniqueViolation проверяет, что ошибка - нарушение уникальности.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
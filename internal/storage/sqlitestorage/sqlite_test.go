@@ -0,0 +1,145 @@
+package sqlitestorage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDSN(t *testing.T) {
+	assert.True(t, IsDSN("sqlite:///var/lib/shortener.db"))
+	assert.True(t, IsDSN("sqlite://shortener.db"))
+	assert.False(t, IsDSN("postgres://localhost:5432/shortener"))
+	assert.False(t, IsDSN(""))
+}
+
+func TestOpen_InvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"sqlite://", "postgres://localhost/db"} {
+		_, err := Open(dsn)
+		assert.ErrorIs(t, err, ErrInvalidDSN, dsn)
+	}
+}
+
+func TestNewSQLiteStorage_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dsn := Scheme + filepath.Join(t.TempDir(), "shortener.db")
+
+	storage, err := NewSQLiteStorage(dsn)
+	require.NoError(t, err)
+	_, err = storage.SaveURL(ctx, "short1", "https://example.com/1", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = storage.SaveURL(ctx, "short2", "https://example.com/2", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, storage.DeletedURLs(ctx, []string{"short2"}, "user"))
+	require.NoError(t, storage.Close())
+
+	// данные и пометки удаления переживают перезапуск, миграции не применяются повторно
+	storage, err = NewSQLiteStorage(dsn)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	record, err := storage.GetURL(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", record.OriginalURL)
+
+	_, err = storage.GetURL(ctx, "short2")
+	assert.Error(t, err)
+}
+
+func TestMigrations_DownAndUp(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(Scheme + filepath.Join(t.TempDir(), "shortener.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, migrations.SQLite)
+	require.NoError(t, err)
+
+	total := len(migrator.Migrations())
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, total, applied)
+
+	rolledBack, err := migrator.Down(ctx, total)
+	require.NoError(t, err)
+	assert.Equal(t, total, rolledBack)
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, total, applied)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+		assert.False(t, status.AppliedAt.IsZero(), status.Name)
+	}
+}
+
+func TestMigrations_DuplicateShortURL(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(Scheme + filepath.Join(t.TempDir(), "shortener.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, migrations.SQLite)
+	require.NoError(t, err)
+
+	// откатываемся до схемы без уникального индекса и сохраняем дубли
+	total := len(migrator.Migrations())
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	_, err = migrator.Down(ctx, total-2)
+	require.NoError(t, err)
+
+	for _, original := range []string{"https://a.example", "https://b.example", "https://c.example"} {
+		_, err = db.ExecContext(ctx, "INSERT INTO urls (original_url, short_url) VALUES (?, 'dup')", original)
+		require.NoError(t, err)
+	}
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	rows, err := db.QueryContext(ctx, "SELECT short_url FROM urls ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		require.NoError(t, rows.Scan(&code))
+		codes = append(codes, code)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"dup", "dup.2", "dup.3"}, codes)
+}
+
+func TestSQLiteStorage_NextID_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dsn := Scheme + filepath.Join(t.TempDir(), "shortener.db")
+
+	storage, err := NewSQLiteStorage(dsn)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = storage.NextID(ctx)
+		require.NoError(t, err)
+	}
+	_, err = storage.SaveURL(ctx, "short", "https://example.com", "user", models.LinkOptions{})
+	require.NoError(t, err)
+	require.NoError(t, storage.Close())
+
+	// счетчик сохранен вместе с записью и продолжается после перезапуска
+	storage, err = NewSQLiteStorage(dsn)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	id, err := storage.NextID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), id)
+}
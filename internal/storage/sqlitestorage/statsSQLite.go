@@ -0,0 +1,20 @@
+package sqlitestorage
+
+import (
+	"context"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// GetStats возвращает число неудаленных ссылок и их владельцев.
+func (s *SQLiteStorage) GetStats(ctx context.Context) (*models.InternalStats, error) {
+	var stats models.InternalStats
+	err := s.storage.QueryRowContext(ctx,
+		"SELECT count(*), count(DISTINCT NULLIF(user_id, '')) FROM urls WHERE is_deleted = FALSE").
+		Scan(&stats.URLs, &stats.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,844 @@
+// Package storagetest - общий набор проверок соответствия для реализаций service.Storage.
+//
+// Любое хранилище подключается к набору одной строкой в своем _test.go:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) service.Storage {
+//			return NewMapURL()
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service/codegen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// baseURL - базовый адрес для проверок списков и пакетов.
+const baseURL = "http://localhost:8080"
+
+// Идентификаторы пользователей в формате UUID, как их создает AuthMiddleware.
+const (
+	owner    = "6f1c3d4a-9a43-4a4e-8f8e-3b8f3a1c2d01"
+	stranger = "6f1c3d4a-9a43-4a4e-8f8e-3b8f3a1c2d02"
+)
+
+// Factory создает новое пустое хранилище для одной проверки.
+// Освобождение ресурсов регистрируется через t.Cleanup.
+type Factory func(t *testing.T) service.Storage
+
+// Run запускает все проверки соответствия для хранилища.
+func Run(t *testing.T, newStorage Factory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s service.Storage)
+	}{
+		{"ping", testPing},
+		{"save_and_get", testSaveAndGet},
+		{"not_found", testNotFound},
+		{"conflict", testConflict},
+		{"concurrent_conflict", testConcurrentConflict},
+		{"short_collision", testShortCollision},
+		{"check_url", testCheckURL},
+		{"batch", testBatch},
+		{"sequential_codes", testSequentialCodes},
+		{"batch_alias", testBatchAlias},
+		{"batch_alias_taken", testBatchAliasTaken},
+		{"list", testList},
+		{"delete", testDelete},
+		{"delete_foreign", testDeleteForeign},
+		{"delete_batch", testDeleteBatch},
+		{"expires_at", testExpiresAt},
+		{"purge_expired", testPurgeExpired},
+		{"purge_limit", testPurgeLimit},
+		{"purge_deleted", testPurgeDeleted},
+		{"max_clicks", testMaxClicks},
+		{"concurrent_clicks", testConcurrentClicks},
+		{"password_hash", testPasswordHash},
+		{"click_stats", testClickStats},
+		{"purge_clicks", testPurgeClicks},
+		{"stats", testStats},
+		{"deletion_jobs", testDeletionJobs},
+		{"claim_deletion_jobs", testClaimDeletionJobs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t))
+		})
+	}
+}
+
+// save сохраняет ссылку и проверяет, что она сохранена под своей короткой ссылкой.
+func save(t *testing.T, s service.Storage, shortURL, originalURL, userID string) {
+	t.Helper()
+
+	saved, err := s.SaveURL(context.Background(), shortURL, originalURL, userID, models.LinkOptions{})
+	require.NoError(t, err)
+	require.Equal(t, shortURL, saved)
+}
+
+func testPing(t *testing.T, s service.Storage) {
+	assert.NoError(t, s.Ping(context.Background()))
+}
+
+func testSaveAndGet(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/save", owner)
+
+	record, err := s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/save", record.OriginalURL)
+}
+
+func testNotFound(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	record, err := s.GetURL(ctx, "missing")
+	assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+	assert.Nil(t, record)
+}
+
+func testConflict(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/conflict", owner)
+
+	// для известного URL возвращается его существующая короткая ссылка
+	shortURL, err := s.SaveURL(ctx, "conf2", "https://example.com/conflict", stranger, models.LinkOptions{})
+	assert.ErrorIs(t, err, errorscustom.ErrConflict)
+	assert.Equal(t, "conf1", shortURL)
+
+	_, err = s.GetURL(ctx, "conf2")
+	assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+
+	// первая ссылка не затронута конфликтом
+	record, err := s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/conflict", record.OriginalURL)
+}
+
+func testConcurrentConflict(t *testing.T, s service.Storage) {
+	const writers = 8
+
+	var (
+		wg      sync.WaitGroup
+		results [writers]string
+		errs    [writers]error
+	)
+	wg.Add(writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.SaveURL(context.Background(),
+				"race"+strconv.Itoa(i), "https://example.com/race", owner, models.LinkOptions{})
+		}(i)
+	}
+	wg.Wait()
+
+	// ровно одна запись выигрывает, остальные получают ее короткую ссылку
+	var winners []string
+	for i := 0; i < writers; i++ {
+		if errs[i] == nil {
+			winners = append(winners, results[i])
+			continue
+		}
+		assert.ErrorIs(t, errs[i], errorscustom.ErrConflict)
+	}
+	require.Len(t, winners, 1)
+	for i := 0; i < writers; i++ {
+		assert.Equal(t, winners[0], results[i])
+	}
+}
+
+func testShortCollision(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/first", owner)
+
+	// занятая короткая ссылка не перезаписывается другим URL
+	shortURL, err := s.SaveURL(ctx, "conf1", "https://example.com/second", owner, models.LinkOptions{})
+	assert.ErrorIs(t, err, errorscustom.ErrShortURLCollision)
+	assert.Empty(t, shortURL)
+
+	record, err := s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/first", record.OriginalURL)
+
+	// второй URL не сохранен и может быть сохранен под другой ссылкой
+	save(t, s, "conf2", "https://example.com/second", owner)
+}
+
+func testCheckURL(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	shortURL, err := s.CheckURL(ctx, "https://example.com/check")
+	require.NoError(t, err)
+	assert.Empty(t, shortURL)
+
+	save(t, s, "conf1", "https://example.com/check", owner)
+
+	shortURL, err = s.CheckURL(ctx, "https://example.com/check")
+	assert.ErrorIs(t, err, errorscustom.ErrConflict)
+	assert.Equal(t, "conf1", shortURL)
+}
+
+func testBatch(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/known", stranger)
+
+	result, err := s.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "a", OriginalURL: "https://example.com/known"},
+		{CorrelationID: "b", OriginalURL: "https://example.com/batch/1"},
+		{CorrelationID: "c", OriginalURL: "https://example.com/batch/2"},
+	}, baseURL, owner)
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+
+	// порядок и correlation_id сохраняются
+	for i, id := range []string{"a", "b", "c"} {
+		assert.Equal(t, id, result[i].CorrelationID)
+		assert.True(t, strings.HasPrefix(result[i].ShortURL, baseURL+"/"), result[i].ShortURL)
+	}
+
+	// для известного URL возвращается существующая короткая ссылка
+	assert.Equal(t, baseURL+"/conf1", result[0].ShortURL)
+
+	// новые ссылки сохранены и разрешаются
+	for i, original := range []string{"https://example.com/batch/1", "https://example.com/batch/2"} {
+		record, err := s.GetURL(ctx, strings.TrimPrefix(result[i+1].ShortURL, baseURL+"/"))
+		require.NoError(t, err)
+		assert.Equal(t, original, record.OriginalURL)
+	}
+
+	// новые ссылки принадлежат пользователю пакета
+	urls, err := s.GetAllURL(ctx, owner, baseURL)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*models.UserURLs{
+		{ShortURL: result[1].ShortURL, OriginalURL: "https://example.com/batch/1"},
+		{ShortURL: result[2].ShortURL, OriginalURL: "https://example.com/batch/2"},
+	}, urls)
+}
+
+func testBatchAlias(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+
+	result, err := s.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "a", OriginalURL: "https://example.com/sale", CustomAlias: "spring-sale"},
+		{CorrelationID: "b", OriginalURL: "https://example.com/other"},
+	}, baseURL, owner)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, baseURL+"/spring-sale", result[0].ShortURL)
+	assert.NotEqual(t, baseURL+"/spring-sale", result[1].ShortURL)
+
+	record, err := s.GetURL(ctx, "spring-sale")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/sale", record.OriginalURL)
+}
+
+func testBatchAliasTaken(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "spring-sale", "https://example.com/sale", stranger)
+
+	_, err := s.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "a", OriginalURL: "https://example.com/first"},
+		{CorrelationID: "b", OriginalURL: "https://example.com/other", CustomAlias: "spring-sale"},
+	}, baseURL, owner)
+	assert.ErrorIs(t, err, errorscustom.ErrAliasTaken)
+
+	var aliasErr *errorscustom.AliasError
+	require.ErrorAs(t, err, &aliasErr)
+	assert.Equal(t, "spring-sale", aliasErr.Alias)
+
+	// пакет не сохранен целиком
+	shortURL, err := s.CheckURL(ctx, "https://example.com/first")
+	assert.NoError(t, err)
+	assert.Empty(t, shortURL)
+}
+
+func testSequentialCodes(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	counter, ok := s.(codegen.Counter)
+	if !ok {
+		t.Skip("хранилище не предоставляет счетчик")
+	}
+	receiver, ok := s.(codegen.Receiver)
+	if !ok {
+		t.Skip("хранилище не принимает стратегию генерации")
+	}
+
+	gen, err := codegen.NewSequential(counter, codegen.Base62, 0)
+	require.NoError(t, err)
+	receiver.SetCodeGenerator(gen)
+
+	// занятая следующим номером ссылка пропускается
+	next, err := counter.NextID(ctx)
+	require.NoError(t, err)
+	taken := strconv.FormatUint(next+1, 10)
+	save(t, s, taken, "https://example.com/taken", owner)
+
+	result, err := s.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "a", OriginalURL: "https://example.com/seq/1"},
+		{CorrelationID: "b", OriginalURL: "https://example.com/seq/2"},
+	}, baseURL, owner)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.NotEqual(t, result[0].ShortURL, result[1].ShortURL)
+
+	for i, original := range []string{"https://example.com/seq/1", "https://example.com/seq/2"} {
+		shortURL := strings.TrimPrefix(result[i].ShortURL, baseURL+"/")
+		assert.NotEqual(t, taken, shortURL)
+
+		record, err := s.GetURL(ctx, shortURL)
+		require.NoError(t, err)
+		assert.Equal(t, original, record.OriginalURL)
+	}
+}
+
+func testList(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	urls, err := s.GetAllURL(ctx, owner, baseURL)
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+
+	save(t, s, "conf1", "https://example.com/list/1", owner)
+	save(t, s, "conf2", "https://example.com/list/2", owner)
+	save(t, s, "conf3", "https://example.com/list/3", stranger)
+
+	urls, err = s.GetAllURL(ctx, owner, baseURL)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*models.UserURLs{
+		{ShortURL: baseURL + "/conf1", OriginalURL: "https://example.com/list/1"},
+		{ShortURL: baseURL + "/conf2", OriginalURL: "https://example.com/list/2"},
+	}, urls)
+}
+
+func testDelete(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/delete/1", owner)
+	save(t, s, "conf2", "https://example.com/delete/2", owner)
+
+	require.NoError(t, s.DeletedURLs(ctx, []string{"conf1", "missing"}, owner))
+
+	// удаленная ссылка отдает "gone"
+	record, err := s.GetURL(ctx, "conf1")
+	assert.ErrorIs(t, err, errorscustom.ErrDeletedURL)
+	assert.Nil(t, record)
+
+	// повторное удаление не является ошибкой
+	require.NoError(t, s.DeletedURLs(ctx, []string{"conf1"}, owner))
+
+	// удаленная ссылка не попадает в список пользователя
+	urls, err := s.GetAllURL(ctx, owner, baseURL)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.UserURLs{
+		{ShortURL: baseURL + "/conf2", OriginalURL: "https://example.com/delete/2"},
+	}, urls)
+}
+
+func testDeleteForeign(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/foreign", owner)
+
+	require.NoError(t, s.DeletedURLs(ctx, []string{"conf1"}, stranger))
+
+	record, err := s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/foreign", record.OriginalURL)
+}
+
+func testDeleteBatch(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/batch/1", owner)
+	save(t, s, "conf2", "https://example.com/batch/2", stranger)
+	save(t, s, "conf3", "https://example.com/batch/3", owner)
+
+	deleted, err := s.DeleteURLsBatch(ctx, []models.DeletedURL{
+		{ShortURL: "conf1", UserID: owner},
+		{ShortURL: "conf2", UserID: stranger},
+		{ShortURL: "conf3", UserID: stranger},
+		{ShortURL: "missing", UserID: owner},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.DeletedURL{
+		{ShortURL: "conf1", UserID: owner},
+		{ShortURL: "conf2", UserID: stranger},
+	}, deleted)
+
+	// ссылки разных владельцев удаляются одним пакетом
+	for _, shortURL := range []string{"conf1", "conf2"} {
+		_, err := s.GetURL(ctx, shortURL)
+		assert.ErrorIs(t, err, errorscustom.ErrDeletedURL, shortURL)
+	}
+
+	// чужая ссылка в пакете пропускается
+	record, err := s.GetURL(ctx, "conf3")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/batch/3", record.OriginalURL)
+
+	// удаленная раньше ссылка своего владельца возвращается снова
+	deleted, err = s.DeleteURLsBatch(ctx, []models.DeletedURL{{ShortURL: "conf1", UserID: owner}})
+	require.NoError(t, err)
+	assert.Equal(t, []models.DeletedURL{{ShortURL: "conf1", UserID: owner}}, deleted)
+
+	deleted, err = s.DeleteURLsBatch(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+// expiring сохраняет ссылку с моментом истечения.
+func expiring(t *testing.T, s service.Storage, shortURL, originalURL string, expiresAt time.Time) {
+	t.Helper()
+
+	saved, err := s.SaveURL(context.Background(), shortURL, originalURL, owner, models.LinkOptions{ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	require.Equal(t, shortURL, saved)
+}
+
+func testExpiresAt(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	// хранилища хранят время с точностью не хуже миллисекунды
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	expiring(t, s, "conf1", "https://example.com/expiring", expiresAt)
+	save(t, s, "conf2", "https://example.com/forever", owner)
+
+	record, err := s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	require.NotNil(t, record.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*record.ExpiresAt), "%v != %v", expiresAt, *record.ExpiresAt)
+
+	record, err = s.GetURL(ctx, "conf2")
+	require.NoError(t, err)
+	assert.Nil(t, record.ExpiresAt)
+
+	// момент истечения сохраняется и для ссылок из пакета
+	result, err := s.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "a", OriginalURL: "https://example.com/batch/expiring", ExpiresAt: &expiresAt},
+	}, baseURL, owner)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	record, err = s.GetURL(ctx, strings.TrimPrefix(result[0].ShortURL, baseURL+"/"))
+	require.NoError(t, err)
+	require.NotNil(t, record.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*record.ExpiresAt), "%v != %v", expiresAt, *record.ExpiresAt)
+}
+
+func testPurgeExpired(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	expiring(t, s, "conf1", "https://example.com/expired", now.Add(-time.Minute))
+	expiring(t, s, "conf2", "https://example.com/alive", now.Add(time.Hour))
+	save(t, s, "conf3", "https://example.com/forever", owner)
+
+	purged, err := s.PurgeExpired(ctx, now, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	// истекшая ссылка удалена целиком
+	_, err = s.GetURL(ctx, "conf1")
+	assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+
+	urls, err := s.GetAllURL(ctx, owner, baseURL)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*models.UserURLs{
+		{ShortURL: baseURL + "/conf2", OriginalURL: "https://example.com/alive"},
+		{ShortURL: baseURL + "/conf3", OriginalURL: "https://example.com/forever"},
+	}, urls)
+
+	// и URL, и короткая ссылка освобождены
+	shortURL, err := s.CheckURL(ctx, "https://example.com/expired")
+	require.NoError(t, err)
+	assert.Empty(t, shortURL)
+	save(t, s, "conf1", "https://example.com/reused", owner)
+
+	// повторная очистка ничего не находит
+	purged, err = s.PurgeExpired(ctx, now, 100)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+}
+
+func testPurgeLimit(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		expiring(t, s, "conf"+strconv.Itoa(i), "https://example.com/limit/"+strconv.Itoa(i), now.Add(-time.Minute))
+	}
+
+	purged, err := s.PurgeExpired(ctx, now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	purged, err = s.PurgeExpired(ctx, now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+}
+
+func testPurgeDeleted(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	save(t, s, "conf1", "https://example.com/deleted", owner)
+	save(t, s, "conf2", "https://example.com/alive", owner)
+	save(t, s, "conf3", "https://example.com/deleted/too", owner)
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{{ShortURL: "conf1", At: now}}))
+	require.NoError(t, s.DeletedURLs(ctx, []string{"conf1", "conf3"}, owner))
+
+	purged, err := s.PurgeDeleted(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	purged, err = s.PurgeDeleted(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	// удаленные ссылки стерты целиком, живая осталась
+	for _, shortURL := range []string{"conf1", "conf3"} {
+		_, err = s.GetURL(ctx, shortURL)
+		assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+	}
+	record, err := s.GetURL(ctx, "conf2")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/alive", record.OriginalURL)
+
+	// URL, короткая ссылка и ее переходы освобождены
+	shortURL, err := s.CheckURL(ctx, "https://example.com/deleted")
+	require.NoError(t, err)
+	assert.Empty(t, shortURL)
+	save(t, s, "conf1", "https://example.com/reused", owner)
+	stats, err := s.ClickStats(ctx, "conf1", now.Add(-time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+
+	// повторная очистка ничего не находит
+	purged, err = s.PurgeDeleted(ctx, 100)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+}
+
+// limited сохраняет ссылку с ограничением числа переходов.
+func limited(t *testing.T, s service.Storage, shortURL, originalURL string, maxClicks int) {
+	t.Helper()
+
+	saved, err := s.SaveURL(context.Background(), shortURL, originalURL, owner, models.LinkOptions{MaxClicks: maxClicks})
+	require.NoError(t, err)
+	require.Equal(t, shortURL, saved)
+}
+
+func testMaxClicks(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	limited(t, s, "conf1", "https://example.com/limited", 2)
+	save(t, s, "conf2", "https://example.com/unlimited", owner)
+
+	record, err := s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	require.NotNil(t, record.ClicksLeft)
+	assert.Equal(t, 2, *record.ClicksLeft)
+
+	clicks, err := s.UseClick(ctx, "conf1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, clicks)
+	clicks, err = s.UseClick(ctx, "conf1")
+	require.NoError(t, err)
+	assert.Zero(t, clicks)
+
+	// исчерпанная ссылка остается в хранилище, но переходов больше нет
+	_, err = s.UseClick(ctx, "conf1")
+	assert.ErrorIs(t, err, errorscustom.ErrExhaustedURL)
+	record, err = s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	require.NotNil(t, record.ClicksLeft)
+	assert.Zero(t, *record.ClicksLeft)
+
+	// у ссылки без ограничения ничего не списывается
+	record, err = s.GetURL(ctx, "conf2")
+	require.NoError(t, err)
+	assert.Nil(t, record.ClicksLeft)
+	clicks, err = s.UseClick(ctx, "conf2")
+	require.NoError(t, err)
+	assert.Equal(t, -1, clicks)
+
+	_, err = s.UseClick(ctx, "missing")
+	assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+
+	// ограничение сохраняется и для ссылок из пакета
+	result, err := s.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "a", OriginalURL: "https://example.com/batch/limited", MaxClicks: 1},
+	}, baseURL, owner)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	shortURL := strings.TrimPrefix(result[0].ShortURL, baseURL+"/")
+	clicks, err = s.UseClick(ctx, shortURL)
+	require.NoError(t, err)
+	assert.Zero(t, clicks)
+	_, err = s.UseClick(ctx, shortURL)
+	assert.ErrorIs(t, err, errorscustom.ErrExhaustedURL)
+}
+
+func testConcurrentClicks(t *testing.T, s service.Storage) {
+	const (
+		maxClicks = 5
+		readers   = 20
+	)
+	limited(t, s, "conf1", "https://example.com/limited", maxClicks)
+
+	var (
+		wg   sync.WaitGroup
+		errs [readers]error
+	)
+	wg.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.UseClick(context.Background(), "conf1")
+		}(i)
+	}
+	wg.Wait()
+
+	// переходов проходит ровно столько, сколько разрешено
+	used := 0
+	for _, err := range errs {
+		if err == nil {
+			used++
+			continue
+		}
+		assert.ErrorIs(t, err, errorscustom.ErrExhaustedURL)
+	}
+	assert.Equal(t, maxClicks, used)
+}
+
+func testPasswordHash(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	const hash = "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+
+	saved, err := s.SaveURL(ctx, "conf1", "https://example.com/protected", owner, models.LinkOptions{PasswordHash: hash})
+	require.NoError(t, err)
+	require.Equal(t, "conf1", saved)
+	save(t, s, "conf2", "https://example.com/open", owner)
+
+	record, err := s.GetURL(ctx, "conf1")
+	require.NoError(t, err)
+	assert.Equal(t, hash, record.PasswordHash)
+	assert.True(t, record.Protected())
+
+	record, err = s.GetURL(ctx, "conf2")
+	require.NoError(t, err)
+	assert.Empty(t, record.PasswordHash)
+}
+
+func testClickStats(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	save(t, s, "conf1", "https://example.com/clicked", owner)
+	save(t, s, "conf2", "https://example.com/other", owner)
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{
+		{ShortURL: "conf1", At: day.Add(-time.Hour), IPHash: "a"},
+		{ShortURL: "conf1", At: day.Add(time.Minute), Referrer: "https://ref.example.com", UserAgent: "test", IPHash: "a"},
+		{ShortURL: "conf1", At: day.Add(2 * time.Minute), IPHash: "b"},
+		{ShortURL: "conf1", At: day.Add(time.Hour + time.Second)},
+		{ShortURL: "conf2", At: day},
+		// переходы по несуществующей ссылке пропускаются
+		{ShortURL: "missing", At: day},
+	}))
+
+	stats, err := s.ClickStats(ctx, "conf1", day, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Total)
+	assert.Equal(t, 2, stats.Unique)
+	require.Len(t, stats.Buckets, 2)
+	assert.True(t, day.Equal(stats.Buckets[0].Start), "%v != %v", day, stats.Buckets[0].Start)
+	assert.Equal(t, 2, stats.Buckets[0].Clicks)
+	assert.True(t, day.Add(time.Hour).Equal(stats.Buckets[1].Start), "%v", stats.Buckets[1].Start)
+	assert.Equal(t, 1, stats.Buckets[1].Clicks)
+
+	stats, err = s.ClickStats(ctx, "missing", day, time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+	assert.Empty(t, stats.Buckets)
+}
+
+func testPurgeClicks(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	expiring(t, s, "conf1", "https://example.com/expired", now.Add(-time.Minute))
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{{ShortURL: "conf1", At: now}}))
+
+	purged, err := s.PurgeExpired(ctx, now, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	// переходы удаляются вместе со ссылкой и не достаются новой ссылке с тем же кодом
+	save(t, s, "conf1", "https://example.com/reused", owner)
+	stats, err := s.ClickStats(ctx, "conf1", now.Add(-time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+}
+
+func testStats(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	stats, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &models.InternalStats{}, stats)
+
+	save(t, s, "conf1", "https://example.com/1", owner)
+	save(t, s, "conf2", "https://example.com/2", owner)
+	save(t, s, "conf3", "https://example.com/3", stranger)
+	save(t, s, "conf4", "https://example.com/anonymous", "")
+	_, err = s.SaveSlice(ctx, []models.MultipleURL{
+		{CorrelationID: "a", OriginalURL: "https://example.com/batch"},
+	}, baseURL, owner)
+	require.NoError(t, err)
+
+	// анонимные ссылки учитываются, но не добавляют пользователей
+	stats, err = s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &models.InternalStats{URLs: 5, Users: 2}, stats)
+
+	// удаленные ссылки и пользователи без ссылок не учитываются
+	require.NoError(t, s.DeletedURLs(ctx, []string{"conf3"}, stranger))
+	stats, err = s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &models.InternalStats{URLs: 4, Users: 1}, stats)
+}
+
+func testDeletionJobs(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first, err := s.SaveDeletionJob(ctx, models.DeletionJob{UserID: owner, URLs: []string{"conf1", "conf2"}, NextAttempt: now})
+	require.NoError(t, err)
+	second, err := s.SaveDeletionJob(ctx, models.DeletionJob{UserID: stranger, URLs: []string{"conf3"}, NextAttempt: now.Add(time.Minute)})
+	require.NoError(t, err)
+	third, err := s.SaveDeletionJob(ctx, models.DeletionJob{UserID: owner, URLs: []string{"conf4"}, NextAttempt: now})
+	require.NoError(t, err)
+	assert.Less(t, first, second)
+	assert.Less(t, second, third)
+
+	// задание с NextAttempt позже before не возвращается
+	jobs, err := s.DueDeletionJobs(ctx, now, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, first, jobs[0].ID)
+	assert.Equal(t, owner, jobs[0].UserID)
+	assert.Equal(t, []string{"conf1", "conf2"}, jobs[0].URLs)
+	assert.True(t, now.Equal(jobs[0].NextAttempt))
+	assert.Equal(t, third, jobs[1].ID)
+
+	// afterID и limit листают задания по порядку
+	jobs, err = s.DueDeletionJobs(ctx, now.Add(time.Hour), first, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, second, jobs[0].ID)
+
+	// неудачная попытка переносит задание, отложенное задание не возвращается
+	require.NoError(t, s.UpdateDeletionJobs(ctx, []models.DeletionJob{
+		{ID: first, Status: models.JobQueued, Attempts: 1, NextAttempt: now.Add(time.Hour), LastError: "storage failed"},
+		{ID: second, Status: models.JobFailed, Attempts: 5, NextAttempt: now, LastError: "storage failed", FinishedAt: &now},
+	}))
+	jobs, err = s.DueDeletionJobs(ctx, now.Add(time.Hour), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, first, jobs[0].ID)
+	assert.Equal(t, 1, jobs[0].Attempts)
+	assert.Equal(t, "storage failed", jobs[0].LastError)
+	assert.Equal(t, []string{"conf1", "conf2"}, jobs[0].URLs)
+	assert.Equal(t, third, jobs[1].ID)
+
+	// выполненное задание хранит результат и больше не возвращается
+	finishedAt := now.Add(time.Minute)
+	require.NoError(t, s.UpdateDeletionJobs(ctx, []models.DeletionJob{
+		{ID: first, Status: models.JobRunning, Attempts: 1, NextAttempt: now.Add(time.Hour), LastError: "storage failed"},
+		{ID: third, Status: models.JobRunning, NextAttempt: now},
+	}))
+	require.NoError(t, s.UpdateDeletionJobs(ctx, []models.DeletionJob{{
+		ID: first, Status: models.JobDone, Attempts: 1, NextAttempt: now.Add(time.Hour),
+		Deleted: []string{"conf1"}, Skipped: []string{"conf2"}, FinishedAt: &finishedAt,
+	}}))
+	jobs, err = s.DueDeletionJobs(ctx, now.Add(time.Hour), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, third, jobs[0].ID)
+	assert.Equal(t, models.JobRunning, jobs[0].Status)
+
+	job, err := s.GetDeletionJob(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, owner, job.UserID)
+	assert.Equal(t, models.JobDone, job.Status)
+	assert.Equal(t, []string{"conf1", "conf2"}, job.URLs)
+	assert.Equal(t, []string{"conf1"}, job.Deleted)
+	assert.Equal(t, []string{"conf2"}, job.Skipped)
+	require.NotNil(t, job.FinishedAt)
+	assert.True(t, finishedAt.Equal(*job.FinishedAt))
+
+	_, err = s.GetDeletionJob(ctx, third+100)
+	assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+
+	// завершенные задания удаляются по истечении срока хранения
+	purged, err := s.PurgeDeletionJobs(ctx, finishedAt)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = s.GetDeletionJob(ctx, second)
+	assert.ErrorIs(t, err, errorscustom.ErrNotFound)
+	_, err = s.GetDeletionJob(ctx, first)
+	require.NoError(t, err)
+
+	purged, err = s.PurgeDeletionJobs(ctx, finishedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = s.GetDeletionJob(ctx, third)
+	require.NoError(t, err)
+}
+
+func testClaimDeletionJobs(t *testing.T, s service.Storage) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := now.Add(time.Minute)
+
+	var ids []int64
+	for _, next := range []time.Time{now, now.Add(time.Hour), now, now} {
+		id, err := s.SaveDeletionJob(ctx, models.DeletionJob{UserID: owner, URLs: []string{"conf1"}, NextAttempt: next})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	require.NoError(t, s.UpdateDeletionJobs(ctx, []models.DeletionJob{
+		{ID: ids[3], Status: models.JobDone, NextAttempt: now, FinishedAt: &now},
+	}))
+
+	// берутся только задания, которые пора выполнить, не больше limit
+	jobs, err := s.ClaimDeletionJobs(ctx, now, until, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, ids[0], jobs[0].ID)
+	assert.Equal(t, []string{"conf1"}, jobs[0].URLs)
+	assert.True(t, until.Equal(jobs[0].NextAttempt), "%v", jobs[0].NextAttempt)
+
+	jobs, err = s.ClaimDeletionJobs(ctx, now, until, 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, ids[2], jobs[0].ID)
+
+	// взятые задания не возвращаются до until
+	jobs, err = s.ClaimDeletionJobs(ctx, now, until, 10)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	job, err := s.GetDeletionJob(ctx, ids[0])
+	require.NoError(t, err)
+	assert.True(t, until.Equal(job.NextAttempt), "%v", job.NextAttempt)
+
+	// после until задания берутся снова, вместе с отложенным на час
+	jobs, err = s.ClaimDeletionJobs(ctx, now.Add(time.Hour), now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	assert.Equal(t, []int64{ids[0], ids[1], ids[2]}, []int64{jobs[0].ID, jobs[1].ID, jobs[2].ID})
+}
@@ -0,0 +1,187 @@
+package workers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+)
+
+// Recorder - интерфейс записи переходов по коротким ссылкам.
+type Recorder interface {
+	Record(shortURL string, r *http.Request)
+	RecordClick(shortURL, referrer, userAgent, remoteAddr string)
+}
+
+// ipKeySize - размер случайного ключа хеширования IP, если ключ не задан.
+const ipKeySize = 32
+
+// ClickRecorder - воркер для записи переходов по коротким ссылкам.
+//
+// Переходы копятся в ограниченном буфере и сохраняются пачками,
+// поэтому запись не замедляет перенаправление: если буфер полон,
+// переход отбрасывается и учитывается в Dropped.
+type ClickRecorder struct {
+	storage   *service.Service
+	queue     chan models.Click
+	batchSize int
+	interval  time.Duration
+	ipKey     []byte
+	logs      *logger.Logger
+
+	now     func() time.Time
+	dropped atomic.Uint64
+}
+
+// NewClickRecorder - конструктор воркера.
+// Переходы сохраняются пачками по batchSize штук или раз в interval.
+// IP клиента хешируется HMAC-SHA256 с ключом ipKey; без ключа берется
+// случайный, и уникальные посетители считаются только в пределах процесса.
+// Нулевой bufferSize отключает запись переходов.
+func NewClickRecorder(storage *service.Service, bufferSize, batchSize int, interval time.Duration, ipKey string, logs *logger.Logger) *ClickRecorder {
+	key := []byte(ipKey)
+	if len(key) == 0 {
+		key = make([]byte, ipKeySize)
+		if _, err := rand.Read(key); err != nil {
+			logs.Error("Error generate click IP key = ", logger.ErrAttr(err))
+		}
+	}
+
+	w := &ClickRecorder{
+		storage:   storage,
+		batchSize: max(batchSize, 1),
+		interval:  interval,
+		ipKey:     key,
+		logs:      logs,
+		now:       time.Now,
+	}
+	if bufferSize > 0 {
+		w.queue = make(chan models.Click, bufferSize)
+	}
+
+	return w
+}
+
+// Record ставит переход из HTTP-запроса в буфер, не блокируясь.
+// Адрес клиента берется из заголовка X-Real-IP, который выставляет прокси,
+// а без него - у соединения.
+func (w *ClickRecorder) Record(shortURL string, r *http.Request) {
+	remoteAddr := r.Header.Get(middleware.RealIPHeader)
+	if remoteAddr == "" {
+		remoteAddr = r.RemoteAddr
+	}
+	w.RecordClick(shortURL, r.Referer(), r.UserAgent(), remoteAddr)
+}
+
+// RecordClick ставит переход в буфер, не блокируясь. remoteAddr - адрес
+// клиента в виде host:port или просто host.
+func (w *ClickRecorder) RecordClick(shortURL, referrer, userAgent, remoteAddr string) {
+	if w.queue == nil {
+		return
+	}
+
+	click := models.Click{
+		ShortURL:  shortURL,
+		At:        w.now().UTC(),
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		IPHash:    w.hashIP(remoteAddr),
+	}
+
+	select {
+	case w.queue <- click:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Dropped возвращает число отброшенных переходов:
+// из-за полного буфера или ошибки сохранения.
+func (w *ClickRecorder) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// hashIP возвращает HMAC-хеш IP-адреса клиента из адреса вида host:port
+// или просто host: порт отбрасывается, чтобы переходы одного клиента
+// с разных соединений давали один хеш.
+func (w *ClickRecorder) hashIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	if host == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, w.ipKey)
+	mac.Write([]byte(host))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// StartWorkerClicks стартует воркер сохранения переходов.
+// После отмены контекста сохраняет оставшиеся в буфере переходы и завершается.
+func (w *ClickRecorder) StartWorkerClicks(ctx context.Context) {
+	if w.queue == nil {
+		return
+	}
+
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	batch := make([]models.Click, 0, w.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// контекст отменен, но буфер нужно дописать
+			flushCtx := context.WithoutCancel(ctx)
+			for {
+				select {
+				case click := <-w.queue:
+					batch = append(batch, click)
+					if len(batch) >= w.batchSize {
+						batch = w.flush(flushCtx, batch)
+					}
+				default:
+					w.flush(flushCtx, batch)
+					return
+				}
+			}
+		case click := <-w.queue:
+			batch = append(batch, click)
+			if len(batch) >= w.batchSize {
+				batch = w.flush(ctx, batch)
+			}
+		case <-tick:
+			batch = w.flush(ctx, batch)
+		}
+	}
+}
+
+// flush сохраняет пачку переходов и возвращает пустую пачку для следующих.
+// Пачка, которую не удалось сохранить, отбрасывается.
+func (w *ClickRecorder) flush(ctx context.Context, batch []models.Click) []models.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := w.storage.SaveClicks(ctx, batch); err != nil {
+		w.logs.Error("Error save clicks = ", logger.ErrAttr(err), logger.IntAttr("count", len(batch)))
+		w.dropped.Add(uint64(len(batch)))
+	}
+
+	return batch[:0]
+}
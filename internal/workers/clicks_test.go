@@ -0,0 +1,118 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/middleware"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClickRecorder_Record - переход попадает в буфер с хешем IP, переполнение не блокирует.
+func TestClickRecorder_Record(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder := NewClickRecorder(nil, 1, 10, time.Second, "key", logger.NewLogger())
+	recorder.now = func() time.Time { return now }
+
+	r := httptest.NewRequest("GET", "/qwerty", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Referer", "https://ref.example.com")
+	r.Header.Set("User-Agent", "test")
+
+	recorder.Record("qwerty", r)
+	recorder.Record("qwerty", r)
+
+	click := <-recorder.queue
+	assert.Equal(t, "qwerty", click.ShortURL)
+	assert.Equal(t, now, click.At)
+	assert.Equal(t, "https://ref.example.com", click.Referrer)
+	assert.Equal(t, "test", click.UserAgent)
+	assert.NotContains(t, click.IPHash, "10.0.0.1")
+	assert.Equal(t, uint64(1), recorder.Dropped())
+
+	// хеш не зависит от порта, но зависит от ключа
+	r.RemoteAddr = "10.0.0.1:4321"
+	assert.Equal(t, click.IPHash, recorder.hashIP(r.RemoteAddr))
+	other := NewClickRecorder(nil, 1, 10, time.Second, "other", logger.NewLogger())
+	assert.NotEqual(t, click.IPHash, other.hashIP(r.RemoteAddr))
+
+	// за прокси адрес клиента берется из X-Real-IP, а не у соединения
+	r.RemoteAddr = "192.168.0.1:1234"
+	r.Header.Set(middleware.RealIPHeader, "10.0.0.1")
+	recorder.Record("qwerty", r)
+	assert.Equal(t, click.IPHash, (<-recorder.queue).IPHash)
+}
+
+// TestClickRecorder_Disabled - нулевой буфер отключает запись.
+func TestClickRecorder_Disabled(t *testing.T) {
+	recorder := NewClickRecorder(nil, 0, 10, time.Second, "", logger.NewLogger())
+	recorder.Record("qwerty", httptest.NewRequest("GET", "/qwerty", nil))
+	assert.Zero(t, recorder.Dropped())
+
+	// воркер сразу завершается
+	recorder.StartWorkerClicks(context.Background())
+}
+
+// TestClickRecorder_Start - переходы сохраняются пачками, остаток - при остановке.
+func TestClickRecorder_Start(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := mocks.NewMockStorage(ctrl)
+
+	saved := make(chan int, 10)
+	mockStorage.EXPECT().SaveClicks(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, clicks []models.Click) error {
+			saved <- len(clicks)
+			return nil
+		}).Times(2)
+
+	serviceTest := service.NewService(mockStorage, logger.NewLogger())
+	recorder := NewClickRecorder(serviceTest, 10, 2, time.Hour, "key", logger.NewLogger())
+	for i := 0; i < 3; i++ {
+		recorder.Record("qwerty", httptest.NewRequest("GET", "/qwerty", nil))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		recorder.StartWorkerClicks(ctx)
+		close(done)
+	}()
+
+	select {
+	case n := <-saved:
+		assert.Equal(t, 2, n)
+	case <-time.After(time.Second):
+		t.Fatal("full batch was not saved")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+	assert.Equal(t, 1, <-saved)
+}
+
+// TestClickRecorder_flush - несохраненная пачка учитывается как отброшенная.
+func TestClickRecorder_flush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := mocks.NewMockStorage(ctrl)
+	mockStorage.EXPECT().SaveClicks(gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+	serviceTest := service.NewService(mockStorage, logger.NewLogger())
+	recorder := NewClickRecorder(serviceTest, 10, 2, time.Hour, "key", logger.NewLogger())
+
+	batch := recorder.flush(context.Background(), []models.Click{{ShortURL: "a"}, {ShortURL: "b"}})
+	require.Empty(t, batch)
+	assert.Equal(t, uint64(2), recorder.Dropped())
+}
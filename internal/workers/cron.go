@@ -0,0 +1,184 @@
+package workers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchYears - на сколько лет вперед ищется следующий запуск,
+// чтобы расписание вроде 30 февраля не зациклило поиск.
+const cronSearchYears = 5
+
+// cronDescriptors - сокращения расписаний cron.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField - допустимые значения поля расписания cron.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+// cronFields - поля расписания cron по порядку.
+var cronFields = [...]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// cronSchedule - расписание cron. Поля хранятся битовыми масками
+// допустимых значений.
+type cronSchedule struct {
+	spec                          string
+	minute, hour, dom, month, dow uint64
+	// domStar и dowStar - день месяца или недели не ограничен,
+	// тогда день подбирается по обоим полям, иначе по любому из них.
+	domStar, dowStar bool
+}
+
+// ParseCron разбирает расписание cron из пяти полей: минута, час, день
+// месяца, месяц и день недели (0 и 7 - воскресенье). Поле - это *,
+// число или диапазон a-b, с шагом /n, или список таких значений через
+// запятую. Также поддерживаются сокращения @hourly, @daily, @weekly,
+// @monthly, @yearly и @every <интервал>. Время берется в часовом поясе
+// времени, от которого считается следующий запуск.
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid cron spec %q: interval must be a positive duration", spec)
+		}
+		return Every(interval), nil
+	}
+
+	expanded := spec
+	if descriptor, ok := cronDescriptors[spec]; ok {
+		expanded = descriptor
+	}
+
+	fields := strings.Fields(expanded)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron spec %q: expected %d fields, got %d", spec, len(cronFields), len(fields))
+	}
+
+	var masks [len(cronFields)]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %w", spec, err)
+		}
+		masks[i] = mask
+	}
+
+	// 7 - тоже воскресенье
+	dow := masks[4]
+	if dow&(1<<7) != 0 {
+		dow = dow&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		spec:    spec,
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     dow,
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField разбирает поле расписания в битовую маску значений.
+func parseCronField(value string, field cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("%s: invalid step %q", field.name, stepPart)
+			}
+		}
+
+		low, high := field.min, field.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("%s: invalid value %q", field.name, lowPart)
+			}
+			switch {
+			case isRange:
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("%s: invalid value %q", field.name, highPart)
+				}
+			case !hasStep:
+				high = low
+			}
+		}
+		if low < field.min || high > field.max || low > high {
+			return 0, fmt.Errorf("%s: %q out of range %d-%d", field.name, part, field.min, field.max)
+		}
+
+		for v := low; v <= high; v += step {
+			mask |= 1 << v
+		}
+	}
+
+	return mask, nil
+}
+
+// Next возвращает первую минуту после after, которая подходит расписанию.
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches проверяет день по дню месяца и дню недели.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// String возвращает расписание в том виде, в котором оно задано.
+func (s *cronSchedule) String() string {
+	return s.spec
+}
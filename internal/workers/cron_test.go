@@ -0,0 +1,69 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	// 2026-10-18 - воскресенье
+	after := time.Date(2026, 10, 18, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{spec: "* * * * *", expected: time.Date(2026, 10, 18, 10, 18, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", expected: time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC)},
+		{spec: "5/20 * * * *", expected: time.Date(2026, 10, 18, 10, 25, 0, 0, time.UTC)},
+		{spec: "0 3 * * *", expected: time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * 1-5", expected: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{spec: "30 2 1,15 * *", expected: time.Date(2026, 11, 1, 2, 30, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 * * 7", expected: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		// ограничены оба дня - подходит любой из них
+		{spec: "0 0 25 * 2", expected: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", expected: time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", expected: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "@every 1m30s", expected: after.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseCron(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, schedule.Next(after))
+			assert.Equal(t, tt.spec, schedule.String())
+		})
+	}
+}
+
+func TestParseCron_Never(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every",
+		"@every -1m",
+		"@sometimes",
+	} {
+		_, err := ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"context"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+)
+
+// WorkerReaper - воркер для удаления из хранилища истекших URL
+// и URL, помеченных удаленными.
+type WorkerReaper struct {
+	storage   *service.Service
+	batchSize int
+	logs      *logger.Logger
+}
+
+// NewWorkerReaper - конструктор воркера.
+// URL удаляются пачками по batchSize штук, когда планировщик
+// запускает задачи Run и RunDeleted.
+func NewWorkerReaper(storage *service.Service, batchSize int, logs *logger.Logger) *WorkerReaper {
+	return &WorkerReaper{
+		storage:   storage,
+		batchSize: batchSize,
+		logs:      logs,
+	}
+}
+
+// Run удаляет истекшие URL один раз - задача планировщика.
+func (w *WorkerReaper) Run(ctx context.Context) error {
+	purged, err := w.reap(ctx)
+	if purged > 0 {
+		w.logs.Info("Expired URLs purged", logger.IntAttr("count", purged))
+	}
+
+	return err
+}
+
+// RunDeleted окончательно удаляет URL, помеченные удаленными, - задача планировщика.
+func (w *WorkerReaper) RunDeleted(ctx context.Context) error {
+	purged, err := w.batches(ctx, w.storage.PurgeDeleted)
+	if purged > 0 {
+		w.logs.Info("Deleted URLs purged", logger.IntAttr("count", purged))
+	}
+
+	return err
+}
+
+// reap удаляет истекшие URL пачками, пока не попадется неполная пачка.
+// Возвращает число удаленных URL и ошибку, на которой удаление прервалось.
+func (w *WorkerReaper) reap(ctx context.Context) (int, error) {
+	return w.batches(ctx, w.storage.PurgeExpired)
+}
+
+// batches вызывает purge пачками по batchSize, пока не попадется неполная пачка.
+func (w *WorkerReaper) batches(ctx context.Context, purge func(ctx context.Context, limit int) (int, error)) (int, error) {
+	var total int
+	for ctx.Err() == nil {
+		purged, err := purge(ctx, w.batchSize)
+		total += purged
+		if err != nil {
+			return total, err
+		}
+		if purged < w.batchSize {
+			break
+		}
+	}
+
+	return total, nil
+}
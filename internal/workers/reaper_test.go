@@ -0,0 +1,68 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWorkerReaper_reap - удаление идет пачками до первой неполной.
+func TestWorkerReaper_reap(t *testing.T) {
+	tests := []struct {
+		name     string
+		batches  []int
+		err      error
+		expected int
+	}{
+		{name: "nothing_expired", batches: []int{0}, expected: 0},
+		{name: "several_batches", batches: []int{10, 10, 3}, expected: 23},
+		{name: "exact_batch", batches: []int{10, 0}, expected: 10},
+		{name: "error", batches: []int{10, 0}, err: errors.New("some error"), expected: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockStorage := mocks.NewMockStorage(ctrl)
+
+			calls := make([]*gomock.Call, 0, len(tt.batches))
+			for i, purged := range tt.batches {
+				var err error
+				if i == len(tt.batches)-1 {
+					err = tt.err
+				}
+				calls = append(calls, mockStorage.EXPECT().
+					PurgeExpired(gomock.Any(), gomock.Any(), 10).Return(purged, err))
+			}
+			gomock.InOrder(calls...)
+
+			serviceTest := service.NewService(mockStorage, logger.NewLogger())
+			reaper := NewWorkerReaper(serviceTest, 10, logger.NewLogger())
+
+			purged, err := reaper.reap(context.Background())
+			assert.Equal(t, tt.expected, purged)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+// TestWorkerReaper_RunDeleted - удаленные URL стираются пачками до первой неполной.
+func TestWorkerReaper_RunDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := mocks.NewMockStorage(ctrl)
+	gomock.InOrder(
+		mockStorage.EXPECT().PurgeDeleted(gomock.Any(), 10).Return(10, nil),
+		mockStorage.EXPECT().PurgeDeleted(gomock.Any(), 10).Return(4, nil),
+	)
+
+	serviceTest := service.NewService(mockStorage, logger.NewLogger())
+	reaper := NewWorkerReaper(serviceTest, 10, logger.NewLogger())
+
+	assert.NoError(t, reaper.RunDeleted(context.Background()))
+}
@@ -0,0 +1,357 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+)
+
+// defaultJobHistory - сколько последних запусков хранится по каждой задаче.
+const defaultJobHistory = 20
+
+// manualSchedule - описание расписания задачи, которая запускается только вручную.
+const manualSchedule = "manual"
+
+// JobScheduler - интерфейс планировщика для админского API.
+type JobScheduler interface {
+	Jobs() []models.ScheduledJob
+	Trigger(name string) error
+}
+
+// JobFunc - фоновая задача. Ошибка попадает в историю запусков.
+type JobFunc func(ctx context.Context) error
+
+// Schedule - расписание фоновой задачи.
+type Schedule interface {
+	// Next возвращает время запуска после after,
+	// нулевое время - запусков больше не будет.
+	Next(after time.Time) time.Time
+	// String возвращает описание расписания.
+	String() string
+}
+
+// intervalSchedule - запуск через равные промежутки времени.
+type intervalSchedule time.Duration
+
+// Every возвращает расписание с запуском раз в interval
+// после окончания предыдущего запуска.
+func Every(interval time.Duration) Schedule {
+	return intervalSchedule(interval)
+}
+
+// Next возвращает время запуска через интервал после after.
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	if s <= 0 {
+		return time.Time{}
+	}
+
+	return after.Add(time.Duration(s))
+}
+
+// String возвращает интервал в виде @every <интервал>.
+func (s intervalSchedule) String() string {
+	return "@every " + time.Duration(s).String()
+}
+
+// JobOption - опция фоновой задачи.
+type JobOption func(*scheduledJob)
+
+// WithJitter задает случайную задержку до jitter перед каждым запуском
+// по расписанию, чтобы копии сервиса не запускали задачу одновременно.
+func WithJitter(jitter time.Duration) JobOption {
+	return func(j *scheduledJob) {
+		if jitter > 0 {
+			j.jitter = jitter
+		}
+	}
+}
+
+// SchedulerOption - опция планировщика.
+type SchedulerOption func(*Scheduler)
+
+// WithHistory задает, сколько последних запусков хранится по каждой задаче.
+func WithHistory(size int) SchedulerOption {
+	return func(s *Scheduler) {
+		if size > 0 {
+			s.historySize = size
+		}
+	}
+}
+
+// scheduledJob - зарегистрированная задача и ее состояние.
+type scheduledJob struct {
+	name     string
+	schedule Schedule
+	fn       JobFunc
+	jitter   time.Duration
+
+	// running - задача выполняется, второй запуск в это время не начинается.
+	running atomic.Bool
+
+	mu       sync.Mutex
+	nextRun  time.Time
+	runs     uint64
+	failures uint64
+	history  []models.JobRun
+}
+
+// Scheduler - планировщик фоновых задач.
+//
+// Задачи регистрируются до запуска планировщика и выполняются по
+// расписанию или вручную через Trigger. Одна задача не выполняется
+// дважды одновременно: запуск по расписанию во время ручного
+// пропускается, а ручной отклоняется errorscustom.ErrJobRunning.
+// Паника задачи перехватывается и попадает в историю как ошибка.
+type Scheduler struct {
+	historySize int
+	logs        *logger.Logger
+	now         func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	order   []*scheduledJob
+	ctx     context.Context
+	stopped bool
+	// manual - ручные запуски, которых ждет остановка планировщика.
+	manual sync.WaitGroup
+}
+
+// NewScheduler - конструктор планировщика.
+func NewScheduler(logs *logger.Logger, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		historySize: defaultJobHistory,
+		logs:        logs,
+		now:         time.Now,
+		jobs:        make(map[string]*scheduledJob),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Register регистрирует задачу name. Задача без расписания
+// запускается только вручную.
+func (s *Scheduler) Register(name string, schedule Schedule, fn JobFunc, opts ...JobOption) error {
+	if name == "" || fn == nil {
+		return errors.New("job name and function are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil {
+		return fmt.Errorf("job %q: scheduler already started", name)
+	}
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %q already registered", name)
+	}
+
+	job := &scheduledJob{
+		name:     name,
+		schedule: schedule,
+		fn:       fn,
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	s.jobs[name] = job
+	s.order = append(s.order, job)
+
+	return nil
+}
+
+// StartScheduler запускает задачи по расписанию. После отмены контекста
+// ждет завершения начатых запусков и возвращается.
+func (s *Scheduler) StartScheduler(ctx context.Context) {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.ctx = ctx
+	jobs := s.order
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		if job.schedule == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(job *scheduledJob) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	// задачи без расписания запускаются вручную до отмены контекста
+	<-ctx.Done()
+	wg.Wait()
+
+	// после stopped новые ручные запуски не начинаются
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.manual.Wait()
+}
+
+// loop запускает задачу по расписанию до отмены контекста.
+func (s *Scheduler) loop(ctx context.Context, job *scheduledJob) {
+	for {
+		next := job.schedule.Next(s.now())
+		if next.IsZero() {
+			job.setNextRun(time.Time{})
+			return
+		}
+		if job.jitter > 0 {
+			next = next.Add(rand.N(job.jitter))
+		}
+		job.setNextRun(next)
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !job.running.CompareAndSwap(false, true) {
+			s.logs.Info("Job skipped, previous run in progress", logger.StringAttr("job", job.name))
+			continue
+		}
+		s.run(ctx, job, models.TriggerSchedule)
+	}
+}
+
+// Trigger запускает задачу name вручную, не дожидаясь ее завершения.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return errorscustom.ErrJobNotFound
+	}
+	if s.ctx == nil || s.stopped || s.ctx.Err() != nil {
+		return errorscustom.ErrSchedulerStopped
+	}
+	if !job.running.CompareAndSwap(false, true) {
+		return errorscustom.ErrJobRunning
+	}
+
+	s.manual.Add(1)
+	go func() {
+		defer s.manual.Done()
+		s.run(s.ctx, job, models.TriggerManual)
+	}()
+
+	return nil
+}
+
+// run выполняет задачу, занятую вызывающим через running,
+// и записывает запуск в историю.
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob, trigger string) {
+	defer job.running.Store(false)
+
+	run := models.JobRun{
+		Trigger:   trigger,
+		StartedAt: s.now(),
+	}
+	err := s.call(ctx, job)
+	run.FinishedAt = s.now()
+	if err != nil {
+		run.Error = err.Error()
+		s.logs.Error("Job failed = ", logger.ErrAttr(err), logger.StringAttr("job", job.name),
+			logger.StringAttr("trigger", trigger))
+	}
+
+	job.record(run, s.historySize)
+}
+
+// call выполняет задачу и превращает ее панику в ошибку.
+func (s *Scheduler) call(ctx context.Context, job *scheduledJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			s.logs.Error("Job panicked", logger.StringAttr("job", job.name),
+				logger.StringAttr("stack", string(debug.Stack())))
+		}
+	}()
+
+	return job.fn(ctx)
+}
+
+// Jobs возвращает состояние задач в порядке регистрации.
+func (s *Scheduler) Jobs() []models.ScheduledJob {
+	s.mu.Lock()
+	jobs := s.order
+	s.mu.Unlock()
+
+	result := make([]models.ScheduledJob, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, job.info())
+	}
+
+	return result
+}
+
+// setNextRun запоминает время следующего запуска по расписанию.
+func (j *scheduledJob) setNextRun(next time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.nextRun = next
+}
+
+// record добавляет запуск в историю, оставляя последние size запусков.
+func (j *scheduledJob) record(run models.JobRun, size int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.runs++
+	if run.Error != "" {
+		j.failures++
+	}
+	if len(j.history) == size {
+		j.history = append(j.history[:0], j.history[1:]...)
+	}
+	j.history = append(j.history, run)
+}
+
+// info возвращает состояние задачи с историей от нового запуска к старому.
+func (j *scheduledJob) info() models.ScheduledJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info := models.ScheduledJob{
+		Name:     j.name,
+		Schedule: manualSchedule,
+		Running:  j.running.Load(),
+		Runs:     j.runs,
+		Failures: j.failures,
+		History:  make([]models.JobRun, 0, len(j.history)),
+	}
+	if j.schedule != nil {
+		info.Schedule = j.schedule.String()
+	}
+	if !j.nextRun.IsZero() {
+		next := j.nextRun
+		info.NextRun = &next
+	}
+	for i := len(j.history) - 1; i >= 0; i-- {
+		info.History = append(info.History, j.history[i])
+	}
+
+	return info
+}
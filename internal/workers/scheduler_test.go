@@ -0,0 +1,176 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startScheduler запускает планировщик и останавливает его в конце теста.
+func startScheduler(t *testing.T, scheduler *Scheduler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.StartScheduler(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// Trigger принимает запуски только после старта
+	require.Eventually(t, func() bool {
+		scheduler.mu.Lock()
+		defer scheduler.mu.Unlock()
+		return scheduler.ctx != nil
+	}, time.Second, time.Millisecond)
+}
+
+// jobByName возвращает состояние задачи.
+func jobByName(t *testing.T, scheduler *Scheduler, name string) models.ScheduledJob {
+	t.Helper()
+
+	for _, job := range scheduler.Jobs() {
+		if job.Name == name {
+			return job
+		}
+	}
+	t.Fatalf("задача %s не найдена", name)
+	return models.ScheduledJob{}
+}
+
+// TestScheduler_Interval - задача выполняется по расписанию, ошибки
+// и паники попадают в историю, а история ограничена.
+func TestScheduler_Interval(t *testing.T) {
+	scheduler := NewScheduler(logger.NewLogger(), WithHistory(3))
+
+	var calls int
+	require.NoError(t, scheduler.Register("flaky", Every(time.Millisecond), func(context.Context) error {
+		calls++
+		switch calls {
+		case 1:
+			return errors.New("storage failed")
+		case 2:
+			panic("boom")
+		}
+		return nil
+	}, WithJitter(time.Millisecond)))
+	startScheduler(t, scheduler)
+
+	require.Eventually(t, func() bool {
+		return jobByName(t, scheduler, "flaky").Runs >= 4
+	}, time.Second, time.Millisecond)
+
+	job := jobByName(t, scheduler, "flaky")
+	assert.Equal(t, "@every 1ms", job.Schedule)
+	assert.Equal(t, uint64(2), job.Failures)
+	assert.NotNil(t, job.NextRun)
+	require.Len(t, job.History, 3)
+	for _, run := range job.History {
+		assert.Equal(t, models.TriggerSchedule, run.Trigger)
+		assert.False(t, run.FinishedAt.Before(run.StartedAt))
+	}
+	// самый старый из сохраненных запусков - паника
+	if job.Runs == 4 {
+		assert.Equal(t, "panic: boom", job.History[2].Error)
+	}
+}
+
+// TestScheduler_Trigger - задача запускается вручную один раз одновременно.
+func TestScheduler_Trigger(t *testing.T) {
+	scheduler := NewScheduler(logger.NewLogger())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, scheduler.Register("manual", nil, func(context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}))
+
+	assert.ErrorIs(t, scheduler.Trigger("manual"), errorscustom.ErrSchedulerStopped)
+	startScheduler(t, scheduler)
+
+	assert.ErrorIs(t, scheduler.Trigger("missing"), errorscustom.ErrJobNotFound)
+	require.NoError(t, scheduler.Trigger("manual"))
+	<-started
+	assert.True(t, jobByName(t, scheduler, "manual").Running)
+	assert.ErrorIs(t, scheduler.Trigger("manual"), errorscustom.ErrJobRunning)
+	close(release)
+
+	require.Eventually(t, func() bool {
+		return !jobByName(t, scheduler, "manual").Running
+	}, time.Second, time.Millisecond)
+	job := jobByName(t, scheduler, "manual")
+	assert.Equal(t, "manual", job.Schedule)
+	assert.Nil(t, job.NextRun)
+	assert.Equal(t, uint64(1), job.Runs)
+	require.Len(t, job.History, 1)
+	assert.Equal(t, models.TriggerManual, job.History[0].Trigger)
+	assert.Empty(t, job.History[0].Error)
+}
+
+// TestScheduler_Stop - остановка ждет ручного запуска, который получает отмену контекста.
+func TestScheduler_Stop(t *testing.T) {
+	scheduler := NewScheduler(logger.NewLogger())
+
+	started := make(chan struct{})
+	require.NoError(t, scheduler.Register("long", Every(time.Hour), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.StartScheduler(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		return scheduler.Trigger("long") == nil
+	}, time.Second, time.Millisecond)
+	<-started
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("планировщик не остановился")
+	}
+
+	job := jobByName(t, scheduler, "long")
+	require.Len(t, job.History, 1)
+	assert.Equal(t, context.Canceled.Error(), job.History[0].Error)
+	assert.ErrorIs(t, scheduler.Trigger("long"), errorscustom.ErrSchedulerStopped)
+}
+
+func TestScheduler_Register(t *testing.T) {
+	scheduler := NewScheduler(logger.NewLogger())
+	noop := func(context.Context) error { return nil }
+
+	require.NoError(t, scheduler.Register("first", Every(time.Minute), noop))
+	require.NoError(t, scheduler.Register("second", nil, noop))
+	assert.Error(t, scheduler.Register("first", nil, noop))
+	assert.Error(t, scheduler.Register("", nil, noop))
+	assert.Error(t, scheduler.Register("empty", nil, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scheduler.StartScheduler(ctx)
+	assert.Error(t, scheduler.Register("late", nil, noop))
+
+	jobs := scheduler.Jobs()
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "first", jobs[0].Name)
+	assert.Equal(t, "second", jobs[1].Name)
+	assert.Empty(t, jobs[1].History)
+}
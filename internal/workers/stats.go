@@ -0,0 +1,55 @@
+package workers
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+)
+
+// ServiceStats - статистика сервиса, подсчитанная последним сбором.
+type ServiceStats struct {
+	models.InternalStats
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StatsRollup - задача, которая периодически собирает статистику сервиса,
+// чтобы мониторинг читал готовые значения, а не обходил хранилище.
+type StatsRollup struct {
+	storage *service.Service
+	logs    *logger.Logger
+
+	now  func() time.Time
+	last atomic.Pointer[ServiceStats]
+}
+
+// NewStatsRollup - конструктор задачи сбора статистики.
+func NewStatsRollup(storage *service.Service, logs *logger.Logger) *StatsRollup {
+	return &StatsRollup{
+		storage: storage,
+		logs:    logs,
+		now:     time.Now,
+	}
+}
+
+// Run собирает статистику один раз - задача планировщика.
+func (w *StatsRollup) Run(ctx context.Context) error {
+	stats, err := w.storage.GetStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.last.Store(&ServiceStats{InternalStats: *stats, UpdatedAt: w.now()})
+	w.logs.Info("Service stats rolled up",
+		logger.IntAttr("urls", stats.URLs), logger.IntAttr("users", stats.Users))
+
+	return nil
+}
+
+// Stats возвращает статистику последнего сбора, nil - если сборов еще не было.
+func (w *StatsRollup) Stats() *ServiceStats {
+	return w.last.Load()
+}
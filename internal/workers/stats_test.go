@@ -0,0 +1,38 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStatsRollup_Run - собранная статистика сохраняется, ошибка сбора не затирает прошлую.
+func TestStatsRollup_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockStorage := mocks.NewMockStorage(ctrl)
+	gomock.InOrder(
+		mockStorage.EXPECT().GetStats(gomock.Any()).Return(&models.InternalStats{URLs: 5, Users: 2}, nil),
+		mockStorage.EXPECT().GetStats(gomock.Any()).Return(nil, errors.New("some error")),
+	)
+
+	rollup := NewStatsRollup(service.NewService(mockStorage, logger.NewLogger()), logger.NewLogger())
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rollup.now = func() time.Time { return now }
+
+	assert.Nil(t, rollup.Stats())
+
+	assert.NoError(t, rollup.Run(context.Background()))
+	expected := &ServiceStats{InternalStats: models.InternalStats{URLs: 5, Users: 2}, UpdatedAt: now}
+	assert.Equal(t, expected, rollup.Stats())
+
+	assert.Error(t, rollup.Run(context.Background()))
+	assert.Equal(t, expected, rollup.Stats())
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
 )
 
@@ -12,7 +17,7 @@ import (
 //
 //go:generate mockgen -source=worker.go -destination=mock_worker.go -package=workers
 type Worker interface {
-	SendDeletionRequestToWorker(req DeletionRequest) error
+	SendDeletionRequestToWorker(ctx context.Context, req DeletionRequest) (int64, error)
 }
 
 // DeletionRequest - запрос на удаление URL из хранилища.
@@ -21,64 +26,565 @@ type DeletionRequest struct {
 	URLs []string
 }
 
-// WorkerDeleted - воркер для удаления URL из хранилища.
-var deleteQueue = make(chan DeletionRequest, 10)
+// Параметры повторов по умолчанию.
+const (
+	defaultMaxAttempts  = 5
+	defaultRetryBackoff = time.Second
+	// maxRetryBackoff - предел паузы между попытками задания.
+	maxRetryBackoff = time.Hour
+	// defaultJobRetention - сколько хранится завершенное задание.
+	defaultJobRetention = 24 * time.Hour
+	// jobsPurgeInterval - наибольшая пауза между удалениями завершенных заданий.
+	jobsPurgeInterval = time.Hour
+	// defaultDrainTimeout - сколько разбирается очередь при остановке.
+	defaultDrainTimeout = 10 * time.Second
+	// claimLease - на сколько задание берется в работу: пока оно в очереди
+	// или буфере, другой экземпляр сервиса его не возьмет. Если экземпляр
+	// упадет, задание выполнит любой другой через claimLease.
+	claimLease = time.Minute
+	// requeueTimeout - сколько после остановки сохраняется возврат
+	// невыполненных заданий в очередь.
+	requeueTimeout = 5 * time.Second
+	// minRetryAfter - наименьшая пауза, через которую клиенту стоит повторить
+	// запрос, не принятый из-за полной очереди.
+	minRetryAfter = time.Second
+)
+
+// DeletionStats - статистика воркера удаления.
+//
+// Queued - заданий в очереди, Done - выполнено заданий, Retried - заданий
+// перенесено после неудачной попытки, Failed - отложено после исчерпания
+// попыток, Rejected - запросов отклонено из-за полной очереди, Errors -
+// ошибок хранилища, в том числе при сохранении состояния заданий.
+type DeletionStats struct {
+	Queued   int    `json:"queued"`
+	Done     uint64 `json:"done"`
+	Retried  uint64 `json:"retried"`
+	Failed   uint64 `json:"failed"`
+	Rejected uint64 `json:"rejected"`
+	Errors   uint64 `json:"errors"`
+}
+
+// deletionCounters - счетчики статистики воркера удаления.
+type deletionCounters struct {
+	done     atomic.Uint64
+	retried  atomic.Uint64
+	failed   atomic.Uint64
+	rejected atomic.Uint64
+	errors   atomic.Uint64
+}
+
+// DeletionOption - опция воркера удаления.
+type DeletionOption func(*WorkerDeleted)
+
+// WithRetry задает число попыток задания и паузу после первой неудачной
+// попытки, каждая следующая пауза вдвое длиннее. Задание, которое не
+// удалось выполнить за maxAttempts попыток, откладывается.
+func WithRetry(maxAttempts int, backoff time.Duration) DeletionOption {
+	return func(w *WorkerDeleted) {
+		if maxAttempts > 0 {
+			w.maxAttempts = maxAttempts
+		}
+		if backoff > 0 {
+			w.backoff = backoff
+		}
+	}
+}
+
+// WithRetention задает, сколько хранится выполненное или отложенное
+// задание, чтобы клиент мог узнать его результат.
+func WithRetention(retention time.Duration) DeletionOption {
+	return func(w *WorkerDeleted) {
+		if retention > 0 {
+			w.retention = retention
+		}
+	}
+}
+
+// WithDrainTimeout задает, сколько после отмены контекста разбирается
+// очередь. Задания, которые не успели выполниться, возвращаются в очередь
+// хранилища, и их сразу может взять другой экземпляр сервиса.
+func WithDrainTimeout(timeout time.Duration) DeletionOption {
+	return func(w *WorkerDeleted) {
+		if timeout > 0 {
+			w.drainTimeout = timeout
+		}
+	}
+}
 
 // WorkerDeleted - воркер для удаления URL из хранилища.
+//
+// Запрос сохраняется в хранилище заданием до ответа клиенту, поэтому не
+// теряется при остановке или падении. Задания из очереди разбирает пул
+// из poolSize горутин: каждая копит ссылки разных пользователей в буфере
+// и удаляет их одним пакетом, когда буфер заполнен или прошел interval.
+// Неудачные задания повторяются с экспоненциальной паузой, а задания,
+// не попавшие в очередь, и задания прошлого запуска подбирает retryLoop.
+// Она передает их пулу мимо очереди, дожидаясь свободной горутины, поэтому
+// накопленные задания не занимают очередь новых запросов.
+// Завершенные задания хранятся retention, затем retryLoop их удаляет.
+// Если очередь полна, новый запрос отклоняется errorscustom.QueueFullError.
 type WorkerDeleted struct {
 	storage      *service.Service
-	errorChannel chan error
-	wg           *sync.WaitGroup
+	queue        chan models.DeletionJob
+	replays      chan models.DeletionJob
+	poolSize     int
+	bufferSize   int
+	interval     time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	retention    time.Duration
+	drainTimeout time.Duration
+	logs         *logger.Logger
+
+	now   func() time.Time
+	stats deletionCounters
+
+	// inflight - ID заданий в очереди и буферах, чтобы не взять их дважды.
+	mu       sync.Mutex
+	inflight map[int64]struct{}
 }
 
 // NewWorkerDeleted - конструктор воркера.
-func NewWorkerDeleted(storage *service.Service) *WorkerDeleted {
-	return &WorkerDeleted{
-		storage: storage,
-		wg:      &sync.WaitGroup{},
+// Очередь вмещает bufferSize заданий, буфер горутины - bufferSize ссылок.
+// Нулевой interval отключает сброс буфера по времени.
+func NewWorkerDeleted(storage *service.Service, poolSize, bufferSize int, interval time.Duration, logs *logger.Logger, opts ...DeletionOption) *WorkerDeleted {
+	bufferSize = max(bufferSize, 1)
+
+	w := &WorkerDeleted{
+		storage:      storage,
+		queue:        make(chan models.DeletionJob, bufferSize),
+		replays:      make(chan models.DeletionJob),
+		poolSize:     max(poolSize, 1),
+		bufferSize:   bufferSize,
+		interval:     interval,
+		maxAttempts:  defaultMaxAttempts,
+		backoff:      defaultRetryBackoff,
+		retention:    defaultJobRetention,
+		drainTimeout: defaultDrainTimeout,
+		logs:         logs,
+		now:          time.Now,
+		inflight:     make(map[int64]struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
 	}
+
+	return w
 }
 
-// StartWorkerDeletion стартует воркер для удаления URL из хранилища.
+// StartWorkerDeletion стартует пул воркеров для удаления URL из хранилища
+// и повтор сохраненных заданий, начиная с заданий прошлого запуска.
+// После отмены контекста не дольше drainTimeout удаляет оставшиеся в
+// очереди URL и завершается, когда остановятся все горутины пула.
 func (w *WorkerDeleted) StartWorkerDeletion(ctx context.Context) {
-	// Запуск worker'а
+	var wg sync.WaitGroup
+	for i := 0; i < w.poolSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(ctx)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.retryLoop(ctx)
+	}()
+
+	wg.Wait()
+}
+
+// run собирает задания из очереди в буфер и удаляет их пакетами.
+// Отмена ctx не прерывает удаление пакета, а запускает drain.
+func (w *WorkerDeleted) run(ctx context.Context) {
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	workCtx := context.WithoutCancel(ctx)
+	buffer := make([]models.DeletionJob, 0, w.bufferSize)
 	for {
 		select {
-		case req, ok := <-deleteQueue:
-			if !ok {
-				// Если deleteQueue закрыт, выходим из цикла
-				w.wg.Wait()
-				return
-			}
-			w.wg.Add(1)
-			go func() {
-				defer w.wg.Done()
-				w.processDeletion(ctx, req)
-			}()
 		case <-ctx.Done():
-			w.wg.Wait()
+			w.drain(workCtx, buffer)
 			return
+		case job := <-w.queue:
+			buffer = w.add(workCtx, buffer, job)
+		case job := <-w.replays:
+			buffer = w.add(workCtx, buffer, job)
+		case <-tick:
+			buffer = w.flush(workCtx, buffer)
 		}
 	}
 }
 
-// processDeletion обрабатывает удаление URL из хранилища.
-func (w *WorkerDeleted) processDeletion(ctx context.Context, req DeletionRequest) {
-	if err := w.storage.DeletedURLs(req.URLs, req.User); err != nil {
+// drain разбирает очередь и буфер после остановки, но не дольше
+// drainTimeout. Невыполненные задания возвращаются в очередь хранилища.
+func (w *WorkerDeleted) drain(ctx context.Context, buffer []models.DeletionJob) {
+	ctx, cancel := context.WithTimeout(ctx, w.drainTimeout)
+	defer cancel()
+
+	for ctx.Err() == nil {
+		select {
+		case job := <-w.queue:
+			buffer = w.add(ctx, buffer, job)
+		default:
+			w.flush(ctx, buffer)
+			return
+		}
+	}
+
+	w.logs.Error("Deletion queue drain timed out = ", logger.ErrAttr(ctx.Err()),
+		logger.IntAttr("buffered", len(buffer)), logger.IntAttr("queued", len(w.queue)))
+	w.requeue(ctx, takeQueued(w.queue, buffer))
+}
+
+// takeQueued забирает из очереди оставшиеся задания, не дожидаясь новых.
+func takeQueued(queue <-chan models.DeletionJob, jobs []models.DeletionJob) []models.DeletionJob {
+	for {
+		select {
+		case job := <-queue:
+			jobs = append(jobs, job)
+		default:
+			return jobs
+		}
+	}
+}
+
+// add добавляет задание в буфер и выполняет буфер, если в нем набралось
+// bufferSize ссылок.
+func (w *WorkerDeleted) add(ctx context.Context, buffer []models.DeletionJob, job models.DeletionJob) []models.DeletionJob {
+	buffer = append(buffer, job)
+
+	var urls int
+	for _, job := range buffer {
+		urls += len(job.URLs)
+	}
+	if urls >= w.bufferSize {
+		return w.flush(ctx, buffer)
+	}
+
+	return buffer
+}
+
+// flush удаляет ссылки заданий из буфера одним пакетом и возвращает пустой
+// буфер. Выполненные задания отмечаются в хранилище, остальные - переносятся.
+// Если пакет не удалился, задания выполняются по одному в flushEach.
+func (w *WorkerDeleted) flush(ctx context.Context, buffer []models.DeletionJob) []models.DeletionJob {
+	if len(buffer) == 0 {
+		return buffer
+	}
+
+	ids := make([]int64, 0, len(buffer))
+	for i, job := range buffer {
+		ids = append(ids, job.ID)
+		buffer[i].Status = models.JobRunning
+	}
+	defer w.release(ids...)
+
+	// статус нужен только клиенту, поэтому его ошибка не останавливает удаление
+	if err := w.storage.UpdateDeletionJobs(ctx, buffer); err != nil {
+		w.reportError("Error update deletion jobs = ", err, logger.IntAttr("count", len(ids)))
+	}
+
+	urls := deletedURLs(buffer)
+	deleted, err := w.storage.DeleteURLsBatch(ctx, urls)
+	if err == nil {
+		w.finish(ctx, buffer, deleted)
+		return buffer[:0]
+	}
+
+	w.reportError("Error delete URLs = ", err, logger.IntAttr("count", len(urls)))
+	switch {
+	case ctx.Err() != nil:
+		// прерванная остановкой попытка не засчитывается
+		w.requeue(ctx, buffer)
+	case len(buffer) == 1:
+		w.fail(ctx, buffer, err)
+	default:
+		w.flushEach(ctx, buffer)
+	}
+
+	return buffer[:0]
+}
+
+// flushEach выполняет задания по одному после неудачи общего пакета,
+// чтобы одно неудачное задание не засчитывало попытку остальным.
+func (w *WorkerDeleted) flushEach(ctx context.Context, jobs []models.DeletionJob) {
+	for i := range jobs {
+		job := jobs[i : i+1]
+		deleted, err := w.storage.DeleteURLsBatch(ctx, deletedURLs(job))
+		if err == nil {
+			w.finish(ctx, job, deleted)
+			continue
+		}
+
+		w.reportError("Error delete URLs = ", err, logger.Int64Attr("job", jobs[i].ID))
+		if ctx.Err() != nil {
+			w.requeue(ctx, jobs[i:])
+			return
+		}
+		w.fail(ctx, job, err)
+	}
+}
+
+// deletedURLs возвращает ссылки заданий на удаление с их владельцами.
+func deletedURLs(jobs []models.DeletionJob) []models.DeletedURL {
+	var urls []models.DeletedURL
+	for _, job := range jobs {
+		for _, shortURL := range job.URLs {
+			urls = append(urls, models.DeletedURL{ShortURL: shortURL, UserID: job.UserID})
+		}
+	}
+
+	return urls
+}
+
+// finish отмечает задания выполненными и делит их ссылки на удаленные
+// и пропущенные по результату пакетного удаления.
+func (w *WorkerDeleted) finish(ctx context.Context, jobs []models.DeletionJob, deleted []models.DeletedURL) {
+	owned := make(map[models.DeletedURL]struct{}, len(deleted))
+	for _, url := range deleted {
+		owned[url] = struct{}{}
+	}
+
+	finishedAt := w.now()
+	for i := range jobs {
+		job := &jobs[i]
+		job.Status = models.JobDone
+		job.LastError = ""
+		job.FinishedAt = &finishedAt
+		job.Deleted = make([]string, 0, len(job.URLs))
+		job.Skipped = make([]string, 0)
+		for _, shortURL := range job.URLs {
+			if _, ok := owned[models.DeletedURL{ShortURL: shortURL, UserID: job.UserID}]; ok {
+				job.Deleted = append(job.Deleted, shortURL)
+			} else {
+				job.Skipped = append(job.Skipped, shortURL)
+			}
+		}
+	}
+
+	// если отметка не сохранится, задания повторятся: удаление идемпотентно
+	if err := w.storage.UpdateDeletionJobs(ctx, jobs); err != nil {
+		w.reportError("Error finish deletion jobs = ", err, logger.IntAttr("count", len(jobs)))
+		return
+	}
+	w.stats.done.Add(uint64(len(jobs)))
+}
+
+// fail засчитывает заданиям неудачную попытку: переносит их на паузу
+// retryBackoff или откладывает, если попытки исчерпаны.
+func (w *WorkerDeleted) fail(ctx context.Context, jobs []models.DeletionJob, err error) {
+	now := w.now()
+	for i := range jobs {
+		job := &jobs[i]
+		job.Attempts++
+		job.LastError = err.Error()
+		if job.Attempts >= w.maxAttempts {
+			job.Status = models.JobFailed
+			job.FinishedAt = &now
+			w.stats.failed.Add(1)
+			w.logs.Error("Deletion job moved aside = ", logger.ErrAttr(err),
+				logger.Int64Attr("job", job.ID), logger.IntAttr("attempts", job.Attempts))
+		} else {
+			job.Status = models.JobQueued
+			job.NextAttempt = now.Add(retryBackoff(w.backoff, job.Attempts))
+			w.stats.retried.Add(1)
+		}
+	}
+
+	if err = w.storage.UpdateDeletionJobs(ctx, jobs); err != nil {
+		w.reportError("Error update deletion jobs = ", err, logger.IntAttr("count", len(jobs)))
+	}
+}
+
+// requeue возвращает прерванные остановкой задания в очередь хранилища
+// без засчитанной попытки, чтобы они выполнились сразу, а не когда
+// истечет срок lease. Контекст к этому моменту отменен, поэтому состояние
+// сохраняется с отдельным таймаутом requeueTimeout.
+func (w *WorkerDeleted) requeue(ctx context.Context, jobs []models.DeletionJob) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(jobs))
+	now := w.now()
+	for i := range jobs {
+		ids = append(ids, jobs[i].ID)
+		jobs[i].Status = models.JobQueued
+		jobs[i].NextAttempt = now
+	}
+	defer w.release(ids...)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requeueTimeout)
+	defer cancel()
+	if err := w.storage.UpdateDeletionJobs(ctx, jobs); err != nil {
+		w.reportError("Error requeue deletion jobs = ", err, logger.IntAttr("count", len(jobs)))
+	}
+}
+
+// reportError логирует ошибку хранилища и учитывает ее в статистике.
+func (w *WorkerDeleted) reportError(msg string, err error, attrs ...any) {
+	w.stats.errors.Add(1)
+	w.logs.Error(msg, append([]any{logger.ErrAttr(err)}, attrs...)...)
+}
+
+// Stats возвращает статистику воркера.
+func (w *WorkerDeleted) Stats() DeletionStats {
+	return DeletionStats{
+		Queued:   len(w.queue),
+		Done:     w.stats.done.Load(),
+		Retried:  w.stats.retried.Load(),
+		Failed:   w.stats.failed.Load(),
+		Rejected: w.stats.rejected.Load(),
+		Errors:   w.stats.errors.Load(),
+	}
+}
+
+// retryBackoff возвращает паузу после attempts неудачных попыток:
+// base, 2*base, 4*base и так далее, но не больше maxRetryBackoff.
+func retryBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxRetryBackoff)
+}
+
+// retryLoop ставит в очередь сохраненные задания, которые пора выполнить:
+// сразу при запуске и затем раз в backoff. Завершенные задания старше
+// retention удаляются не реже раза в jobsPurgeInterval.
+func (w *WorkerDeleted) retryLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.backoff)
+	defer ticker.Stop()
+	purge := time.NewTicker(min(w.retention, jobsPurgeInterval))
+	defer purge.Stop()
+
+	w.replay(ctx)
+	for {
 		select {
-		case w.errorChannel <- err:
 		case <-ctx.Done():
-			fmt.Println("Operation canceled, skipping error reporting.")
+			return
+		case <-ticker.C:
+			w.replay(ctx)
+		case <-purge.C:
+			w.purge(ctx)
 		}
 	}
 }
 
-// SendDeletionRequestToWorker отправляет запрос на удаление URL из хранилища.
-func (w *WorkerDeleted) SendDeletionRequestToWorker(req DeletionRequest) error {
+// purge удаляет задания, завершенные раньше, чем retention назад.
+func (w *WorkerDeleted) purge(ctx context.Context) {
+	purged, err := w.storage.PurgeDeletionJobs(ctx, w.now().Add(-w.retention))
+	if err != nil {
+		w.reportError("Error purge deletion jobs = ", err)
+		return
+	}
+	if purged > 0 {
+		w.logs.Info("Purged deletion jobs", logger.IntAttr("count", purged))
+	}
+}
+
+// replay передает пулу все задания, которые пора выполнить и которые
+// еще не в работе, беря их в хранилище пачками по bufferSize. Задание
+// передается, когда его готова принять горутина пула, поэтому replay
+// не занимает очередь новых запросов.
+func (w *WorkerDeleted) replay(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := w.now()
+		jobs, err := w.storage.ClaimDeletionJobs(ctx, now, now.Add(w.lease()), w.bufferSize)
+		if err != nil {
+			w.reportError("Error load deletion jobs = ", err)
+			return
+		}
+
+		for _, job := range jobs {
+			if !w.claim(job.ID) {
+				continue
+			}
+			select {
+			case w.replays <- job:
+			case <-ctx.Done():
+				w.release(job.ID)
+				return
+			}
+		}
+
+		if len(jobs) < w.bufferSize {
+			return
+		}
+	}
+}
+
+// lease возвращает, на сколько задание берется в работу: не меньше
+// claimLease и двух интервалов сброса буфера.
+func (w *WorkerDeleted) lease() time.Duration {
+	return max(claimLease, 2*w.interval)
+}
+
+// claim отмечает задание взятым в работу. Возвращает false, если оно уже в работе.
+func (w *WorkerDeleted) claim(id int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.inflight[id]; ok {
+		return false
+	}
+	w.inflight[id] = struct{}{}
+	return true
+}
+
+// release снимает с заданий отметку о работе.
+func (w *WorkerDeleted) release(ids ...int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, id := range ids {
+		delete(w.inflight, id)
+	}
+}
+
+// SendDeletionRequestToWorker сохраняет запрос на удаление заданием, ставит
+// его в очередь и возвращает ID задания для запроса его состояния.
+// Задание сохраняется уже взятым в работу, чтобы его не взял другой
+// экземпляр сервиса. Если очередь полна, запрос не сохраняется и
+// отклоняется errorscustom.QueueFullError. Задание, которое успели
+// сохранить, но не успели поставить в заполнившуюся очередь, retryLoop
+// возьмет, когда истечет срок lease.
+func (w *WorkerDeleted) SendDeletionRequestToWorker(ctx context.Context, req DeletionRequest) (int64, error) {
+	if len(w.queue) == cap(w.queue) {
+		w.stats.rejected.Add(1)
+		return 0, &errorscustom.QueueFullError{RetryAfter: max(w.interval, minRetryAfter)}
+	}
+
+	job := models.DeletionJob{
+		UserID:      req.User,
+		URLs:        req.URLs,
+		Status:      models.JobQueued,
+		NextAttempt: w.now().Add(w.lease()),
+	}
+	id, err := w.storage.SaveDeletionJob(ctx, job)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save the deletion request: %w", err)
+	}
+	job.ID = id
+
+	// задание уже могла взять retryLoop
+	if !w.claim(job.ID) {
+		return id, nil
+	}
 	select {
-	case deleteQueue <- req:
-		return nil
+	case w.queue <- job:
 	default:
-		return fmt.Errorf("the deletion request queue is currently full, please try again later")
+		w.release(job.ID)
 	}
+
+	return id, nil
 }
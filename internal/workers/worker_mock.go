@@ -5,6 +5,7 @@
 package workers
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,15 +35,16 @@ func (m *MockWorker) EXPECT() *MockWorkerMockRecorder {
 }
 
 // SendDeletionRequestToWorker mocks base method.
-func (m *MockWorker) SendDeletionRequestToWorker(req DeletionRequest) error {
+func (m *MockWorker) SendDeletionRequestToWorker(ctx context.Context, req DeletionRequest) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendDeletionRequestToWorker", req)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SendDeletionRequestToWorker", ctx, req)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SendDeletionRequestToWorker indicates an expected call of SendDeletionRequestToWorker.
-func (mr *MockWorkerMockRecorder) SendDeletionRequestToWorker(req interface{}) *gomock.Call {
+func (mr *MockWorkerMockRecorder) SendDeletionRequestToWorker(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDeletionRequestToWorker", reflect.TypeOf((*MockWorker)(nil).SendDeletionRequestToWorker), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDeletionRequestToWorker", reflect.TypeOf((*MockWorker)(nil).SendDeletionRequestToWorker), ctx, req)
 }
@@ -2,57 +2,476 @@ package workers
 
 import (
 	"context"
-	"github.com/golang/mock/gomock"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/errorscustom"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/logger"
-	"github.com/kamencov/go-musthave-shortener-tpl/internal/mocks"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/models"
 	"github.com/kamencov/go-musthave-shortener-tpl/internal/service"
-	"testing"
+	"github.com/kamencov/go-musthave-shortener-tpl/internal/storage/mapstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-type TestWorkerDeleted struct {
-	name        string
-	userID      string
-	urls        []string
-	expectedErr error
+// jobsStorage - хранилище в памяти, которое пересылает пакеты на удаление
+// в канал и отказывает первые failures раз и каждый раз, когда в пакете
+// есть ссылка poison.
+type jobsStorage struct {
+	*mapstorage.MapStorage
+
+	mu       sync.Mutex
+	failures int
+	poison   string
+	calls    int
+	deleted  chan []models.DeletedURL
+}
+
+func newJobsStorage(failures int) *jobsStorage {
+	return &jobsStorage{
+		MapStorage: mapstorage.NewMapURL(),
+		failures:   failures,
+		deleted:    make(chan []models.DeletedURL, 100),
+	}
+}
+
+func (s *jobsStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.failures != 0 {
+		s.failures--
+		return nil, errors.New("storage failed")
+	}
+	if slices.ContainsFunc(urls, func(url models.DeletedURL) bool { return url.ShortURL == s.poison }) {
+		return nil, errors.New("poisoned batch")
+	}
+	s.deleted <- append([]models.DeletedURL(nil), urls...)
+	return s.MapStorage.DeleteURLsBatch(ctx, urls)
+}
+
+// batches возвращает число попыток удалить пакет.
+func (s *jobsStorage) batches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+// job возвращает сохраненное состояние задания.
+func (s *jobsStorage) job(t *testing.T, id int64) *models.DeletionJob {
+	job, err := s.GetDeletionJob(context.Background(), id)
+	require.NoError(t, err)
+	return job
 }
 
-// TestNewWorkerDeleted - тестируем работоспособность воркера.
-func TestNewWorkerDeleted(t *testing.T) {
-	testCase := []TestWorkerDeleted{
-		{
-			name:        "successful",
-			userID:      "test",
-			urls:        []string{"www, vvv"},
-			expectedErr: nil,
-		},
+// pending возвращает сохраненные задания, которые еще не выполнены.
+func (s *jobsStorage) pending(t *testing.T) []models.DeletionJob {
+	jobs, err := s.DueDeletionJobs(context.Background(), time.Now().Add(24*time.Hour), 0, 100)
+	require.NoError(t, err)
+	return jobs
+}
+
+// waitDeleted ждет пакет на удаление.
+func waitDeleted(t *testing.T, storage *jobsStorage) []models.DeletedURL {
+	t.Helper()
+
+	select {
+	case urls := <-storage.deleted:
+		return urls
+	case <-time.After(time.Second):
+		t.Fatal("пакет не удален")
+		return nil
 	}
+}
+
+// send отправляет запрос воркеру и возвращает ID задания.
+func send(t *testing.T, worker *WorkerDeleted, user string, urls ...string) int64 {
+	t.Helper()
+
+	id, err := worker.SendDeletionRequestToWorker(context.Background(), DeletionRequest{User: user, URLs: urls})
+	require.NoError(t, err)
+	return id
+}
 
-	for _, tt := range testCase {
-		t.Run(tt.name, func(t *testing.T) {
-			// делаем заглушку базы.
-			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
-			mockStorage := mocks.NewMockStorage(ctrl)
+// startWorker запускает воркер и останавливает его в конце теста.
+func startWorker(t *testing.T, worker *WorkerDeleted) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.StartWorkerDeletion(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
 
-			// имитируем действие метода DeletedURLs
-			mockStorage.EXPECT().DeletedURLs(gomock.Any(), gomock.Any()).Return(tt.expectedErr).AnyTimes()
+// TestWorkerDeleted_BufferFull - запросы разных пользователей удаляются одним пакетом,
+// когда буфер заполнен, а выполненные задания больше не выполняются.
+func TestWorkerDeleted_BufferFull(t *testing.T) {
+	storage := newJobsStorage(0)
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 1, 3, 0, logger.NewLogger(),
+		WithRetry(5, time.Hour))
+	startWorker(t, workTest)
 
-			// создаем сервис.
-			serviceTest := service.NewService(mockStorage, logger.NewLogger())
+	send(t, workTest, "user1", "aaa", "bbb")
+	send(t, workTest, "user2", "ccc")
 
-			// создаем воркер
-			workTest := NewWorkerDeleted(serviceTest)
+	assert.Equal(t, []models.DeletedURL{
+		{ShortURL: "aaa", UserID: "user1"},
+		{ShortURL: "bbb", UserID: "user1"},
+		{ShortURL: "ccc", UserID: "user2"},
+	}, waitDeleted(t, storage))
+	assert.Eventually(t, func() bool {
+		return len(storage.pending(t)) == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return workTest.Stats().Done == 2
+	}, time.Second, 5*time.Millisecond)
+}
 
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
+// TestWorkerDeleted_Retry - неудачное задание повторяется после паузы.
+func TestWorkerDeleted_Retry(t *testing.T) {
+	storage := newJobsStorage(1)
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 2, 100, 5*time.Millisecond, logger.NewLogger(),
+		WithRetry(3, 10*time.Millisecond))
+	startWorker(t, workTest)
 
-			go workTest.StartWorkerDeletion(ctx)
+	id := send(t, workTest, "user1", "aaa")
 
-			// Отправка запроса на удаление
-			err := workTest.SendDeletionRequestToWorker(DeletionRequest{User: "test", URLs: []string{"example.com"}})
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
+	assert.Equal(t, []models.DeletedURL{{ShortURL: "aaa", UserID: "user1"}}, waitDeleted(t, storage))
+	assert.Eventually(t, func() bool {
+		return storage.job(t, id).Status == models.JobDone
+	}, time.Second, 5*time.Millisecond)
+	job := storage.job(t, id)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Empty(t, job.LastError)
+	assert.Equal(t, 2, storage.batches())
+	assert.Empty(t, storage.pending(t))
+}
+
+// TestWorkerDeleted_Poison - задание откладывается после исчерпания попыток.
+func TestWorkerDeleted_Poison(t *testing.T) {
+	storage := newJobsStorage(-1)
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 1, 100, 5*time.Millisecond, logger.NewLogger(),
+		WithRetry(2, 5*time.Millisecond))
+	startWorker(t, workTest)
+
+	id := send(t, workTest, "user1", "aaa")
+
+	assert.Eventually(t, func() bool {
+		return storage.job(t, id).Status == models.JobFailed
+	}, time.Second, 5*time.Millisecond)
+	job := storage.job(t, id)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "storage failed", job.LastError)
+	assert.NotNil(t, job.FinishedAt)
+
+	// отложенное задание больше не выполняется
+	assert.Empty(t, storage.pending(t))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 2, storage.batches())
+
+	stats := workTest.Stats()
+	assert.Equal(t, uint64(1), stats.Retried)
+	assert.Equal(t, uint64(1), stats.Failed)
+	assert.Equal(t, uint64(2), stats.Errors)
+	assert.Zero(t, stats.Done)
+}
+
+// TestWorkerDeleted_PoisonInBatch - если пакет не удалился, задания выполняются
+// по одному и попытка засчитывается только неудавшемуся.
+func TestWorkerDeleted_PoisonInBatch(t *testing.T) {
+	storage := newJobsStorage(0)
+	storage.poison = "bad"
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 1, 3, 0, logger.NewLogger(),
+		WithRetry(5, time.Hour))
+	startWorker(t, workTest)
+
+	bad := send(t, workTest, "user1", "bad")
+	good := send(t, workTest, "user2", "aaa", "bbb")
+
+	assert.Equal(t, []models.DeletedURL{
+		{ShortURL: "aaa", UserID: "user2"},
+		{ShortURL: "bbb", UserID: "user2"},
+	}, waitDeleted(t, storage))
+	assert.Eventually(t, func() bool {
+		return storage.job(t, good).Status == models.JobDone && storage.job(t, bad).Status == models.JobQueued
+	}, time.Second, 5*time.Millisecond)
+	assert.Zero(t, storage.job(t, good).Attempts)
+	assert.Equal(t, 1, storage.job(t, bad).Attempts)
+	assert.Equal(t, "poisoned batch", storage.job(t, bad).LastError)
+	// общий пакет и по пакету на задание
+	assert.Equal(t, 3, storage.batches())
+}
+
+// TestWorkerDeleted_Result - выполненное задание делит ссылки на удаленные
+// и чужие, а по истечении срока хранения удаляется.
+func TestWorkerDeleted_Result(t *testing.T) {
+	storage := newJobsStorage(0)
+	ctx := context.Background()
+	_, err := storage.SaveURL(ctx, "aaa", "https://example.com/a", "user1", models.LinkOptions{})
+	require.NoError(t, err)
+	_, err = storage.SaveURL(ctx, "bbb", "https://example.com/b", "user2", models.LinkOptions{})
+	require.NoError(t, err)
+
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 1, 100, 5*time.Millisecond, logger.NewLogger(),
+		WithRetention(50*time.Millisecond))
+	id := send(t, workTest, "user1", "aaa", "bbb", "ccc")
+	empty := send(t, workTest, "user1")
+	assert.Equal(t, models.JobQueued, storage.job(t, id).Status)
+	startWorker(t, workTest)
+
+	assert.Eventually(t, func() bool {
+		return storage.job(t, id).Status == models.JobDone
+	}, time.Second, 5*time.Millisecond)
+	job := storage.job(t, id)
+	assert.Equal(t, []string{"aaa"}, job.Deleted)
+	assert.Equal(t, []string{"bbb", "ccc"}, job.Skipped)
+	assert.NotNil(t, job.FinishedAt)
+
+	// пустой запрос тоже выполняется
+	assert.Eventually(t, func() bool {
+		return storage.job(t, empty).Status == models.JobDone
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, err := storage.GetDeletionJob(ctx, id)
+		return errors.Is(err, errorscustom.ErrNotFound)
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestWorkerDeleted_Replay - задания прошлого запуска, в том числе прерванные,
+// выполняются при старте.
+func TestWorkerDeleted_Replay(t *testing.T) {
+	storage := newJobsStorage(0)
+	_, err := storage.SaveDeletionJob(context.Background(), models.DeletionJob{
+		UserID: "user1", URLs: []string{"aaa"}, Status: models.JobRunning, NextAttempt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 1, 100, 5*time.Millisecond, logger.NewLogger(),
+		WithRetry(5, time.Hour))
+	startWorker(t, workTest)
+
+	assert.Equal(t, []models.DeletedURL{{ShortURL: "aaa", UserID: "user1"}}, waitDeleted(t, storage))
+	assert.Eventually(t, func() bool {
+		return len(storage.pending(t)) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+// gatedStorage - хранилище, которое удаляет пакеты только после закрытия gate.
+type gatedStorage struct {
+	*jobsStorage
+	gate chan struct{}
+}
+
+func (s *gatedStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	<-s.gate
+	return s.jobsStorage.DeleteURLsBatch(ctx, urls)
+}
+
+// TestWorkerDeleted_ReplayBacklog - задания прошлого запуска передаются пулу
+// мимо очереди, поэтому их накопилось больше, чем вмещает очередь, новые
+// запросы не отклоняются.
+func TestWorkerDeleted_ReplayBacklog(t *testing.T) {
+	storage := newJobsStorage(0)
+	for _, url := range []string{"aaa", "bbb", "ccc"} {
+		_, err := storage.SaveDeletionJob(context.Background(), models.DeletionJob{
+			UserID: "user1", URLs: []string{url}, Status: models.JobQueued, NextAttempt: time.Now(),
 		})
+		require.NoError(t, err)
+	}
+
+	gate := make(chan struct{})
+	workTest := NewWorkerDeleted(service.NewService(&gatedStorage{storage, gate}, logger.NewLogger()), 1, 1, 0, logger.NewLogger(),
+		WithRetry(5, time.Hour))
+	startWorker(t, workTest)
+
+	// первое задание прошлого запуска уже выполняется, остальные ждут горутину пула
+	require.Eventually(t, func() bool {
+		return storage.job(t, 1).Status == models.JobRunning
+	}, time.Second, 5*time.Millisecond)
+	id := send(t, workTest, "user2", "ddd")
+	// новое задание сохранено взятым в работу и другой экземпляр его не возьмет
+	assert.True(t, storage.job(t, id).NextAttempt.After(time.Now().Add(claimLease/2)))
+
+	close(gate)
+	assert.Eventually(t, func() bool {
+		return len(storage.pending(t)) == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Zero(t, workTest.Stats().Rejected)
+}
+
+// TestWorkerDeleted_SendContext - запрос с отмененным контекстом не сохраняется.
+func TestWorkerDeleted_SendContext(t *testing.T) {
+	storage := newJobsStorage(0)
+	workTest := NewWorkerDeleted(service.NewService(&contextSave{storage}, logger.NewLogger()), 1, 1, 0, logger.NewLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := workTest.SendDeletionRequestToWorker(ctx, DeletionRequest{User: "user1", URLs: []string{"aaa"}})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, storage.pending(t))
+}
+
+// contextSave - хранилище, которое не сохраняет задание с отмененным контекстом.
+type contextSave struct {
+	*jobsStorage
+}
+
+func (s *contextSave) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.jobsStorage.SaveDeletionJob(ctx, job)
+}
+
+// TestWorkerDeleted_Shutdown - при остановке очередь разбирается и удаляется.
+func TestWorkerDeleted_Shutdown(t *testing.T) {
+	storage := newJobsStorage(0)
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 3, 100, time.Hour, logger.NewLogger())
+
+	send(t, workTest, "user1", "aaa")
+	send(t, workTest, "user2", "bbb")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	workTest.StartWorkerDeletion(ctx)
+	close(storage.deleted)
+
+	var urls []models.DeletedURL
+	for batch := range storage.deleted {
+		urls = append(urls, batch...)
+	}
+	assert.ElementsMatch(t, []models.DeletedURL{
+		{ShortURL: "aaa", UserID: "user1"},
+		{ShortURL: "bbb", UserID: "user2"},
+	}, urls)
+	assert.Empty(t, storage.pending(t))
+}
+
+// TestWorkerDeleted_QueueFull - запрос в полную очередь отклоняется с паузой
+// до повтора и не сохраняется.
+func TestWorkerDeleted_QueueFull(t *testing.T) {
+	storage := newJobsStorage(0)
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 1, 1, 0, logger.NewLogger(),
+		WithRetry(5, 10*time.Millisecond))
+
+	send(t, workTest, "user1", "aaa")
+	_, err := workTest.SendDeletionRequestToWorker(context.Background(), DeletionRequest{User: "user1", URLs: []string{"bbb"}})
+	var queueFullErr *errorscustom.QueueFullError
+	require.ErrorAs(t, err, &queueFullErr)
+	assert.ErrorIs(t, err, errorscustom.ErrQueueFull)
+	assert.Equal(t, time.Second, queueFullErr.RetryAfter)
+	assert.Len(t, storage.pending(t), 1)
+
+	stats := workTest.Stats()
+	assert.Equal(t, 1, stats.Queued)
+	assert.Equal(t, uint64(1), stats.Rejected)
+
+	startWorker(t, workTest)
+	assert.Equal(t, []models.DeletedURL{{ShortURL: "aaa", UserID: "user1"}}, waitDeleted(t, storage))
+}
+
+// blockingStorage - хранилище, которое удаляет пакет только после отмены контекста.
+type blockingStorage struct {
+	*jobsStorage
+}
+
+func (s *blockingStorage) DeleteURLsBatch(ctx context.Context, urls []models.DeletedURL) ([]models.DeletedURL, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// TestWorkerDeleted_DrainTimeout - при остановке очередь разбирается не дольше
+// drainTimeout, а прерванное задание возвращается в очередь хранилища без
+// попыток и без ожидания lease.
+func TestWorkerDeleted_DrainTimeout(t *testing.T) {
+	storage := newJobsStorage(0)
+	workTest := NewWorkerDeleted(service.NewService(&blockingStorage{storage}, logger.NewLogger()), 1, 100, time.Hour, logger.NewLogger(),
+		WithDrainTimeout(20*time.Millisecond))
+
+	id := send(t, workTest, "user1", "aaa")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	workTest.StartWorkerDeletion(ctx)
+	assert.Less(t, time.Since(start), time.Second)
+
+	job := storage.job(t, id)
+	assert.Equal(t, models.JobQueued, job.Status)
+	assert.Zero(t, job.Attempts)
+	assert.Empty(t, job.LastError)
+	assert.False(t, job.NextAttempt.After(time.Now()), "задание ждет истечения lease")
+	assert.Len(t, storage.pending(t), 1)
+	assert.Equal(t, uint64(1), workTest.Stats().Errors)
+}
+
+// TestWorkerDeleted_DrainRequeue - задания буфера и очереди, которые drain
+// не успел разобрать, возвращаются в очередь хранилища без ожидания lease.
+func TestWorkerDeleted_DrainRequeue(t *testing.T) {
+	storage := newJobsStorage(0)
+	workTest := NewWorkerDeleted(service.NewService(storage, logger.NewLogger()), 1, 100, time.Hour, logger.NewLogger())
+
+	buffered := send(t, workTest, "user1", "aaa")
+	queued := send(t, workTest, "user2", "bbb")
+	buffer := []models.DeletionJob{<-workTest.queue}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	workTest.drain(ctx, buffer)
+
+	for _, id := range []int64{buffered, queued} {
+		job := storage.job(t, id)
+		assert.Equal(t, models.JobQueued, job.Status)
+		assert.False(t, job.NextAttempt.After(time.Now()), "задание ждет истечения lease")
+	}
+	assert.Zero(t, storage.batches())
+	assert.Empty(t, workTest.queue)
+	assert.Empty(t, workTest.inflight)
+}
+
+// TestWorkerDeleted_SaveError - запрос не принимается, если задание не сохранилось.
+func TestWorkerDeleted_SaveError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	storage := newJobsStorage(0)
+	workTest := NewWorkerDeleted(service.NewService(&failingSave{storage}, logger.NewLogger()), 1, 1, 0, logger.NewLogger())
+	_, err := workTest.SendDeletionRequestToWorker(context.Background(), DeletionRequest{User: "user1", URLs: []string{"aaa"}})
+	assert.Error(t, err)
+	workTest.StartWorkerDeletion(ctx)
+}
+
+// failingSave - хранилище, которое не сохраняет задания.
+type failingSave struct {
+	*jobsStorage
+}
+
+func (s *failingSave) SaveDeletionJob(ctx context.Context, job models.DeletionJob) (int64, error) {
+	return 0, errors.New("storage failed")
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 4, expected: 8 * time.Second},
+		{attempts: 100, expected: maxRetryBackoff},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, retryBackoff(time.Second, tt.attempts), tt.attempts)
 	}
 }